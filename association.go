@@ -0,0 +1,490 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// the kinds of the declared associations
+const (
+	hasOne = iota
+	hasMany
+	belongsTo
+	manyToMany
+)
+
+// association is a relation declared by tag has_one(fk), has_many(fk),
+// belongs_to(fk) or many2many(join_table,fk,ref_fk), the field isn't mapped
+// to a column and is loaded by Preload.
+type association struct {
+	kind   int
+	field  string       // name of the struct field
+	target reflect.Type // struct type of the associated records
+
+	// has_one, has_many: the target's column which references the owner
+	// belongs_to: the owner's column which references the target
+	// many2many: the join table's column which references the owner
+	fk string
+
+	joinTable string // many2many only
+	refFk     string // many2many only, the join table's column which references the target
+
+	// tag cascade(save,delete), the records are saved or deleted with the owner
+	cascadeSave   bool
+	cascadeDelete bool
+}
+
+var assocTags = map[string]int{
+	"HAS_ONE":    hasOne,
+	"HAS_MANY":   hasMany,
+	"BELONGS_TO": belongsTo,
+	"MANY2MANY":  manyToMany,
+}
+
+// parseAssociation returns the association declared by the field's tags, nil
+// if there's none
+func (engine *Engine) parseAssociation(owner reflect.Type, field reflect.StructField, tags []string) (*association, error) {
+	var cascadeSave, cascadeDelete bool
+	for _, key := range tags {
+		k := strings.ToUpper(key)
+		if strings.HasPrefix(k, "CASCADE(") && strings.HasSuffix(k, ")") {
+			for _, opt := range strings.Split(k[len("CASCADE")+1:len(k)-1], ",") {
+				switch strings.TrimSpace(opt) {
+				case "SAVE":
+					cascadeSave = true
+				case "DELETE":
+					cascadeDelete = true
+				}
+			}
+		}
+	}
+
+	for _, key := range tags {
+		name, argStr := strings.ToUpper(key), ""
+		if i := strings.Index(key, "("); i > 0 && strings.HasSuffix(key, ")") {
+			name, argStr = strings.ToUpper(key[:i]), key[i+1:len(key)-1]
+		}
+		kind, ok := assocTags[name]
+		if !ok {
+			continue
+		}
+
+		var args []string
+		for _, arg := range strings.Split(argStr, ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+		arg := func(i int, def string) string {
+			if i < len(args) && args[i] != "" {
+				return args[i]
+			}
+			return def
+		}
+
+		target := field.Type
+		if kind == hasMany || kind == manyToMany {
+			if target.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("field %v of %v should be a slice", field.Name, key)
+			}
+			target = target.Elem()
+		}
+		if target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+		if target.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field %v of %v should be a struct", field.Name, key)
+		}
+
+		assoc := &association{kind: kind, field: field.Name, target: target,
+			cascadeSave: cascadeSave, cascadeDelete: cascadeDelete}
+		ownerFk := engine.ColumnMapper.Obj2Table(owner.Name()) + "_id"
+		switch kind {
+		case hasOne, hasMany:
+			assoc.fk = arg(0, ownerFk)
+		case belongsTo:
+			assoc.fk = arg(0, engine.ColumnMapper.Obj2Table(field.Name)+"_id")
+		case manyToMany:
+			assoc.joinTable = arg(0, engine.TableMapper.Obj2Table(owner.Name())+"_"+
+				engine.TableMapper.Obj2Table(target.Name()))
+			assoc.fk = arg(1, ownerFk)
+			assoc.refFk = arg(2, engine.ColumnMapper.Obj2Table(target.Name())+"_id")
+		}
+		return assoc, nil
+	}
+	return nil, nil
+}
+
+// Preload loads the associations declared by tags after Get or Find, the
+// nested ones are separated by dot, i.e. Preload("Orders", "Orders.Items")
+func (statement *Statement) Preload(fields ...string) *Statement {
+	statement.preloads = append(statement.preloads, fields...)
+	return statement
+}
+
+// Preload loads the associations declared by tags after Get or Find, the
+// nested ones are separated by dot, i.e. Preload("Orders", "Orders.Items")
+func (session *Session) Preload(fields ...string) *Session {
+	session.Statement.Preload(fields...)
+	return session
+}
+
+// preloadSlice loads the associations of the records found by Find
+func (session *Session) preloadSlice(rowsSlicePtr interface{}, paths []string) error {
+	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
+	var beans []reflect.Value
+	switch sliceValue.Kind() {
+	case reflect.Slice:
+		for i := 0; i < sliceValue.Len(); i++ {
+			if bean := reflect.Indirect(sliceValue.Index(i)); bean.Kind() == reflect.Struct {
+				beans = append(beans, bean)
+			}
+		}
+	case reflect.Map:
+		// the struct values of a map are not addressable, they're copied
+		// and put back
+		keys := sliceValue.MapKeys()
+		for _, key := range keys {
+			elem := sliceValue.MapIndex(key)
+			if elem.Kind() == reflect.Ptr {
+				if !elem.IsNil() && elem.Elem().Kind() == reflect.Struct {
+					beans = append(beans, elem.Elem())
+				}
+				continue
+			}
+			if elem.Kind() == reflect.Struct {
+				bean := reflect.New(elem.Type()).Elem()
+				bean.Set(elem)
+				beans = append(beans, bean)
+				defer sliceValue.SetMapIndex(key, bean)
+			}
+		}
+	}
+	return session.preload(beans, paths)
+}
+
+// preload loads the associations of beans, which are addressable structs of
+// the same type
+func (session *Session) preload(beans []reflect.Value, paths []string) error {
+	if len(beans) == 0 || len(paths) == 0 {
+		return nil
+	}
+
+	// the nested paths are grouped by their first field
+	var fields []string
+	nested := make(map[string][]string)
+	for _, path := range paths {
+		fs := strings.SplitN(path, ".", 2)
+		if _, ok := nested[fs[0]]; !ok {
+			fields = append(fields, fs[0])
+			nested[fs[0]] = nil
+		}
+		if len(fs) == 2 {
+			nested[fs[0]] = append(nested[fs[0]], fs[1])
+		}
+	}
+
+	table := session.Engine.autoMapType(beans[0])
+	ext := session.Engine.tableExt(table)
+	for _, field := range fields {
+		assoc, ok := ext.assocs[field]
+		if !ok {
+			return fmt.Errorf("%v has no association %v", beans[0].Type().Name(), field)
+		}
+		targets, assign, err := session.loadAssociation(table, assoc, beans)
+		if err != nil {
+			return err
+		}
+		// the nested ones are loaded first since the fields may hold copies
+		if err = session.preload(targets, nested[field]); err != nil {
+			return err
+		}
+		assign()
+	}
+	return nil
+}
+
+// relatedSession returns a session which loads or saves the associated
+// records in the transaction and the tenant of session, closeRelated closes
+// it. The cascade fields of the records are loaded until the engine's
+// cascade depth.
+func (session *Session) relatedSession() *Session {
+	related := session.Engine.NewSession()
+	related.cascadeDeep = session.cascadeDeep + 1
+	if session.tenantId != nil {
+		related.Tenant(session.tenantId)
+	}
+	if !session.IsAutoCommit {
+		related.Tx = session.Tx
+		related.IsAutoCommit = false
+	}
+	related.identities = session.identities
+	related.cascading = session.cascading
+	return related
+}
+
+// closeRelated closes a session returned by relatedSession, the processors
+// waiting for the commit are handed to session
+func (session *Session) closeRelated(related *Session) {
+	if !session.IsAutoCommit {
+		for bean, closures := range related.afterInsertBeans {
+			session.afterInsertBeans[bean] = closures
+		}
+		for bean, closures := range related.afterUpdateBeans {
+			session.afterUpdateBeans[bean] = closures
+		}
+		for bean, closures := range related.afterDeleteBeans {
+			session.afterDeleteBeans[bean] = closures
+		}
+	}
+	// the transaction is owned by session
+	related.Tx = nil
+	related.Close()
+}
+
+// singlePK returns the primary key column of table, associations don't
+// support composite primary keys
+func singlePK(table *core.Table) (*core.Column, error) {
+	if len(table.PrimaryKeys) != 1 {
+		return nil, fmt.Errorf("table %v should have one primary key for associations", table.Name)
+	}
+	return table.PKColumns()[0], nil
+}
+
+// assocKey returns the key which matches a column's value with the value
+// referencing it
+func assocKey(v reflect.Value) string {
+	return fmt.Sprintf("%v", reflect.Indirect(v).Interface())
+}
+
+// columnKeys returns the distinct non-zero values of col in beans, and the
+// keys of every bean
+func columnKeys(beans []reflect.Value, col *core.Column) ([]interface{}, []string, error) {
+	var args []interface{}
+	keys := make([]string, len(beans))
+	seen := make(map[string]bool)
+	for i := range beans {
+		v, err := colValueOfV(col, &beans[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if isZeroValue(*v) {
+			continue
+		}
+		keys[i] = assocKey(*v)
+		if !seen[keys[i]] {
+			seen[keys[i]] = true
+			args = append(args, reflect.Indirect(*v).Interface())
+		}
+	}
+	return args, keys, nil
+}
+
+// findRelated finds the records of target whose column colName is one of
+// args, by one query per chunk of args. The soft deleted ones are included
+// if unscoped.
+func (session *Session) findRelated(target reflect.Type, colName string, args []interface{}, unscoped bool) ([]reflect.Value, error) {
+	var targets []reflect.Value
+	related := session.relatedSession()
+	defer session.closeRelated(related)
+	limit := session.Engine.inArgsLimit()
+	for start := 0; start < len(args); start += limit {
+		end := start + limit
+		if end > len(args) {
+			end = len(args)
+		}
+
+		if unscoped {
+			related.Unscoped()
+		}
+		rows := reflect.New(reflect.SliceOf(reflect.PtrTo(target)))
+		if err := related.In(colName, args[start:end]...).Find(rows.Interface()); err != nil {
+			return nil, err
+		}
+		rows = rows.Elem()
+		for i := 0; i < rows.Len(); i++ {
+			targets = append(targets, rows.Index(i).Elem())
+		}
+	}
+	return targets, nil
+}
+
+// setAssociation sets the associated records to the field
+func setAssociation(field reflect.Value, targets []reflect.Value) {
+	switch field.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(field.Type(), 0, len(targets))
+		for _, target := range targets {
+			if field.Type().Elem().Kind() == reflect.Ptr {
+				s = reflect.Append(s, target.Addr())
+			} else {
+				s = reflect.Append(s, target)
+			}
+		}
+		field.Set(s)
+	case reflect.Ptr:
+		if len(targets) > 0 {
+			field.Set(targets[0].Addr())
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	default:
+		if len(targets) > 0 {
+			field.Set(targets[0])
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+// loadAssociation loads the associated records of beans, assign sets them to
+// the beans' fields
+func (session *Session) loadAssociation(table *core.Table, assoc *association, beans []reflect.Value) ([]reflect.Value, func(), error) {
+	targetTable := session.Engine.autoMapType(reflect.New(assoc.target).Elem())
+
+	switch assoc.kind {
+	case hasOne, hasMany:
+		pkCol, err := singlePK(table)
+		if err != nil {
+			return nil, nil, err
+		}
+		fkCol := targetTable.GetColumn(assoc.fk)
+		if fkCol == nil {
+			return nil, nil, fmt.Errorf("table %v has no column %v", targetTable.Name, assoc.fk)
+		}
+		args, keys, err := columnKeys(beans, pkCol)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets, err := session.findRelated(assoc.target, fkCol.Name, args, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		group := make(map[string][]reflect.Value)
+		for i := range targets {
+			v, err := colValueOfV(fkCol, &targets[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			key := assocKey(*v)
+			group[key] = append(group[key], targets[i])
+		}
+		return targets, func() {
+			for i, bean := range beans {
+				setAssociation(bean.FieldByName(assoc.field), group[keys[i]])
+			}
+		}, nil
+
+	case belongsTo:
+		fkCol := table.GetColumn(assoc.fk)
+		if fkCol == nil {
+			return nil, nil, fmt.Errorf("table %v has no column %v", table.Name, assoc.fk)
+		}
+		pkCol, err := singlePK(targetTable)
+		if err != nil {
+			return nil, nil, err
+		}
+		args, keys, err := columnKeys(beans, fkCol)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets, err := session.findRelated(assoc.target, pkCol.Name, args, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		byKey := make(map[string][]reflect.Value)
+		for i := range targets {
+			v, err := colValueOfV(pkCol, &targets[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			byKey[assocKey(*v)] = targets[i : i+1]
+		}
+		return targets, func() {
+			for i, bean := range beans {
+				setAssociation(bean.FieldByName(assoc.field), byKey[keys[i]])
+			}
+		}, nil
+
+	case manyToMany:
+		pkCol, err := singlePK(table)
+		if err != nil {
+			return nil, nil, err
+		}
+		targetPK, err := singlePK(targetTable)
+		if err != nil {
+			return nil, nil, err
+		}
+		args, keys, err := columnKeys(beans, pkCol)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		links := make(map[string][]string)
+		var targetArgs []interface{}
+		seen := make(map[string]bool)
+		engine := session.Engine
+		limit := engine.inArgsLimit()
+		for start := 0; start < len(args); start += limit {
+			end := start + limit
+			if end > len(args) {
+				end = len(args)
+			}
+			sqlStr := fmt.Sprintf("SELECT %v, %v FROM %v WHERE %v IN (%v)",
+				engine.Quote(assoc.fk), engine.Quote(assoc.refFk), engine.Quote(assoc.joinTable),
+				engine.Quote(assoc.fk), strings.TrimSuffix(strings.Repeat("?,", end-start), ","))
+			related := session.relatedSession()
+			results, err := related.query(sqlStr, args[start:end]...)
+			session.closeRelated(related)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, result := range results {
+				key, refKey := string(result[assoc.fk]), string(result[assoc.refFk])
+				links[key] = append(links[key], refKey)
+				if !seen[refKey] {
+					seen[refKey] = true
+					arg, err := engine.pkOfStr(targetTable, targetPK, refKey)
+					if err != nil {
+						return nil, nil, err
+					}
+					targetArgs = append(targetArgs, arg)
+				}
+			}
+		}
+		targets, err := session.findRelated(assoc.target, targetPK.Name, targetArgs, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		byKey := make(map[string]reflect.Value)
+		for i := range targets {
+			v, err := colValueOfV(targetPK, &targets[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			byKey[assocKey(*v)] = targets[i]
+		}
+		return targets, func() {
+			for i, bean := range beans {
+				var linked []reflect.Value
+				for _, refKey := range links[keys[i]] {
+					if target, ok := byKey[refKey]; ok {
+						linked = append(linked, target)
+					}
+				}
+				setAssociation(bean.FieldByName(assoc.field), linked)
+			}
+		}, nil
+	}
+	return nil, nil, errors.New("unknown association")
+}
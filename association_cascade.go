@@ -0,0 +1,410 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// cascadeAssocs returns the associations of bean with option cascade(save)
+// or cascade(delete), bean should be a pointer to struct
+func (session *Session) cascadeAssocs(bean interface{}, isDelete bool) []*association {
+	v := reflect.ValueOf(bean)
+	if !session.Statement.UseCascade || v.Kind() != reflect.Ptr ||
+		v.Elem().Kind() != reflect.Struct || session.cascading[bean] {
+		return nil
+	}
+
+	ext := session.Engine.tableExt(session.Engine.autoMapType(v.Elem()))
+	names := make([]string, 0, len(ext.assocs))
+	for name, assoc := range ext.assocs {
+		if (isDelete && assoc.cascadeDelete) || (!isDelete && assoc.cascadeSave) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	assocs := make([]*association, len(names))
+	for i, name := range names {
+		assocs[i] = ext.assocs[name]
+	}
+	return assocs
+}
+
+// sliceCascades reports whether the elements of a slice inserted are saved
+// with their associations, the elements should be addressable.
+func (session *Session) sliceCascades(sliceValue reflect.Value) bool {
+	elem := sliceValue.Index(0)
+	if elem.Kind() != reflect.Ptr {
+		if !elem.CanAddr() {
+			return false
+		}
+		elem = elem.Addr()
+	}
+	return len(session.cascadeAssocs(elem.Interface(), false)) > 0
+}
+
+// markCascading marks the bean being saved or deleted with its associations,
+// so the associations referencing it back don't save it again.
+func (session *Session) markCascading(bean interface{}) func() {
+	if session.cascading == nil {
+		session.cascading = make(map[interface{}]bool)
+	}
+	session.cascading[bean] = true
+	return func() {
+		delete(session.cascading, bean)
+	}
+}
+
+// inTransaction runs op in the session's transaction, a transaction is begun
+// and committed if the session isn't in one.
+func (session *Session) inTransaction(op func() error) error {
+	if !session.IsAutoCommit {
+		return op()
+	}
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	defer func() {
+		session.IsAutoCommit = true
+		session.Tx = nil
+	}()
+
+	if err := op(); err != nil {
+		session.Rollback()
+		return err
+	}
+	return session.Commit()
+}
+
+// assocBeans returns the pointers of the records held by an association
+// field, false if the field isn't populated
+func assocBeans(field reflect.Value) ([]interface{}, bool) {
+	switch field.Kind() {
+	case reflect.Slice:
+		if field.IsNil() {
+			return nil, false
+		}
+		beans := make([]interface{}, 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			elem := field.Index(i)
+			if elem.Kind() != reflect.Ptr {
+				beans = append(beans, elem.Addr().Interface())
+			} else if !elem.IsNil() {
+				beans = append(beans, elem.Interface())
+			}
+		}
+		return beans, true
+	case reflect.Ptr:
+		if field.IsNil() {
+			return nil, false
+		}
+		return []interface{}{field.Interface()}, true
+	}
+	if isZeroValue(field) {
+		return nil, false
+	}
+	return []interface{}{field.Addr().Interface()}, true
+}
+
+// saveRelated inserts the record if its primary key is empty, or updates it
+func (session *Session) saveRelated(bean interface{}) error {
+	if session.cascading[bean] {
+		return nil
+	}
+	related := session.relatedSession()
+	defer session.closeRelated(related)
+
+	var err error
+	if pk := session.Engine.IdOf(bean); isPKZero(pk) {
+		_, err = related.Insert(bean)
+	} else {
+		_, err = related.Id(pk).Update(bean)
+	}
+	return err
+}
+
+// saveBelongs saves the records referenced by bean's belongs_to associations
+// and fills bean's foreign keys, they're saved before bean.
+func (session *Session) saveBelongs(table *core.Table, bean interface{}, assocs []*association) error {
+	v := rValue(bean)
+	for _, assoc := range assocs {
+		if assoc.kind != belongsTo {
+			continue
+		}
+		targets, ok := assocBeans(v.FieldByName(assoc.field))
+		if !ok {
+			continue
+		}
+		fkCol := table.GetColumn(assoc.fk)
+		if fkCol == nil {
+			return fmt.Errorf("table %v has no column %v", table.Name, assoc.fk)
+		}
+
+		if err := session.saveRelated(targets[0]); err != nil {
+			return err
+		}
+		fkField, err := colValueOfV(fkCol, &v)
+		if err != nil {
+			return err
+		}
+		if err = setGeneratedValue(fkField, session.Engine.IdOf(targets[0])[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveChildren saves the records of bean's has_one, has_many and many2many
+// associations, they're saved after bean to get its primary key. The links
+// of many2many are replaced if isUpdate.
+func (session *Session) saveChildren(bean interface{}, ownerKey interface{}, assocs []*association, isUpdate bool) error {
+	v := rValue(bean)
+	for _, assoc := range assocs {
+		if assoc.kind == belongsTo {
+			continue
+		}
+		children, ok := assocBeans(v.FieldByName(assoc.field))
+		if !ok {
+			continue
+		}
+
+		if assoc.kind == manyToMany {
+			if err := session.saveLinks(assoc, ownerKey, children, isUpdate); err != nil {
+				return err
+			}
+			continue
+		}
+
+		targetTable := session.Engine.autoMapType(reflect.New(assoc.target).Elem())
+		fkCol := targetTable.GetColumn(assoc.fk)
+		if fkCol == nil {
+			return fmt.Errorf("table %v has no column %v", targetTable.Name, assoc.fk)
+		}
+		for _, child := range children {
+			cv := rValue(child)
+			fkField, err := colValueOfV(fkCol, &cv)
+			if err != nil {
+				return err
+			}
+			if err = setGeneratedValue(fkField, ownerKey); err != nil {
+				return err
+			}
+			if err = session.saveRelated(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveLinks saves the targets of a many2many association and the rows of the
+// join table which link them to the owner
+func (session *Session) saveLinks(assoc *association, ownerKey interface{}, targets []interface{}, isUpdate bool) error {
+	for _, target := range targets {
+		if err := session.saveRelated(target); err != nil {
+			return err
+		}
+	}
+
+	engine := session.Engine
+	related := session.relatedSession()
+	defer session.closeRelated(related)
+	if isUpdate {
+		sqlStr := fmt.Sprintf("DELETE FROM %v WHERE %v = ?",
+			engine.Quote(assoc.joinTable), engine.Quote(assoc.fk))
+		if _, err := related.exec(sqlStr, ownerKey); err != nil {
+			return err
+		}
+	}
+	sqlStr := fmt.Sprintf("INSERT INTO %v (%v, %v) VALUES (?, ?)",
+		engine.Quote(assoc.joinTable), engine.Quote(assoc.fk), engine.Quote(assoc.refFk))
+	for _, target := range targets {
+		if _, err := related.exec(sqlStr, ownerKey, engine.IdOf(target)[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertCascade inserts bean and saves its associations with cascade(save)
+func (session *Session) insertCascade(bean interface{}, assocs []*association) (int64, error) {
+	table := session.Engine.autoMapType(rValue(bean))
+	var affected int64
+	err := session.inTransaction(func() error {
+		defer session.markCascading(bean)()
+
+		if err := session.saveBelongs(table, bean, assocs); err != nil {
+			return err
+		}
+		cnt, err := session.innerInsert(bean)
+		if err != nil {
+			return err
+		}
+		affected = cnt
+
+		// the children could not be linked without the primary key
+		pk := session.Engine.IdOf(bean)
+		if len(pk) != 1 {
+			return nil
+		}
+		return session.saveChildren(bean, pk[0], assocs, false)
+	})
+	return affected, err
+}
+
+// updateCascade updates bean and saves its associations with cascade(save)
+func (session *Session) updateCascade(bean interface{}, assocs []*association, condiBean ...interface{}) (int64, error) {
+	table := session.Engine.autoMapType(rValue(bean))
+	ownerPK := session.Engine.IdOf(bean)
+	if isPKZero(ownerPK) && session.Statement.IdParam != nil {
+		ownerPK = *session.Statement.IdParam
+	}
+
+	var affected int64
+	err := session.inTransaction(func() error {
+		defer session.markCascading(bean)()
+
+		if err := session.saveBelongs(table, bean, assocs); err != nil {
+			return err
+		}
+		isAutoClose := session.IsAutoClose
+		session.IsAutoClose = false
+		cnt, err := session.Update(bean, condiBean...)
+		session.IsAutoClose = isAutoClose
+		if err != nil {
+			return err
+		}
+		affected = cnt
+
+		// the children could not be linked without the primary key
+		if len(ownerPK) != 1 || isPKZero(ownerPK) {
+			return nil
+		}
+		return session.saveChildren(bean, ownerPK[0], assocs, true)
+	})
+	return affected, err
+}
+
+// deletedKeys returns the primary keys of the records which will be deleted
+func (session *Session) deletedKeys(table *core.Table, bean interface{}) ([]interface{}, error) {
+	st := &session.Statement
+	if pk := session.Engine.IdOf(bean); !isPKZero(pk) && st.IdParam == nil &&
+		st.WhereStr == "" && len(st.inColumns) == 0 {
+		return []interface{}{pk[0]}, nil
+	}
+
+	pkCol, err := singlePK(table)
+	if err != nil {
+		return nil, err
+	}
+	related := session.relatedSession()
+	defer session.closeRelated(related)
+	related.identities = nil // the records are read partly
+	related.Statement.WhereStr, related.Statement.Params = st.WhereStr, st.Params
+	related.Statement.IdParam, related.Statement.inColumns = st.IdParam, st.inColumns
+	if st.unscoped || st.forceDelete {
+		related.Unscoped()
+	}
+
+	owners := reflect.New(reflect.SliceOf(reflect.PtrTo(table.Type)))
+	if err := related.Cols(pkCol.Name).NoCascade().Find(owners.Interface(), bean); err != nil {
+		return nil, err
+	}
+	owners = owners.Elem()
+	keys := make([]interface{}, owners.Len())
+	for i := range keys {
+		keys[i] = session.Engine.IdOf(owners.Index(i).Interface())[0]
+	}
+	return keys, nil
+}
+
+// deleteCascade deletes the records of bean's associations with
+// cascade(delete) and then bean. The children of has_one and has_many are
+// deleted one by one so their processors are called, the links of many2many
+// are removed but the targets are kept.
+func (session *Session) deleteCascade(bean interface{}, assocs []*association) (int64, error) {
+	table := session.Engine.autoMapType(rValue(bean))
+	var affected int64
+	err := session.inTransaction(func() error {
+		defer session.markCascading(bean)()
+
+		keys, err := session.deletedKeys(table, bean)
+		if err != nil {
+			return err
+		}
+		for _, assoc := range assocs {
+			switch assoc.kind {
+			case hasOne, hasMany:
+				err = session.deleteChildren(assoc, keys)
+			case manyToMany:
+				err = session.deleteLinks(assoc, keys)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		isAutoClose := session.IsAutoClose
+		session.IsAutoClose = false
+		affected, err = session.Delete(bean)
+		session.IsAutoClose = isAutoClose
+		return err
+	})
+	return affected, err
+}
+
+func (session *Session) deleteChildren(assoc *association, keys []interface{}) error {
+	targetTable := session.Engine.autoMapType(reflect.New(assoc.target).Elem())
+	fkCol := targetTable.GetColumn(assoc.fk)
+	if fkCol == nil {
+		return fmt.Errorf("table %v has no column %v", targetTable.Name, assoc.fk)
+	}
+	forceDelete := session.Statement.forceDelete
+	children, err := session.findRelated(assoc.target, fkCol.Name, keys, forceDelete)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		bean := child.Addr().Interface()
+		related := session.relatedSession()
+		if forceDelete {
+			related.ForceDelete()
+		}
+		related.Statement.idCondOnly = true
+		_, err := related.Id(session.Engine.IdOf(bean)).Delete(bean)
+		session.closeRelated(related)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (session *Session) deleteLinks(assoc *association, keys []interface{}) error {
+	engine := session.Engine
+	related := session.relatedSession()
+	defer session.closeRelated(related)
+	limit := engine.inArgsLimit()
+	for start := 0; start < len(keys); start += limit {
+		end := start + limit
+		if end > len(keys) {
+			end = len(keys)
+		}
+		sqlStr := fmt.Sprintf("DELETE FROM %v WHERE %v IN (%v)",
+			engine.Quote(assoc.joinTable), engine.Quote(assoc.fk),
+			strings.TrimSuffix(strings.Repeat("?,", end-start), ","))
+		if _, err := related.exec(sqlStr, keys[start:end]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
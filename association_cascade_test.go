@@ -0,0 +1,59 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+)
+
+type CascadeAuthor struct {
+	Id    int64
+	Name  string
+	Books []*CascadeBook `xorm:"has_many(author_id) cascade(save,delete)"`
+}
+
+type CascadeBook struct {
+	Id       int64
+	AuthorId int64
+	Title    string
+	Inserted bool `xorm:"-"`
+}
+
+func (book *CascadeBook) BeforeInsert() {
+	book.Inserted = true
+}
+
+func TestCascadeSaveDelete(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(CascadeAuthor), new(CascadeBook)))
+
+	author := CascadeAuthor{Name: "a", Books: []*CascadeBook{{Title: "x"}, {Title: "y"}}}
+	_, err := engine.Insert(&author)
+	assertNoErr(t, err)
+	for _, book := range author.Books {
+		if book.Id == 0 || book.AuthorId != author.Id || !book.Inserted {
+			t.Errorf("the book is not inserted with the author: %+v", book)
+		}
+	}
+
+	author.Books[0].Title = "z"
+	author.Books = append(author.Books, &CascadeBook{Title: "w"})
+	_, err = engine.Id(author.Id).Update(&author)
+	assertNoErr(t, err)
+	var books []CascadeBook
+	assertNoErr(t, engine.Where("author_id = ?", author.Id).Asc("id").Find(&books))
+	if len(books) != 3 || books[0].Title != "z" || books[2].Title != "w" {
+		t.Errorf("the books are not saved with the author: %+v", books)
+	}
+
+	_, err = engine.Id(author.Id).Delete(&CascadeAuthor{})
+	assertNoErr(t, err)
+	cnt, err := engine.Count(new(CascadeBook))
+	assertNoErr(t, err)
+	if cnt != 0 {
+		t.Errorf("%d books are left after the author is deleted", cnt)
+	}
+}
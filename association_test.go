@@ -0,0 +1,93 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+)
+
+type AssocCustomer struct {
+	Id      int64
+	Name    string
+	Orders  []AssocOrder  `xorm:"has_many(customer_id)"`
+	Profile *AssocProfile `xorm:"has_one(customer_id)"`
+}
+
+type AssocProfile struct {
+	Id         int64
+	CustomerId int64
+	Bio        string
+}
+
+type AssocOrder struct {
+	Id         int64
+	CustomerId int64
+	Customer   *AssocCustomer `xorm:"belongs_to(customer_id)"`
+	Items      []*AssocItem   `xorm:"has_many(order_id)"`
+	Tags       []AssocTag     `xorm:"many2many(assoc_order_tag,order_id,tag_id)"`
+}
+
+type AssocItem struct {
+	Id      int64
+	OrderId int64
+	Name    string
+}
+
+type AssocTag struct {
+	Id   int64
+	Name string
+}
+
+type AssocOrderTag struct {
+	OrderId int64 `xorm:"pk"`
+	TagId   int64 `xorm:"pk"`
+}
+
+func TestPreload(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(AssocCustomer), new(AssocProfile), new(AssocOrder),
+		new(AssocItem), new(AssocTag), new(AssocOrderTag)))
+
+	_, err := engine.Insert(&AssocCustomer{Name: "a"}, &AssocCustomer{Name: "b"},
+		&AssocProfile{CustomerId: 1, Bio: "bio"},
+		&AssocOrder{CustomerId: 1}, &AssocOrder{CustomerId: 1}, &AssocOrder{CustomerId: 2},
+		&AssocItem{OrderId: 1, Name: "x"}, &AssocItem{OrderId: 1, Name: "y"}, &AssocItem{OrderId: 3, Name: "z"},
+		&AssocTag{Name: "t1"}, &AssocTag{Name: "t2"},
+		&AssocOrderTag{OrderId: 1, TagId: 1}, &AssocOrderTag{OrderId: 1, TagId: 2}, &AssocOrderTag{OrderId: 2, TagId: 2})
+	assertNoErr(t, err)
+
+	log.reset()
+	var customers []AssocCustomer
+	assertNoErr(t, engine.Preload("Profile", "Orders.Items", "Orders.Tags").Asc("id").Find(&customers))
+	if len(customers) != 2 {
+		t.Fatalf("found %d customers, expected 2", len(customers))
+	}
+	a, b := customers[0], customers[1]
+	if a.Profile == nil || a.Profile.Bio != "bio" || b.Profile != nil {
+		t.Errorf("the profiles are %+v, %+v", a.Profile, b.Profile)
+	}
+	if len(a.Orders) != 2 || len(b.Orders) != 1 {
+		t.Fatalf("the orders are %+v, %+v", a.Orders, b.Orders)
+	}
+	if len(a.Orders[0].Items) != 2 || len(a.Orders[1].Items) != 0 || len(b.Orders[0].Items) != 1 {
+		t.Errorf("the nested items are not loaded: %+v", customers)
+	}
+	if len(a.Orders[0].Tags) != 2 || len(a.Orders[1].Tags) != 1 || a.Orders[1].Tags[0].Name != "t2" {
+		t.Errorf("the tags are not loaded by the join table: %+v", a.Orders)
+	}
+	// one query of customers, one of each association and one more of the
+	// join table
+	if len(log.sqls) != 6 {
+		t.Errorf("expected 6 queries, got %d: %v", len(log.sqls), log.sqls)
+	}
+
+	var order AssocOrder
+	has, err := engine.Preload("Customer").Id(3).Get(&order)
+	assertNoErr(t, err)
+	if !has || order.Customer == nil || order.Customer.Name != "b" {
+		t.Errorf("the customer is not loaded: %+v", order)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// SetCascadeDepth sets how many levels of nested cascade fields are loaded,
+// the default is 1, which loads the cascade fields of the queried records
+// but not theirs. 0 disables cascade loading.
+func (engine *Engine) SetCascadeDepth(depth int) {
+	engine.cascadeDepth = depth
+}
+
+// inArgsLimit returns the max count of the arguments of an IN condition
+// which loads the related records, the others are loaded by more queries.
+func (engine *Engine) inArgsLimit() int {
+	switch engine.dialect.DBType() {
+	case core.SQLITE:
+		// 999 parameters, some are left for the other conditions
+		return 900
+	case core.ORACLE:
+		return 1000
+	case core.MSSQL:
+		return 2000
+	}
+	return 10000
+}
+
+// cascadeField is a cascade field waiting for the referenced record
+type cascadeField struct {
+	field reflect.Value
+	pk    interface{}
+}
+
+// cascadeBatch collects the cascade fields of the records read by Find, the
+// referenced records are loaded by one IN query per table instead of one
+// query per record.
+type cascadeBatch struct {
+	types  []reflect.Type
+	fields map[reflect.Type][]cascadeField
+}
+
+func newCascadeBatch() *cascadeBatch {
+	return &cascadeBatch{fields: make(map[reflect.Type][]cascadeField)}
+}
+
+func (batch *cascadeBatch) add(structType reflect.Type, field reflect.Value, pk interface{}) {
+	if _, ok := batch.fields[structType]; !ok {
+		batch.types = append(batch.types, structType)
+	}
+	batch.fields[structType] = append(batch.fields[structType], cascadeField{field, pk})
+}
+
+// setCascade sets the loaded record, a pointer, to a struct or pointer field
+func setCascade(field reflect.Value, loaded reflect.Value) {
+	if field.Kind() == reflect.Ptr {
+		field.Set(loaded)
+	} else {
+		field.Set(loaded.Elem())
+	}
+}
+
+// cascade loads the record referenced by a cascade field, it's delayed to
+// the end of Find if the records are read by batch.
+func (session *Session) cascade(field reflect.Value, pk core.PK) error {
+	if session.cascadeDeep >= session.Engine.cascadeDepth {
+		return nil
+	}
+	structType := field.Type()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if session.cascades != nil {
+		session.cascades.add(structType, field, pk[0])
+		return nil
+	}
+
+	loaded, err := session.cascadeGet(structType, pk)
+	if err != nil {
+		return err
+	}
+	setCascade(field, loaded)
+	return nil
+}
+
+// loadCascades loads the records referenced by the collected cascade fields
+func (session *Session) loadCascades(batch *cascadeBatch) error {
+	for _, structType := range batch.types {
+		fields := batch.fields[structType]
+		table := session.Engine.autoMapType(reflect.New(structType).Elem())
+		pkCol, err := singlePK(table)
+		if err != nil {
+			return err
+		}
+
+		loaded := make(map[string]reflect.Value)
+		var args []interface{}
+		for _, f := range fields {
+			key := fmt.Sprintf("%v", f.pk)
+			if _, ok := loaded[key]; ok {
+				continue
+			}
+			if session.useIdentities() {
+				if bean, ok := session.identityOf(table.Name, core.PK{f.pk}); ok {
+					loaded[key] = reflect.ValueOf(bean)
+					continue
+				}
+			}
+			loaded[key] = reflect.Value{}
+			args = append(args, f.pk)
+		}
+
+		targets, err := session.findRelated(structType, pkCol.Name, args, false)
+		if err != nil {
+			return err
+		}
+		for i := range targets {
+			v, err := colValueOfV(pkCol, &targets[i])
+			if err != nil {
+				return err
+			}
+			loaded[assocKey(*v)] = targets[i].Addr()
+		}
+
+		for _, f := range fields {
+			ptr := loaded[fmt.Sprintf("%v", f.pk)]
+			if !ptr.IsValid() {
+				return errors.New("cascade obj is not exist!")
+			}
+			setCascade(f.field, ptr)
+		}
+	}
+	return nil
+}
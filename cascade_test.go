@@ -0,0 +1,83 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type CascadeCountry struct {
+	Id   int64
+	Name string
+}
+
+type CascadeCity struct {
+	Id      int64
+	Name    string
+	Country *CascadeCountry `xorm:"country_id"`
+}
+
+type CascadeShop struct {
+	Id   int64
+	Name string
+	City *CascadeCity `xorm:"city_id"`
+}
+
+func TestCascadeBatch(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(CascadeCountry), new(CascadeCity), new(CascadeShop)))
+	_, err := engine.Insert(&CascadeCountry{Name: "c"})
+	assertNoErr(t, err)
+	country := &CascadeCountry{Id: 1}
+	_, err = engine.Insert(&CascadeCity{Name: "x", Country: country}, &CascadeCity{Name: "y", Country: country})
+	assertNoErr(t, err)
+	_, err = engine.Insert(&CascadeShop{Name: "a", City: &CascadeCity{Id: 1}},
+		&CascadeShop{Name: "b", City: &CascadeCity{Id: 2}}, &CascadeShop{Name: "c", City: &CascadeCity{Id: 1}})
+	assertNoErr(t, err)
+
+	log.reset()
+	var shops []CascadeShop
+	assertNoErr(t, engine.Asc("id").Find(&shops))
+	if len(shops) != 3 || shops[0].City == nil || shops[0].City.Name != "x" ||
+		shops[1].City.Name != "y" || shops[2].City.Name != "x" {
+		t.Fatalf("the cities are not loaded: %+v", shops)
+	}
+	// the cities are loaded by one query, their countries are not loaded
+	if len(log.sqls) != 2 {
+		t.Errorf("expected 2 queries, got %v", log.sqls)
+	}
+	if shops[0].City.Country != nil {
+		t.Errorf("the country is loaded out of the depth: %+v", shops[0].City.Country)
+	}
+
+	engine.SetCascadeDepth(2)
+	log.reset()
+	shops = nil
+	assertNoErr(t, engine.Asc("id").Find(&shops))
+	if len(shops) != 3 || shops[1].City.Country == nil || shops[1].City.Country.Name != "c" {
+		t.Errorf("the countries are not loaded: %+v", shops)
+	}
+	if len(log.sqls) != 3 {
+		t.Errorf("expected 3 queries, got %v", log.sqls)
+	}
+
+	engine.SetCascadeDepth(0)
+	log.reset()
+	shops = nil
+	assertNoErr(t, engine.Asc("id").Find(&shops))
+	if len(log.sqls) != 1 {
+		t.Errorf("expected 1 query without cascade, got %v", log.sqls)
+	}
+}
+
+func TestInArgsLimit(t *testing.T) {
+	engine := testEngine(t, core.SQLITE)
+	if limit := engine.inArgsLimit(); limit >= 999 {
+		t.Errorf("the limit %d is over sqlite's parameters", limit)
+	}
+}
@@ -0,0 +1,462 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// Check is a check constraint, which is declared by tag check(expr) or read
+// by DBMetas.
+type Check struct {
+	Name    string // empty for the declared ones, see XName
+	Col     string // the column declaring it, empty for the ones read by DBMetas
+	Expr    string
+	options string // enum or set if it keeps the values of column Col in its options
+}
+
+// XName returns the constraint name of the check in table tableName
+func (check *Check) XName(tableName string) string {
+	if check.Name != "" {
+		return check.Name
+	}
+	// the constraints are in the schema of the table
+	_, tableName = splitSchema(tableName, "")
+	if check.options != "" {
+		return fmt.Sprintf("CK_%v_%v_%v", tableName, check.Col, check.options)
+	}
+	return fmt.Sprintf("CK_%v_%v", tableName, check.Col)
+}
+
+// columnOptionDialect is implemented by the dialects which read the checks,
+// comments, generated columns and foreign keys of tables, DBMetas reads them
+// with the columns and the indexes
+type columnOptionDialect interface {
+	GetColumnOptions(tableName string) (*tableExt, error)
+}
+
+// Checks returns the check constraints of a mapped table, or of a table
+// returned by the last DBMetas
+func (engine *Engine) Checks(table *core.Table) []*Check {
+	return engine.tableExt(table).checks
+}
+
+// Comment returns the comment of table's column colName, or of table itself
+// if colName is empty
+func (engine *Engine) Comment(table *core.Table, colName string) string {
+	ext := engine.tableExt(table)
+	if colName == "" {
+		return ext.comment
+	}
+	if colExt := ext.column(colName); colExt != nil {
+		return colExt.comment
+	}
+	return ""
+}
+
+// Generated returns the expression of a generated column and whether it's
+// stored, the expression is empty if the column is not generated.
+func (engine *Engine) Generated(table *core.Table, colName string) (expr string, stored bool) {
+	if colExt := engine.tableExt(table).column(colName); colExt != nil {
+		return colExt.generated, colExt.stored
+	}
+	return "", false
+}
+
+// quoteComment quotes a comment as a string literal
+func quoteComment(comment string) string {
+	return "'" + strings.Replace(comment, "'", "''", -1) + "'"
+}
+
+// unquoteComment removes the quotes of tag comment('...')
+func unquoteComment(comment string) string {
+	if len(comment) >= 2 && comment[0] == '\'' && comment[len(comment)-1] == '\'' {
+		return strings.Replace(comment[1:len(comment)-1], "''", "'", -1)
+	}
+	return comment
+}
+
+// pgCastRegexp matches the type casts which postgres adds to expressions
+var pgCastRegexp = regexp.MustCompile(`::[a-z_]+(\[\])?`)
+
+// normalizeExpr removes what the databases add to the expressions of checks,
+// generated columns and indexes, i.e. spaces, parentheses, quotes and casts
+func normalizeExpr(expr string) string {
+	expr = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', '(', ')', '`', '"', '[', ']':
+			return -1
+		}
+		return r
+	}, strings.ToLower(expr))
+	return pgCastRegexp.ReplaceAllString(expr, "")
+}
+
+// parenBody returns what's in the first parentheses of s
+func parenBody(s string) string {
+	start := strings.Index(s, "(")
+	if start < 0 {
+		return ""
+	}
+	depth, inQuote := 0, false
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(s[start+1 : i])
+			}
+		}
+	}
+	return ""
+}
+
+// queryStrings runs a query of the dialects which read the column options,
+// NULL is read as the empty string
+func queryStrings(db *core.DB, logger core.ILogger, s string, args ...interface{}) ([][]string, error) {
+	rows, err := db.Query(s, args...)
+	if logger != nil {
+		logger.Info("[sql]", s, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]string, 0)
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result := make([]string, len(cols))
+		for i, value := range values {
+			result[i] = value.String
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// columnsEnd returns the position of the parenthesis closing the columns of
+// CREATE TABLE, -1 if there's none
+func columnsEnd(sqlStr string) int {
+	start := strings.Index(strings.ToUpper(sqlStr), "CREATE TABLE")
+	if start < 0 {
+		return -1
+	}
+	depth, inQuote := 0, false
+	for i := start; i < len(sqlStr); i++ {
+		switch c := sqlStr[i]; {
+		case c == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// columnSQL returns the definition of col with its generated expression,
+// identity and mysql's comment, withPk is false if the primary key is defined
+// by table.
+func (engine *Engine) columnSQL(table *core.Table, col *core.Column, withPk bool) string {
+	dialect := engine.dialect
+	dbType := dialect.DBType()
+	var generated, comment string
+	var stored bool
+	if colExt := engine.columnExt(table, col); colExt != nil {
+		generated, stored, comment = colExt.generated, colExt.stored, colExt.comment
+	}
+	// the values of a column backed by a sequence are not generated by the
+	// database's autoincrement, i.e. postgres' SERIAL
+	seqName := engine.sequenceName(table, col)
+	if seqName != "" && col.IsAutoIncrement {
+		c := *col
+		c.IsAutoIncrement = false
+		col = &c
+	}
+
+	sqlStr := engine.quoteName(col.Name) + " "
+	if generated != "" && dbType == core.MSSQL {
+		// a computed column has no type
+		sqlStr += "AS (" + generated + ") "
+		if stored {
+			sqlStr += "PERSISTED "
+		}
+	} else {
+		sqlStr += dialect.SqlType(col) + " "
+		if generated != "" {
+			switch {
+			case dbType == core.POSTGRES:
+				if !stored {
+					engine.LogWarnf("postgres has stored generated columns only, column %v is stored", col.Name)
+				}
+				stored = true
+			case dbType == core.ORACLE:
+				if stored {
+					engine.LogWarnf("oracle has virtual columns only, column %v is virtual", col.Name)
+				}
+				stored = false
+			}
+			sqlStr += "GENERATED ALWAYS AS (" + generated + ") "
+			if stored {
+				sqlStr += "STORED "
+			} else {
+				sqlStr += "VIRTUAL "
+			}
+		}
+	}
+
+	if withPk && col.IsPrimaryKey {
+		sqlStr += "PRIMARY KEY "
+		// postgres' autoincrement is its type SERIAL
+		if col.IsAutoIncrement && dialect.AutoIncrStr() != "" {
+			sqlStr += dialect.AutoIncrStr() + " "
+			// mssql's IDENTITY(start,increment)
+			if start, step := engine.autoIncrOf(table, col); dbType == core.MSSQL && (start != 0 || step != 0) {
+				if start == 0 {
+					start = 1
+				}
+				if step == 0 {
+					step = 1
+				}
+				sqlStr = strings.TrimSpace(sqlStr) + fmt.Sprintf("(%d,%d) ", start, step)
+			}
+		}
+	}
+	// a generated column has no default
+	switch {
+	case col.Default != "" && generated == "":
+		sqlStr += "DEFAULT " + col.Default + " "
+	case seqName != "" && dbType == core.POSTGRES:
+		sqlStr += "DEFAULT nextval(" + sqlString(seqName) + ") "
+	case seqName != "" && dbType == core.MSSQL:
+		sqlStr += "DEFAULT NEXT VALUE FOR " + engine.quoteName(seqName) + " "
+	}
+	if dialect.ShowCreateNull() && !(generated != "" && dbType == core.MSSQL) {
+		if col.Nullable {
+			sqlStr += "NULL "
+		} else {
+			sqlStr += "NOT NULL "
+		}
+	}
+	if comment != "" && dbType == core.MYSQL {
+		sqlStr += "COMMENT " + quoteComment(comment) + " "
+	}
+	return strings.TrimSpace(sqlStr)
+}
+
+// checkSQL returns the definition of a check which is used by CREATE TABLE
+// and ALTER TABLE
+func (engine *Engine) checkSQL(tableName string, check *Check) string {
+	return fmt.Sprintf("CONSTRAINT %v CHECK (%v)", engine.quoteName(check.XName(tableName)), check.Expr)
+}
+
+// commentSQL returns the sql which changes the comment of table tableName,
+// or of its column colName, from old to comment. It's empty for mysql, whose
+// comments belong to the definitions, and sqlite3, which has no comments.
+func (engine *Engine) commentSQL(tableName, colName, comment, old string) string {
+	tableName = engine.tbNameWithSchema(tableName)
+	switch engine.dialect.DBType() {
+	case core.POSTGRES, core.ORACLE:
+		target := "TABLE " + engine.Quote(tableName)
+		if colName != "" {
+			target = "COLUMN " + engine.Quote(tableName) + "." + engine.Quote(colName)
+		}
+		return fmt.Sprintf("COMMENT ON %v IS %v", target, quoteComment(comment))
+	case core.MSSQL:
+		proc := "sp_addextendedproperty"
+		if old != "" && comment != "" {
+			proc = "sp_updateextendedproperty"
+		} else if old != "" {
+			proc = "sp_dropextendedproperty"
+		}
+		args := []string{"@name = N'MS_Description'"}
+		if comment != "" {
+			args = append(args, "@value = N"+quoteComment(comment))
+		}
+		schema, name := splitSchema(tableName, "dbo")
+		args = append(args, "@level0type = N'SCHEMA'", "@level0name = N"+quoteComment(schema),
+			"@level1type = N'TABLE'", "@level1name = N"+quoteComment(name))
+		if colName != "" {
+			args = append(args, "@level2type = N'COLUMN'", "@level2name = N"+quoteComment(colName))
+		}
+		return "EXEC " + proc + " " + strings.Join(args, ", ")
+	}
+	return ""
+}
+
+// genCommentSQLs generates the sqls which set the comments after the table
+// is created
+func (statement *Statement) genCommentSQLs() []string {
+	engine := statement.Engine
+	table := statement.RefTable
+	tableName := statement.TableName()
+	ext := engine.tableExt(table)
+	sqls := make([]string, 0)
+
+	if ext.comment != "" {
+		if sqlStr := engine.commentSQL(tableName, "", ext.comment, ""); sqlStr != "" {
+			sqls = append(sqls, sqlStr)
+		}
+	}
+	for _, col := range table.Columns() {
+		colExt := ext.column(col.Name)
+		if colExt == nil || colExt.comment == "" {
+			continue
+		}
+		if sqlStr := engine.commentSQL(tableName, col.Name, colExt.comment, ""); sqlStr != "" {
+			sqls = append(sqls, sqlStr)
+		}
+	}
+	return sqls
+}
+
+// syncColumnOptions changes the comments and checks of table which differ
+// from oriTable, read from database. The generated columns are only warned
+// since they could not be altered.
+func (session *Session) syncColumnOptions(table, oriTable *core.Table) error {
+	engine := session.Engine
+	if _, ok := engine.dialect.(columnOptionDialect); !ok {
+		return nil
+	}
+	dbType := engine.dialect.DBType()
+	ext := engine.tableExt(table)
+	oriExt := engine.tableExt(oriTable)
+	tableName := engine.Quote(engine.tbNameWithSchema(table.Name))
+
+	var sqls []string
+	if dbType != core.SQLITE {
+		if ext.comment != oriExt.comment {
+			if dbType == core.MYSQL {
+				sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v COMMENT = %v", tableName, quoteComment(ext.comment)))
+			} else {
+				sqls = append(sqls, engine.commentSQL(table.Name, "", ext.comment, oriExt.comment))
+			}
+		}
+		for _, col := range table.Columns() {
+			comment, oriComment := engine.Comment(table, col.Name), engine.Comment(oriTable, col.Name)
+			if comment == oriComment {
+				continue
+			}
+			if dbType == core.MYSQL {
+				// the primary key is kept, but not its autoincrement
+				def := engine.columnSQL(table, col, false)
+				if col.IsAutoIncrement {
+					def += " " + engine.dialect.AutoIncrStr()
+				}
+				sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v MODIFY COLUMN %v", tableName, def))
+			} else {
+				sqls = append(sqls, engine.commentSQL(table.Name, col.Name, comment, oriComment))
+			}
+		}
+	}
+
+	for _, col := range table.Columns() {
+		if oriTable.GetColumn(col.Name) == nil {
+			continue
+		}
+		expr, stored := engine.Generated(table, col.Name)
+		oriExpr, oriStored := engine.Generated(oriTable, col.Name)
+		// postgres has stored ones only and oracle has virtual ones only
+		if dbType == core.POSTGRES || dbType == core.ORACLE {
+			stored = oriStored
+		}
+		if normalizeExpr(expr) != normalizeExpr(oriExpr) || (expr != "" && stored != oriStored) {
+			engine.LogWarnf("Table %s column %s db generated is %s, struct generated is %s",
+				table.Name, col.Name, oriExpr, expr)
+		}
+	}
+
+	var added, dropped []*Check
+	for _, check := range ext.checks {
+		var oriCheck *Check
+		for _, check2 := range oriExt.checks {
+			if strings.EqualFold(check.XName(table.Name), check2.Name) {
+				oriCheck = check2
+				break
+			}
+		}
+		if oriCheck == nil {
+			added = append(added, check)
+		} else if check.options != "" {
+			// the options of the enum or set are changed
+			if !sameOptions(check.Expr, oriCheck.Expr) {
+				dropped = append(dropped, oriCheck)
+				added = append(added, check)
+			}
+		} else if normalizeExpr(check.Expr) != normalizeExpr(oriCheck.Expr) {
+			engine.LogWarnf("Table %s check %s db is %s, struct is %s",
+				table.Name, oriCheck.Name, oriCheck.Expr, check.Expr)
+		}
+	}
+	// only the checks named by xorm are dropped
+	_, name := splitSchema(table.Name, "")
+	prefix := strings.ToLower("CK_" + name + "_")
+	for _, check2 := range oriExt.checks {
+		if !strings.HasPrefix(strings.ToLower(check2.Name), prefix) {
+			continue
+		}
+		found := false
+		for _, check := range ext.checks {
+			if strings.EqualFold(check.XName(table.Name), check2.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dropped = append(dropped, check2)
+		}
+	}
+
+	if len(added) > 0 || len(dropped) > 0 {
+		// sqlite3 could not alter the constraints of a table
+		if dbType == core.SQLITE {
+			return session.rebuildTable(table, oriTable)
+		}
+		for _, check := range dropped {
+			if dbType == core.MYSQL {
+				sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v DROP CHECK %v", tableName, engine.Quote(check.Name)))
+			} else {
+				sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v DROP CONSTRAINT %v", tableName, engine.Quote(check.Name)))
+			}
+		}
+		for _, check := range added {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v ADD %v", tableName, engine.checkSQL(table.Name, check)))
+		}
+	}
+
+	for _, sqlStr := range sqls {
+		if _, err := session.exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type OptionGroup struct {
+	Id int64
+}
+
+type OptionItem struct {
+	Id       int64
+	GroupId  int64   `xorm:"fk(option_group.id) on_delete(cascade)"`
+	Price    float64 `xorm:"check(price > 0) comment('the price')"`
+	SubTotal float64
+	Total    float64 `xorm:"generated(price * 2) stored"`
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	expected := map[core.DbType]string{
+		core.MYSQL: "CREATE TABLE IF NOT EXISTS `option_item` (`id` BIGINT(20) PRIMARY KEY AUTO_INCREMENT NOT NULL, " +
+			"`group_id` BIGINT(20) NULL, `price` DOUBLE NULL COMMENT 'the price', `sub_total` DOUBLE NULL, " +
+			"`total` DOUBLE GENERATED ALWAYS AS (price * 2) STORED NULL, " +
+			"CONSTRAINT `CK_option_item_price` CHECK (price > 0), " +
+			"CONSTRAINT `FK_option_item_group_id` FOREIGN KEY (`group_id`) REFERENCES `option_group` (`id`) ON DELETE CASCADE)",
+		core.POSTGRES: `CREATE TABLE IF NOT EXISTS "option_item" ("id" SERIAL PRIMARY KEY NOT NULL, ` +
+			`"group_id" BIGINT NULL, "price" DOUBLE PRECISION NULL, "sub_total" DOUBLE PRECISION NULL, ` +
+			`"total" DOUBLE PRECISION GENERATED ALWAYS AS (price * 2) STORED NULL, ` +
+			`CONSTRAINT "CK_option_item_price" CHECK (price > 0), ` +
+			`CONSTRAINT "FK_option_item_group_id" FOREIGN KEY ("group_id") REFERENCES "option_group" ("id") ON DELETE CASCADE)`,
+		core.MSSQL: `IF NOT EXISTS (SELECT [name] FROM sys.tables WHERE [object_id] = OBJECT_ID(N'option_item') ) ` +
+			`CREATE TABLE "option_item" ("id" BIGINT PRIMARY KEY IDENTITY NOT NULL, ` +
+			`"group_id" BIGINT NULL, "price" REAL NULL, "sub_total" REAL NULL, "total" AS (price * 2) PERSISTED, ` +
+			`CONSTRAINT "CK_option_item_price" CHECK (price > 0), ` +
+			`CONSTRAINT "FK_option_item_group_id" FOREIGN KEY ("group_id") REFERENCES "option_group" ("id") ON DELETE CASCADE);`,
+		core.ORACLE: "CREATE TABLE option_item (id NUMBER NOT NULL, group_id NUMBER NULL, price NUMBER NULL, sub_total NUMBER NULL, " +
+			"total NUMBER GENERATED ALWAYS AS (price * 2) VIRTUAL NULL, PRIMARY KEY (id), " +
+			"CONSTRAINT CK_option_item_price CHECK (price > 0), " +
+			"CONSTRAINT FK_option_item_group_id FOREIGN KEY (group_id) REFERENCES option_group (id) ON DELETE CASCADE)",
+		core.SQLITE: "CREATE TABLE IF NOT EXISTS `option_item` (`id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, " +
+			"`group_id` INTEGER NULL, `price` REAL NULL, `sub_total` REAL NULL, " +
+			"`total` REAL GENERATED ALWAYS AS (price * 2) STORED NULL, " +
+			"CONSTRAINT `CK_option_item_price` CHECK (price > 0), " +
+			"CONSTRAINT `FK_option_item_group_id` FOREIGN KEY (`group_id`) REFERENCES `option_group` (`id`) ON DELETE CASCADE)",
+	}
+	for dbType, sqlStr := range expected {
+		engine := testEngine(t, dbType)
+		session := engine.NewSession()
+		session.Statement.RefTable = engine.TableInfo(new(OptionItem))
+		assertSQL(t, sqlStr, session.Statement.genCreateTableSQL())
+		session.Close()
+	}
+}
+
+func TestColumnOptions(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(OptionGroup), new(OptionItem)))
+	// Sync2 again finds nothing changed
+	assertNoErr(t, engine.Sync2(new(OptionGroup), new(OptionItem)))
+
+	_, err := engine.Insert(&OptionGroup{Id: 1})
+	assertNoErr(t, err)
+	_, err = engine.Insert(&OptionItem{GroupId: 1, Price: 2})
+	assertNoErr(t, err)
+	if _, err = engine.Insert(&OptionItem{GroupId: 1, Price: -1}); err == nil {
+		t.Error("the check is not created")
+	}
+
+	var item OptionItem
+	has, err := engine.Get(&item)
+	assertNoErr(t, err)
+	if !has || item.Total != 4 {
+		t.Errorf("the generated column is not computed: %+v", item)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// Converter converts the values of a type which can't implement
+// core.Conversion, like decimal.Decimal, uuid.UUID or net.IP
+type Converter struct {
+	Type    reflect.Type
+	SQLType core.SQLType
+	ToDB    func(v interface{}) (interface{}, error)
+	FromDB  func(data []byte) (interface{}, error)
+}
+
+// RegisterConverter registers the converter of goType, which is a value of
+// the type like decimal.Decimal{}. The fields of the type and the pointers
+// to it are mapped to sqlType and converted by toDB and fromDB. A column
+// could use the converter of another type by tag `conv(decimal.Decimal)`,
+// or none by `conv(-)`.
+func (engine *Engine) RegisterConverter(goType interface{}, sqlType core.SQLType,
+	toDB func(v interface{}) (interface{}, error), fromDB func(data []byte) (interface{}, error)) {
+	t := reflect.TypeOf(goType)
+	conv := &Converter{Type: t, SQLType: sqlType, ToDB: toDB, FromDB: fromDB}
+
+	engine.mutex.Lock()
+	engine.converters[t] = conv
+	engine.mutex.Unlock()
+}
+
+// fieldConverter returns the converter of a field of type t, name is the
+// one of tag conv
+func (engine *Engine) fieldConverter(name string, t reflect.Type) *Converter {
+	switch name {
+	case "-":
+		return nil
+	case "":
+		if conv, ok := engine.converters[t]; ok {
+			return conv
+		}
+		if t.Kind() == reflect.Ptr {
+			return engine.converters[t.Elem()]
+		}
+		return nil
+	}
+	for goType, conv := range engine.converters {
+		if strings.EqualFold(goType.String(), name) {
+			return conv
+		}
+	}
+	return nil
+}
+
+// converterOf returns the converter of table's column whose field is of
+// type t, nil if it has none
+func (engine *Engine) converterOf(table *core.Table, col *core.Column, t reflect.Type) *Converter {
+	var name string
+	if table != nil && col != nil {
+		if colExt := engine.columnExt(table, col); colExt != nil {
+			name = colExt.converter
+		}
+	}
+
+	engine.mutex.RLock()
+	defer engine.mutex.RUnlock()
+	if len(engine.converters) == 0 {
+		return nil
+	}
+	return engine.fieldConverter(name, t)
+}
+
+// toDB converts the value of a field, the nil pointers are NULL
+func (conv *Converter) toDB(v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Ptr && v.Type() != conv.Type {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() != conv.Type {
+		if !v.Type().ConvertibleTo(conv.Type) {
+			return nil, fmt.Errorf("converter of %v can't convert %v", conv.Type, v.Type())
+		}
+		v = v.Convert(conv.Type)
+	}
+	return conv.ToDB(v.Interface())
+}
+
+// fromDB sets the field by the value converted from data
+func (conv *Converter) fromDB(fieldValue *reflect.Value, data []byte) error {
+	x, err := conv.FromDB(data)
+	if err != nil {
+		return err
+	}
+
+	target := *fieldValue
+	if target.Kind() == reflect.Ptr && target.Type() != conv.Type {
+		if x == nil {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+	if x == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(x)
+	if !v.Type().ConvertibleTo(target.Type()) {
+		return fmt.Errorf("converter of %v returned %v which can't be set to %v", conv.Type, v.Type(), target.Type())
+	}
+	target.Set(v.Convert(target.Type()))
+	return nil
+}
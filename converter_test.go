@@ -0,0 +1,87 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type ConvPoint struct {
+	X, Y int
+}
+
+type ConvHost struct {
+	Id     int64
+	Addr   net.IP
+	Origin ConvPoint
+	Spot   *ConvPoint
+}
+
+func registerConverters(engine *Engine) {
+	engine.RegisterConverter(net.IP{}, core.SQLType{core.Varchar, 45, 0},
+		func(v interface{}) (interface{}, error) {
+			return v.(net.IP).String(), nil
+		},
+		func(data []byte) (interface{}, error) {
+			return net.ParseIP(string(data)), nil
+		})
+	engine.RegisterConverter(ConvPoint{}, core.SQLType{core.Varchar, 32, 0},
+		func(v interface{}) (interface{}, error) {
+			p := v.(ConvPoint)
+			return fmt.Sprintf("%d,%d", p.X, p.Y), nil
+		},
+		func(data []byte) (interface{}, error) {
+			var p ConvPoint
+			_, err := fmt.Sscanf(string(data), "%d,%d", &p.X, &p.Y)
+			return p, err
+		})
+}
+
+func TestConverter(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	registerConverters(engine)
+
+	table := engine.TableInfo(new(ConvHost))
+	for _, name := range []string{"addr", "origin", "spot"} {
+		if col := table.GetColumn(name); col == nil || col.SQLType.Name != core.Varchar {
+			t.Errorf("expected %v a varchar, got %v", name, col)
+		}
+	}
+
+	assertNoErr(t, engine.Sync2(new(ConvHost)))
+	host := &ConvHost{Addr: net.ParseIP("10.0.0.1"), Origin: ConvPoint{1, 2}}
+	_, err := engine.Insert(host)
+	assertNoErr(t, err)
+	_, err = engine.Insert(&ConvHost{Addr: net.ParseIP("10.0.0.2"), Spot: &ConvPoint{3, 4}})
+	assertNoErr(t, err)
+
+	results, err := engine.Query("SELECT addr, origin, spot FROM conv_host WHERE id = ?", host.Id)
+	assertNoErr(t, err)
+	if string(results[0]["addr"]) != "10.0.0.1" || string(results[0]["origin"]) != "1,2" || results[0]["spot"] != nil {
+		t.Errorf("unexpected stored values %v", results[0])
+	}
+
+	_, err = engine.Id(host.Id).Update(&ConvHost{Origin: ConvPoint{5, 6}})
+	assertNoErr(t, err)
+
+	var hosts []ConvHost
+	assertNoErr(t, engine.Find(&hosts, &ConvHost{Addr: net.ParseIP("10.0.0.1")}))
+	if len(hosts) != 1 || !hosts[0].Addr.Equal(net.ParseIP("10.0.0.1")) ||
+		hosts[0].Origin != (ConvPoint{5, 6}) || hosts[0].Spot != nil {
+		t.Errorf("unexpected hosts %+v", hosts)
+	}
+
+	got := new(ConvHost)
+	has, err := engine.Where("addr = ?", "10.0.0.2").Get(got)
+	assertNoErr(t, err)
+	if !has || got.Spot == nil || *got.Spot != (ConvPoint{3, 4}) {
+		t.Errorf("unexpected host %+v", got)
+	}
+}
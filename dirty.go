@@ -0,0 +1,150 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// Change is a column of a tracked bean which is changed since it's loaded
+type Change struct {
+	Column string
+	Old    interface{}
+	New    interface{}
+}
+
+// TrackChanges makes the session snapshot the beans loaded by Get and Find,
+// then Update sends only the changed columns of them, zero values included,
+// and skips the beans which are not changed.
+func (session *Session) TrackChanges() *Session {
+	session.trackChanges = true
+	if session.snapshots == nil {
+		session.snapshots = make(map[interface{}]map[string]interface{})
+	}
+	return session
+}
+
+// isTracked reports whether col is compared for changes
+func isTracked(col *core.Column) bool {
+	return col.MapType != core.ONLYFROMDB && !col.IsPrimaryKey && !col.IsVersion &&
+		!col.IsCreated && !col.IsUpdated && !col.IsDeleted
+}
+
+// snapshotValues returns the values of bean's tracked columns as they're
+// sent to database
+func (session *Session) snapshotValues(table *core.Table, bean interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, col := range table.Columns() {
+		if !isTracked(col) {
+			continue
+		}
+		fieldValue, err := colValueOf(col, bean)
+		if err != nil {
+			return nil, err
+		}
+		v, err := session.value2Interface(col, *fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		// blobs share the memory with the field
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		values[col.Name] = v
+	}
+	return values, nil
+}
+
+// snapshot remembers the values of a loaded bean, bean should be a pointer
+func (session *Session) snapshot(bean interface{}) {
+	if !session.trackChanges || reflect.ValueOf(bean).Kind() != reflect.Ptr {
+		return
+	}
+	table := session.Engine.autoMapType(rValue(bean))
+	values, err := session.snapshotValues(table, bean)
+	if err != nil {
+		session.Engine.LogError(err)
+		return
+	}
+	session.snapshots[bean] = values
+}
+
+// snapshotSlice remembers the elements of a loaded slice
+func (session *Session) snapshotSlice(rowsSlicePtr interface{}) {
+	if !session.trackChanges {
+		return
+	}
+	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
+	if sliceValue.Kind() != reflect.Slice {
+		return
+	}
+	for i := 0; i < sliceValue.Len(); i++ {
+		elem := sliceValue.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			session.snapshot(elem.Interface())
+		} else if elem.Kind() == reflect.Struct {
+			session.snapshot(elem.Addr().Interface())
+		}
+	}
+}
+
+// Changes returns the changed columns of a bean tracked by TrackChanges, the
+// second result is false if the bean is not tracked.
+func (session *Session) Changes(bean interface{}) ([]Change, bool, error) {
+	// the snapshots are kept by pointers, the other beans may be unhashable
+	if reflect.ValueOf(bean).Kind() != reflect.Ptr {
+		return nil, false, nil
+	}
+	old, ok := session.snapshots[bean]
+	if !ok {
+		return nil, false, nil
+	}
+	table := session.Engine.autoMapType(rValue(bean))
+	values, err := session.snapshotValues(table, bean)
+	if err != nil {
+		return nil, true, err
+	}
+
+	changes := make([]Change, 0)
+	for _, col := range table.Columns() {
+		v, ok := values[col.Name]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(old[col.Name], v) {
+			changes = append(changes, Change{col.Name, old[col.Name], v})
+		}
+	}
+	return changes, true, nil
+}
+
+// changedCols returns the SET list of the changed columns, the version,
+// created and updated columns are left to Update which sets them itself
+func (session *Session) changedCols(table *core.Table, bean interface{}, changes []Change) ([]string, []interface{}, error) {
+	colNames := make([]string, 0, len(changes))
+	args := make([]interface{}, 0, len(changes))
+	for _, change := range changes {
+		col := table.GetColumn(change.Column)
+		if col == nil || !isTracked(col) {
+			continue
+		}
+		fieldValue, err := colValueOf(col, bean)
+		if err != nil {
+			return nil, nil, err
+		}
+		arg, err := session.value2Interface(col, *fieldValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		colNames = append(colNames, session.Engine.Quote(col.Name)+" = ?")
+		args = append(args, arg)
+	}
+	return colNames, args, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type DirtyDoc struct {
+	Id      int64
+	Name    string
+	Count   int
+	Ver     int       `xorm:"version"`
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+func TestTrackChanges(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(DirtyDoc)))
+	_, err := engine.Insert(&DirtyDoc{Name: "a", Count: 1})
+	assertNoErr(t, err)
+
+	session := engine.NewSession().TrackChanges()
+	defer session.Close()
+	var doc DirtyDoc
+	has, err := session.Get(&doc)
+	assertNoErr(t, err)
+	if !has {
+		t.Fatal("no record")
+	}
+	created := doc.Created
+
+	// nothing changed, nothing updated
+	log.reset()
+	cnt, err := session.Update(&doc)
+	assertNoErr(t, err)
+	if cnt != 0 || len(log.sqls) != 0 {
+		t.Errorf("the unchanged bean is updated: %v", log.sqls)
+	}
+
+	doc.Count = 0
+	cnt, err = session.Update(&doc)
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Fatalf("updated %d records, expected 1", cnt)
+	}
+	if len(log.sqls) != 1 {
+		t.Fatalf("expected 1 sql, got %v", log.sqls)
+	}
+	sqlStr := strings.SplitN(log.sqls[0], " [args]", 2)[0]
+	assertSQL(t, "UPDATE `dirty_doc` SET `count` = ?, `updated` = ?, `ver` = `ver` + 1 WHERE (`id` = ?) AND `ver` = ?", sqlStr)
+	if doc.Ver != 2 {
+		t.Errorf("the version is %d, expected 2", doc.Ver)
+	}
+
+	var got DirtyDoc
+	has, err = engine.Id(doc.Id).Get(&got)
+	assertNoErr(t, err)
+	if !has || got.Count != 0 || got.Name != "a" || got.Ver != 2 || !got.Created.Equal(created) {
+		t.Errorf("the updated record is %+v, created at %v", got, created)
+	}
+
+	// maps are not tracked
+	_, err = session.Table(new(DirtyDoc)).Id(doc.Id).Update(map[string]interface{}{"name": "b"})
+	assertNoErr(t, err)
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+)
+
+// TableNameResolver is implemented by the beans whose table depends on the
+// record, like log_202610 of a time-partitioned log. ResolveTableName is
+// called by Insert, Get, Update and Delete for each bean with the session,
+// the columns are still mapped once by the type. An empty name means the
+// mapped table, and Table() has the priority.
+type TableNameResolver interface {
+	ResolveTableName(session *Session) string
+}
+
+// SetValue binds a value to the session for TableNameResolver, it lasts
+// until the session is closed.
+func (session *Session) SetValue(key string, value interface{}) *Session {
+	if session.values == nil {
+		session.values = make(map[string]interface{})
+	}
+	session.values[key] = value
+	return session
+}
+
+// Value returns the value bound by SetValue, nil if none
+func (session *Session) Value(key string) interface{} {
+	return session.values[key]
+}
+
+// resolveTableName returns the table of the bean resolved by itself, empty
+// if it's not a TableNameResolver
+func (session *Session) resolveTableName(bean interface{}) string {
+	if resolver, ok := bean.(TableNameResolver); ok {
+		return resolver.ResolveTableName(session)
+	}
+	if v := reflect.ValueOf(bean); v.Kind() == reflect.Struct {
+		// the method may be declared on the pointer
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		if resolver, ok := ptr.Interface().(TableNameResolver); ok {
+			return resolver.ResolveTableName(session)
+		}
+	}
+	return ""
+}
+
+// useBeanTable makes the statement use the table resolved by the bean
+func (session *Session) useBeanTable(bean interface{}) {
+	session.Statement.beanTableName = session.resolveTableName(bean)
+}
+
+// groupByTable groups the elements of a slice by their resolved tables, in
+// the order the tables first appear. The groups are slices of pointers to
+// the elements, so the generated values are set to the elements. It returns
+// nil if all the elements are in the same table.
+func (session *Session) groupByTable(sliceValue reflect.Value) []reflect.Value {
+	elemType := sliceValue.Type().Elem()
+	if elemType.Kind() != reflect.Ptr {
+		elemType = reflect.PtrTo(elemType)
+	}
+	if !elemType.Implements(reflect.TypeOf((*TableNameResolver)(nil)).Elem()) {
+		return nil
+	}
+
+	names := make([]string, 0)
+	groups := make(map[string]reflect.Value)
+	for i := 0; i < sliceValue.Len(); i++ {
+		elem := sliceValue.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		name := session.resolveTableName(elem.Interface())
+		group, ok := groups[name]
+		if !ok {
+			names = append(names, name)
+			group = reflect.MakeSlice(reflect.SliceOf(elemType), 0, 1)
+		}
+		groups[name] = reflect.Append(group, elem)
+	}
+	if len(names) < 2 {
+		return nil
+	}
+
+	sorted := make([]reflect.Value, len(names))
+	for i, name := range names {
+		sorted[i] = groups[name]
+	}
+	return sorted
+}
@@ -0,0 +1,86 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+)
+
+type DynLog struct {
+	Id    int64
+	Msg   string
+	Month string `xorm:"-"`
+}
+
+func (l *DynLog) ResolveTableName(session *Session) string {
+	month := l.Month
+	if month == "" {
+		month, _ = session.Value("month").(string)
+	}
+	if month == "" {
+		return ""
+	}
+	return "dyn_log_" + month
+}
+
+func TestResolveTableName(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	for _, name := range []string{"dyn_log_202609", "dyn_log_202610"} {
+		assertNoErr(t, engine.Table(name).CreateTable(new(DynLog)))
+	}
+
+	logs := []*DynLog{{Msg: "a", Month: "202609"}, {Msg: "b", Month: "202610"}, {Msg: "c", Month: "202610"}}
+	affected, err := engine.Insert(&logs)
+	assertNoErr(t, err)
+	if affected != 3 {
+		t.Errorf("expected 3 inserted, got %v", affected)
+	}
+	count := func(name string) int64 {
+		n, err := engine.Table(name).Count(new(DynLog))
+		assertNoErr(t, err)
+		return n
+	}
+	if count("dyn_log_202609") != 1 || count("dyn_log_202610") != 2 {
+		t.Fatalf("expected the rows in their months, got %v and %v", count("dyn_log_202609"), count("dyn_log_202610"))
+	}
+
+	_, err = engine.Insert(&DynLog{Msg: "d", Month: "202609"})
+	assertNoErr(t, err)
+	if count("dyn_log_202609") != 2 {
+		t.Errorf("expected 2 rows in 202609, got %v", count("dyn_log_202609"))
+	}
+
+	log := &DynLog{Month: "202610"}
+	has, err := engine.Id(2).Get(log)
+	assertNoErr(t, err)
+	if !has || log.Msg != "c" {
+		t.Errorf("expected c of 202610, got %v %v", has, log.Msg)
+	}
+
+	_, err = engine.Id(1).Update(&DynLog{Msg: "x", Month: "202610"})
+	assertNoErr(t, err)
+	log = new(DynLog)
+	has, err = engine.Table("dyn_log_202609").Id(1).Get(log)
+	assertNoErr(t, err)
+	if !has || log.Msg != "a" {
+		t.Errorf("expected 202609 not updated, got %v", log.Msg)
+	}
+	log = new(DynLog)
+	has, err = engine.Table("dyn_log_202610").Id(1).Get(log)
+	assertNoErr(t, err)
+	if !has || log.Msg != "x" {
+		t.Errorf("expected 202610 updated, got %v", log.Msg)
+	}
+
+	// the month is bound to the session
+	session := engine.NewSession()
+	defer session.Close()
+	_, err = session.SetValue("month", "202610").Id(1).Delete(new(DynLog))
+	assertNoErr(t, err)
+	if count("dyn_log_202609") != 2 || count("dyn_log_202610") != 1 {
+		t.Errorf("expected one row of 202610 deleted, got %v and %v", count("dyn_log_202609"), count("dyn_log_202610"))
+	}
+}
@@ -33,6 +33,10 @@ type Engine struct {
 	TagIdentifier string
 	Tables        map[reflect.Type]*core.Table
 
+	tableExts    map[*core.Table]*tableExt
+	pkGenerators map[string]PKGenerator
+	converters   map[reflect.Type]*Converter
+
 	mutex  *sync.RWMutex
 	Cacher core.Cacher
 
@@ -49,6 +53,12 @@ type Engine struct {
 	TZLocation *time.Location
 
 	disableGlobalCache bool
+	cascadeDepth       int
+	schema             string
+	fulltextConfig     string
+	sqliteVersion      string // read once by hasReturning
+
+	metaTables []*core.Table // the tables read by the last DBMetas
 }
 
 func (engine *Engine) SetLogger(logger core.ILogger) {
@@ -102,10 +112,11 @@ func (engine *Engine) Quote(sql string) string {
 	if len(sql) == 0 {
 		return sql
 	}
-	if string(sql[0]) == engine.dialect.QuoteStr() || sql[0] == '`' {
-		return sql
+	// oracle's quote is blank
+	if strings.TrimSpace(engine.dialect.QuoteStr()) == "" {
+		return engine.dialect.QuoteStr() + engine.quoteName(sql) + engine.dialect.QuoteStr()
 	}
-	return engine.dialect.QuoteStr() + sql + engine.dialect.QuoteStr()
+	return engine.quoteName(sql)
 }
 
 // A simple wrapper to dialect's core.SqlType method
@@ -152,6 +163,13 @@ func (engine *Engine) NoCascade() *Session {
 	return session.NoCascade()
 }
 
+// Preload loads the associations declared by tags after Get or Find
+func (engine *Engine) Preload(fields ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Preload(fields...)
+}
+
 // Set a table use a special cacher
 func (engine *Engine) MapCacher(bean interface{}, cacher core.Cacher) {
 	v := rValue(bean)
@@ -326,11 +344,20 @@ func (engine *Engine) DBMetas() ([]*core.Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = engine.loadMetas(tables, true); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
 
+// loadMetas reads the columns, the indexes and the exts of tables, replace
+// means they replace the tables of the last DBMetas, or they're kept with
+// them.
+func (engine *Engine) loadMetas(tables []*core.Table, replace bool) error {
 	for _, table := range tables {
 		colSeq, cols, err := engine.dialect.GetColumns(table.Name)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		for _, name := range colSeq {
 			table.AddColumn(cols[name])
@@ -339,7 +366,7 @@ func (engine *Engine) DBMetas() ([]*core.Table, error) {
 		//table.ColumnsSeq = colSeq
 		indexes, err := engine.dialect.GetIndexes(table.Name)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		table.Indexes = indexes
 
@@ -348,12 +375,49 @@ func (engine *Engine) DBMetas() ([]*core.Table, error) {
 				if col := table.GetColumn(name); col != nil {
 					col.Indexes[index.Name] = true
 				} else {
-					return nil, fmt.Errorf("Unknown col "+name+" in indexes %v of table", index, table.ColumnsSeq())
+					return fmt.Errorf("Unknown col "+name+" in indexes %v of table", index, table.ColumnsSeq())
 				}
 			}
 		}
 	}
-	return tables, nil
+
+	optionDialect, hasOptions := engine.dialect.(columnOptionDialect)
+	indexDialect, hasIndexOptions := engine.dialect.(indexOptionDialect)
+	if !hasOptions && !hasIndexOptions {
+		return nil
+	}
+
+	var err error
+	exts := make(map[*core.Table]*tableExt)
+	for _, table := range tables {
+		ext := newTableExt()
+		if hasOptions {
+			if ext, err = optionDialect.GetColumnOptions(table.Name); err != nil {
+				return err
+			}
+		}
+		if hasIndexOptions {
+			if ext.indexes, err = indexDialect.GetIndexOptions(table.Name); err != nil {
+				return err
+			}
+		}
+		exts[table] = ext
+	}
+
+	// only the exts of the last DBMetas are kept
+	engine.mutex.Lock()
+	if replace {
+		for _, table := range engine.metaTables {
+			delete(engine.tableExts, table)
+		}
+		engine.metaTables = nil
+	}
+	engine.metaTables = append(engine.metaTables, tables...)
+	for table, ext := range exts {
+		engine.tableExts[table] = ext
+	}
+	engine.mutex.Unlock()
+	return nil
 }
 
 /*
@@ -369,7 +433,8 @@ func (engine *Engine) DumpAllToFile(fp string) error {
 }
 
 /*
-dump database all table structs and data to w
+dump database all table structs and data to w, the tables are in the schema
+set by SetSchema
 */
 func (engine *Engine) DumpAll(w io.Writer) error {
 	tables, err := engine.DBMetas()
@@ -378,18 +443,27 @@ func (engine *Engine) DumpAll(w io.Writer) error {
 	}
 
 	for _, table := range tables {
-		_, err = io.WriteString(w, engine.dialect.CreateTableSql(table, "", table.StoreEngine, "")+"\n\n")
-		if err != nil {
-			return err
+		tbName := engine.tbNameWithSchema(table.Name)
+		session := engine.NewSession()
+		session.Statement.RefTable = table
+		session.Statement.StoreEngine = table.StoreEngine
+		sqls := []string{session.Statement.createTableSQL(tbName)}
+		sqls = append(sqls, session.Statement.genCommentSQLs()...)
+		session.Close()
+		for _, sqlStr := range sqls {
+			_, err = io.WriteString(w, sqlStr+"\n\n")
+			if err != nil {
+				return err
+			}
 		}
 		for _, index := range table.Indexes {
-			_, err = io.WriteString(w, engine.dialect.CreateIndexSql(table.Name, index)+"\n\n")
+			_, err = io.WriteString(w, engine.indexSQL(tbName, table, index)+"\n\n")
 			if err != nil {
 				return err
 			}
 		}
 
-		rows, err := engine.DB().Query("SELECT * FROM " + engine.Quote(table.Name))
+		rows, err := engine.DB().Query("SELECT * FROM " + engine.Quote(tbName))
 		if err != nil {
 			return err
 		}
@@ -401,6 +475,13 @@ func (engine *Engine) DumpAll(w io.Writer) error {
 		if len(cols) == 0 {
 			continue
 		}
+		// the generated columns could not be inserted
+		insertCols := make([]string, 0, len(cols))
+		for _, colName := range cols {
+			if expr, _ := engine.Generated(table, colName); expr == "" {
+				insertCols = append(insertCols, colName)
+			}
+		}
 		for rows.Next() {
 			dest := make([]interface{}, len(cols))
 			err = rows.ScanSlice(&dest)
@@ -408,7 +489,7 @@ func (engine *Engine) DumpAll(w io.Writer) error {
 				return err
 			}
 
-			_, err = io.WriteString(w, "INSERT INTO "+engine.Quote(table.Name)+" ("+engine.Quote(strings.Join(cols, engine.Quote(", ")))+") VALUES (")
+			_, err = io.WriteString(w, "INSERT INTO "+engine.Quote(tbName)+" ("+engine.quoteColumns(insertCols...)+") VALUES (")
 			if err != nil {
 				return err
 			}
@@ -416,6 +497,9 @@ func (engine *Engine) DumpAll(w io.Writer) error {
 			var temp string
 			for i, d := range dest {
 				col := table.GetColumn(cols[i])
+				if expr, _ := engine.Generated(table, cols[i]); expr != "" {
+					continue
+				}
 				if d == nil {
 					temp += ", NULL"
 				} else if col.SQLType.IsText() || col.SQLType.IsTime() {
@@ -553,6 +637,13 @@ func (engine *Engine) Omit(columns ...string) *Session {
 	return session.Omit(columns...)
 }
 
+// Returning reads the columns back into the bean after Insert and Update
+func (engine *Engine) Returning(columns ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Returning(columns...)
+}
+
 // Set null when column is zero-value and nullable for update
 func (engine *Engine) Nullable(columns ...string) *Session {
 	session := engine.NewSession()
@@ -685,6 +776,20 @@ func (engine *Engine) TableInfo(bean interface{}) *core.Table {
 	return engine.autoMapType(v)
 }
 
+// tableByName returns the mapped table named tableName, which may be
+// qualified by the schema, nil if no bean is mapped to it
+func (engine *Engine) tableByName(tableName string) *core.Table {
+	_, name := splitSchema(tableName, "")
+	engine.mutex.RLock()
+	defer engine.mutex.RUnlock()
+	for _, table := range engine.Tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
 func addIndex(indexName string, table *core.Table, col *core.Column, indexType int) {
 	if index, ok := table.Indexes[indexName]; ok {
 		index.AddColumn(col.Name)
@@ -730,6 +835,19 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 
 	var idFieldColName string
 	var err error
+	ext := newTableExt()
+	indexDefs := make(map[string]*Index)
+
+	method = v.MethodByName("TableComment")
+	if !method.IsValid() && v.CanAddr() {
+		method = v.Addr().MethodByName("TableComment")
+	}
+	if method.IsValid() {
+		results := method.Call([]reflect.Value{})
+		if len(results) == 1 {
+			ext.comment, _ = results[0].Interface().(string)
+		}
+	}
 
 	hasCacheTag := false
 	hasNoCacheTag := false
@@ -745,44 +863,45 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 		if ormTagStr != "" {
 			col = &core.Column{FieldName: t.Field(i).Name, Nullable: true, IsPrimaryKey: false,
 				IsAutoIncrement: false, MapType: core.TWOSIDES, Indexes: make(map[string]bool)}
-			tags := strings.Split(ormTagStr, " ")
+			tags := splitTopLevel(ormTagStr, ' ')
 
 			if len(tags) > 0 {
 				if tags[0] == "-" {
 					continue
 				}
+				if assoc, err := engine.parseAssociation(t, t.Field(i), tags); err != nil {
+					engine.LogError(err)
+					continue
+				} else if assoc != nil {
+					ext.assocs[assoc.field] = assoc
+					continue
+				}
 				if strings.ToUpper(tags[0]) == "EXTENDS" {
-					if fieldValue.Kind() == reflect.Struct {
-						parentTable := engine.mapType(fieldValue)
-						for _, col := range parentTable.Columns() {
-							col.FieldName = fmt.Sprintf("%v.%v", t.Field(i).Name, col.FieldName)
-							table.AddColumn(col)
-						}
-
-						continue
-					} else if fieldValue.Kind() == reflect.Ptr {
-						f := fieldValue.Type().Elem()
-						if f.Kind() == reflect.Struct {
-							fieldValue = fieldValue.Elem()
-							if !fieldValue.IsValid() || fieldValue.IsNil() {
-								fieldValue = reflect.New(f).Elem()
-							}
-						}
-
-						parentTable := engine.mapType(fieldValue)
-						for _, col := range parentTable.Columns() {
-							col.FieldName = fmt.Sprintf("%v.%v", t.Field(i).Name, col.FieldName)
-							table.AddColumn(col)
+					var prefix string
+					for _, key := range tags[1:] {
+						if k := strings.ToUpper(key); strings.HasPrefix(k, "PREFIX(") && strings.HasSuffix(k, ")") {
+							prefix = strings.Trim(key[len("PREFIX")+1:len(key)-1], "'")
 						}
-
+					}
+					if engine.mapExtends(table, ext, t.Field(i), fieldValue, prefix) {
 						continue
 					}
 					//TODO: warning
 				}
 
 				indexNames := make(map[string]int)
-				var isIndex, isUnique bool
+				indexOrders := make(map[string]string)
+				indexExprs := make(map[string]string)
+				var isIndex, isUnique, isFulltext bool
 				var preKey string
+				var generator, sequence string
+				var autoIncrStart, autoIncrStep int64
+				var isDeletedBy, isTenant bool
+				var fkRef, onDelete, onUpdate string
+				var check, comment, generated string
+				var stored bool
+				var convName string
+				var isArray bool
 				for j, key := range tags {
 					k := strings.ToUpper(key)
 					switch {
@@ -799,16 +918,25 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 						} else {
 							col.Nullable = (strings.ToUpper(tags[j-1]) != "NOT")
 						}
-					// TODO: for postgres how add autoincr?
-					/*case strings.HasPrefix(k, "AUTOINCR(") && strings.HasSuffix(k, ")"):
-					col.IsAutoIncrement = true
-
-					autoStart := k[len("AUTOINCR")+1 : len(k)-1]
-					autoStartInt, err := strconv.Atoi(autoStart)
-					if err != nil {
-						engine.LogError(err)
-					}
-					col.AutoIncrStart = autoStartInt*/
+					case strings.HasPrefix(k, "AUTOINCR(") && strings.HasSuffix(k, ")"):
+						col.IsAutoIncrement = true
+						fs := strings.Split(k[len("AUTOINCR")+1:len(k)-1], ",")
+						autoIncrStart, err = strconv.ParseInt(strings.TrimSpace(fs[0]), 10, 64)
+						if err != nil {
+							engine.LogError(err)
+						}
+						if len(fs) > 1 {
+							autoIncrStep, err = strconv.ParseInt(strings.TrimSpace(fs[1]), 10, 64)
+							if err != nil {
+								engine.LogError(err)
+							}
+						}
+					case strings.HasPrefix(k, "SEQ(") && strings.HasSuffix(k, ")"):
+						sequence = key[len("SEQ")+1 : len(key)-1]
+						if !supportSequence(engine.dialect.DBType()) {
+							// fall back to autoincrement
+							col.IsAutoIncrement = true
+						}
 					case k == "AUTOINCR":
 						col.IsAutoIncrement = true
 						//col.AutoIncrStart = 1
@@ -823,16 +951,50 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 						col.IsUpdated = true
 					case k == "DELETED":
 						col.IsDeleted = true
+					case k == "DELETED_BY":
+						isDeletedBy = true
+					case k == "TENANT":
+						isTenant = true
+					case strings.HasPrefix(k, "FK(") && strings.HasSuffix(k, ")"):
+						fkRef = key[len("FK")+1 : len(key)-1]
+					case strings.HasPrefix(k, "ON_DELETE(") && strings.HasSuffix(k, ")"):
+						onDelete = fkAction(k[len("ON_DELETE")+1 : len(k)-1])
+					case strings.HasPrefix(k, "ON_UPDATE(") && strings.HasSuffix(k, ")"):
+						onUpdate = fkAction(k[len("ON_UPDATE")+1 : len(k)-1])
+					case strings.HasPrefix(k, "CHECK(") && strings.HasSuffix(k, ")"):
+						check = key[len("CHECK")+1 : len(key)-1]
+					case strings.HasPrefix(k, "COMMENT(") && strings.HasSuffix(k, ")"):
+						comment = unquoteComment(key[len("COMMENT")+1 : len(key)-1])
+					case strings.HasPrefix(k, "GENERATED(") && strings.HasSuffix(k, ")"):
+						generated = key[len("GENERATED")+1 : len(key)-1]
+					case k == "STORED":
+						stored = true
+					case strings.HasPrefix(k, "CONV(") && strings.HasSuffix(k, ")"):
+						convName = key[len("CONV")+1 : len(key)-1]
+					case k == "ARRAY":
+						isArray = true
+					case isPgType(k):
+						col.SQLType = core.SQLType{k, 0, 0}
+					case k == "VIRTUAL":
+						stored = false
 					case strings.HasPrefix(k, "INDEX(") && strings.HasSuffix(k, ")"):
-						indexName := k[len("INDEX")+1 : len(k)-1]
+						indexName, order, expr := parseIndexTag(indexDefs, key[len("INDEX")+1:len(key)-1], false)
 						indexNames[indexName] = core.IndexType
+						indexOrders[indexName], indexExprs[indexName] = order, expr
 					case k == "INDEX":
 						isIndex = true
 					case strings.HasPrefix(k, "UNIQUE(") && strings.HasSuffix(k, ")"):
-						indexName := k[len("UNIQUE")+1 : len(k)-1]
+						indexName, order, expr := parseIndexTag(indexDefs, key[len("UNIQUE")+1:len(key)-1], true)
 						indexNames[indexName] = core.UniqueType
+						indexOrders[indexName], indexExprs[indexName] = order, expr
 					case k == "UNIQUE":
 						isUnique = true
+					case strings.HasPrefix(k, "FULLTEXT(") && strings.HasSuffix(k, ")"):
+						indexName, _, _ := parseIndexTag(indexDefs, key[len("FULLTEXT")+1:len(key)-1], false)
+						indexDefs[indexName].Method = "FULLTEXT"
+						indexNames[indexName] = core.IndexType
+					case k == "FULLTEXT":
+						isFulltext = true
 					case k == "NOTNULL":
 						col.Nullable = false
 					case k == "CACHE":
@@ -843,6 +1005,12 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 						if !hasNoCacheTag {
 							hasNoCacheTag = true
 						}
+					case k == "ULID":
+						generator = "ulid"
+					case strings.HasPrefix(k, "SNOWFLAKE(") && strings.HasSuffix(k, ")"):
+						generator = strings.ToLower(k)
+					case strings.HasPrefix(k, "GEN(") && strings.HasSuffix(k, ")"):
+						generator = strings.ToLower(key[len("GEN")+1 : len(key)-1])
 					case k == "NOT":
 					default:
 						if strings.HasPrefix(k, "'") && strings.HasSuffix(k, "'") {
@@ -857,8 +1025,10 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 								continue
 							}
 							col.SQLType = core.SQLType{fs[0], 0, 0}
+							// the options keep their case
+							optionStr := key[len(fs[0])+1 : len(key)-1]
 							if fs[0] == core.Enum && fs[1][0] == '\'' { //enum
-								options := strings.Split(fs[1][0:len(fs[1])-1], ",")
+								options := strings.Split(optionStr, ",")
 								col.EnumOptions = make(map[string]int)
 								for k, v := range options {
 									v = strings.TrimSpace(v)
@@ -866,7 +1036,7 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 									col.EnumOptions[v] = k
 								}
 							} else if fs[0] == core.Set && fs[1][0] == '\'' { //set
-								options := strings.Split(fs[1][0:len(fs[1])-1], ",")
+								options := strings.Split(optionStr, ",")
 								col.SetOptions = make(map[string]int)
 								for k, v := range options {
 									v = strings.TrimSpace(v)
@@ -903,8 +1073,15 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 					preKey = k
 				}
 				if col.SQLType.Name == "" {
-					col.SQLType = core.Type2SQLType(fieldType)
+					if conv := engine.fieldConverter(convName, fieldType); conv != nil {
+						col.SQLType = conv.SQLType
+					} else if isArray {
+						col.SQLType = pgArrayType(fieldType)
+					} else {
+						col.SQLType = core.Type2SQLType(fieldType)
+					}
 				}
+				col.SQLType = engine.pgNativeType(col.SQLType)
 				if col.Length == 0 {
 					col.Length = col.SQLType.DefaultLength
 				}
@@ -915,18 +1092,98 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 				if col.Name == "" {
 					col.Name = engine.ColumnMapper.Obj2Table(t.Field(i).Name)
 				}
+				if col.IsVersion && isTimeVersion(col) && col.Default == "1" {
+					col.Default = ""
+				}
+
+				// uuid means the type unless the column is a primary key
+				if generator == "" && col.IsPrimaryKey && col.SQLType.Name == core.Uuid {
+					generator = "uuid"
+				}
+				if generator != "" {
+					ext.newColumn(col.Name).generator = generator
+				}
+				if isDeletedBy {
+					ext.deletedBy = col.Name
+				}
+				if isTenant {
+					ext.tenant = col.Name
+				}
+				if fkRef != "" {
+					// fk(table.col), the column is id if omitted
+					refTable, refCol := fkRef, "id"
+					if pos := strings.LastIndex(fkRef, "."); pos > 0 {
+						refTable, refCol = fkRef[:pos], fkRef[pos+1:]
+					}
+					ext.foreignKeys = append(ext.foreignKeys, &ForeignKey{
+						Cols:     []string{col.Name},
+						RefTable: refTable,
+						RefCols:  []string{refCol},
+						OnDelete: onDelete,
+						OnUpdate: onUpdate,
+					})
+				}
+				if check != "" {
+					ext.checks = append(ext.checks, &Check{Col: col.Name, Expr: check})
+				}
+				if len(columnOptions(col)) > 0 && emulatesEnum(engine.dialect.DBType()) {
+					ext.checks = append(ext.checks, engine.optionsCheck(col))
+				}
+				if comment != "" {
+					ext.newColumn(col.Name).comment = comment
+				}
+				if convName != "" {
+					ext.newColumn(col.Name).converter = convName
+				}
+				if generated != "" {
+					colExt := ext.newColumn(col.Name)
+					colExt.generated = generated
+					colExt.stored = stored
+					// the database computes the values
+					col.MapType = core.ONLYFROMDB
+				}
+				if sequence != "" && supportSequence(engine.dialect.DBType()) {
+					ext.newColumn(col.Name).sequence = sequence
+				}
+				if autoIncrStart != 0 || autoIncrStep != 0 {
+					colExt := ext.newColumn(col.Name)
+					colExt.autoIncrStart = autoIncrStart
+					colExt.autoIncrStep = autoIncrStep
+				}
 
 				if isUnique {
 					indexNames[col.Name] = core.UniqueType
 				} else if isIndex {
 					indexNames[col.Name] = core.IndexType
 				}
+				if isFulltext {
+					indexName, _, _ := parseIndexTag(indexDefs, col.Name, false)
+					indexDefs[indexName].Method = "FULLTEXT"
+					indexNames[indexName] = core.IndexType
+				}
 
 				for indexName, indexType := range indexNames {
+					if expr := indexExprs[indexName]; expr != "" {
+						// the expression is indexed instead of the column
+						if _, ok := table.Indexes[indexName]; !ok {
+							table.AddIndex(core.NewIndex(indexName, indexType))
+						}
+						indexDefs[indexName].Cols = append(indexDefs[indexName].Cols, expr)
+						continue
+					}
 					addIndex(indexName, table, col, indexType)
+					if def, ok := indexDefs[indexName]; ok {
+						def.Cols = append(def.Cols, col.Name+indexOrders[indexName])
+					}
 				}
 			}
 		} else {
+			// the anonymous structs are flattened as extends
+			if isEmbeddedStruct(t.Field(i)) && engine.fieldConverter("", fieldType) == nil &&
+				engine.mapExtends(table, ext, t.Field(i), fieldValue, "") {
+				continue
+			}
+
 			var sqlType core.SQLType
 			if fieldValue.CanAddr() {
 				if _, ok := fieldValue.Addr().Interface().(core.Conversion); ok {
@@ -938,6 +1195,9 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 			} else {
 				sqlType = core.Type2SQLType(fieldType)
 			}
+			if conv := engine.fieldConverter("", fieldType); conv != nil {
+				sqlType = conv.SQLType
+			}
 			col = core.NewColumn(engine.ColumnMapper.Obj2Table(t.Field(i).Name),
 				t.Field(i).Name, sqlType, sqlType.DefaultLength,
 				sqlType.DefaultLength2, true)
@@ -962,6 +1222,26 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 		table.AutoIncrement = col.Name
 	}
 
+	method = v.MethodByName("Indexes")
+	if !method.IsValid() && v.CanAddr() {
+		method = v.Addr().MethodByName("Indexes")
+	}
+	if method.IsValid() {
+		results := method.Call([]reflect.Value{})
+		if len(results) == 1 {
+			defs, _ := results[0].Interface().([]*Index)
+			for _, def := range defs {
+				table.AddIndex(def.coreIndex(table))
+				indexDefs[def.Name] = def
+			}
+		}
+	}
+	for name, def := range indexDefs {
+		if !def.plain() {
+			ext.indexes[name] = def
+		}
+	}
+
 	if hasCacheTag {
 		if engine.Cacher != nil { // !nash! use engine's cacher if provided
 			engine.Logger.Info("enable cache on table:", table.Name)
@@ -976,6 +1256,7 @@ func (engine *Engine) mapType(v reflect.Value) *core.Table {
 		table.Cacher = nil
 	}
 
+	engine.tableExts[table] = ext
 	return table
 }
 
@@ -1013,7 +1294,11 @@ func (engine *Engine) IdOfV(rv reflect.Value) core.PK {
 	table := engine.autoMapType(v)
 	pk := make([]interface{}, len(table.PrimaryKeys))
 	for i, col := range table.PKColumns() {
-		pkField := v.FieldByName(col.FieldName)
+		pkField, err := colValueOfV(col, &v)
+		if err != nil {
+			engine.LogError(err)
+			continue
+		}
 		switch pkField.Kind() {
 		case reflect.String:
 			pk[i] = pkField.String()
@@ -1021,11 +1306,39 @@ func (engine *Engine) IdOfV(rv reflect.Value) core.PK {
 			pk[i] = pkField.Int()
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			pk[i] = pkField.Uint()
+		default:
+			// i.e. uuid types, they're cached by their string form
+			if s, ok := pkField.Interface().(fmt.Stringer); ok {
+				pk[i] = s.String()
+			} else {
+				pk[i] = fmt.Sprintf("%v", pkField.Interface())
+			}
 		}
 	}
 	return core.PK(pk)
 }
 
+// pkOfStr converts a primary key read from database as string to the value
+// IdOf returns for the same record, so they share the same cache key.
+func (engine *Engine) pkOfStr(table *core.Table, col *core.Column, s string) (interface{}, error) {
+	if table.Type != nil {
+		if field, ok := fieldByPath(table.Type, col.FieldName); ok {
+			switch field.Type.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return strconv.ParseInt(s, 10, 64)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return strconv.ParseUint(s, 10, 64)
+			default:
+				return s, nil
+			}
+		}
+	}
+	if col.SQLType.IsNumeric() {
+		return strconv.ParseInt(s, 10, 64)
+	}
+	return s, nil
+}
+
 // create indexes
 func (engine *Engine) CreateIndexes(bean interface{}) error {
 	session := engine.NewSession()
@@ -1093,12 +1406,13 @@ func (engine *Engine) ClearCache(beans ...interface{}) error {
 // table, column, index, unique. but will not delete or change anything.
 // If you change some field, you should change the database manually.
 func (engine *Engine) Sync(beans ...interface{}) error {
-	for _, bean := range beans {
+	for _, bean := range engine.sortByDependency(beans) {
 		table := engine.TableInfo(bean)
+		tbName := engine.tbNameWithSchema(table.Name)
 
 		s := engine.NewSession()
 		defer s.Close()
-		isExist, err := s.Table(bean).isTableExist(table.Name)
+		isExist, err := s.Table(bean).isTableExist(tbName)
 		if err != nil {
 			return err
 		}
@@ -1127,7 +1441,7 @@ func (engine *Engine) Sync(beans ...interface{}) error {
 				session := engine.NewSession()
 				session.Statement.RefTable = table
 				defer session.Close()
-				isExist, err := session.Engine.dialect.IsColumnExist(table.Name, col.Name)
+				isExist, err := session.Engine.dialect.IsColumnExist(tbName, col.Name)
 				if err != nil {
 					return err
 				}
@@ -1148,7 +1462,7 @@ func (engine *Engine) Sync(beans ...interface{}) error {
 				defer session.Close()
 				if index.Type == core.UniqueType {
 					//isExist, err := session.isIndexExist(table.Name, name, true)
-					isExist, err := session.isIndexExist2(table.Name, index.Cols, true)
+					isExist, err := session.isIndexExist2(tbName, index.Cols, true)
 					if err != nil {
 						return err
 					}
@@ -1156,13 +1470,13 @@ func (engine *Engine) Sync(beans ...interface{}) error {
 						session := engine.NewSession()
 						session.Statement.RefTable = table
 						defer session.Close()
-						err = session.addUnique(table.Name, name)
+						err = session.addUnique(tbName, name)
 						if err != nil {
 							return err
 						}
 					}
 				} else if index.Type == core.IndexType {
-					isExist, err := session.isIndexExist2(table.Name, index.Cols, false)
+					isExist, err := session.isIndexExist2(tbName, index.Cols, false)
 					if err != nil {
 						return err
 					}
@@ -1170,7 +1484,7 @@ func (engine *Engine) Sync(beans ...interface{}) error {
 						session := engine.NewSession()
 						session.Statement.RefTable = table
 						defer session.Close()
-						err = session.addIndex(table.Name, name)
+						err = session.addIndex(tbName, name)
 						if err != nil {
 							return err
 						}
@@ -1228,7 +1542,7 @@ func (engine *Engine) CreateTables(beans ...interface{}) error {
 		return err
 	}
 
-	for _, bean := range beans {
+	for _, bean := range engine.sortByDependency(beans) {
 		err = session.CreateTable(bean)
 		if err != nil {
 			session.Rollback()
@@ -1247,8 +1561,10 @@ func (engine *Engine) DropTables(beans ...interface{}) error {
 		return err
 	}
 
-	for _, bean := range beans {
-		err = session.DropTable(bean)
+	// the tables referencing the others are dropped first
+	sorted := engine.sortByDependency(beans)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		err = session.DropTable(sorted[i])
 		if err != nil {
 			session.Rollback()
 			return err
@@ -1291,6 +1607,13 @@ func (engine *Engine) InsertOne(bean interface{}) (int64, error) {
 	return session.InsertOne(bean)
 }
 
+// InsertSelect copies the records selected by subquery into targetTable
+func (engine *Engine) InsertSelect(targetTable interface{}, cols []string, subquery interface{}, args ...interface{}) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.InsertSelect(targetTable, cols, subquery, args...)
+}
+
 // Update records, bean's non-empty fields are updated contents,
 // condiBean' non-empty filds are conditions
 // CAUTION:
@@ -1459,3 +1782,47 @@ func (engine *Engine) Unscoped() *Session {
 	session.IsAutoClose = true
 	return session.Unscoped()
 }
+
+// OnlyDeleted makes the queries return the soft deleted records only
+func (engine *Engine) OnlyDeleted() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.OnlyDeleted()
+}
+
+// ForceDelete makes Delete remove the records even if the table has a
+// "deleted" column
+func (engine *Engine) ForceDelete() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.ForceDelete()
+}
+
+// DeletedBy sets the value of the "deleted_by" column for soft delete
+func (engine *Engine) DeletedBy(who interface{}) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.DeletedBy(who)
+}
+
+// Tenant returns a session bound to the tenant, see Session.Tenant
+func (engine *Engine) Tenant(id interface{}) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Tenant(id)
+}
+
+// WithoutScope disables the named scopes, or all of them if no name given
+func (engine *Engine) WithoutScope(names ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.WithoutScope(names...)
+}
+
+// Restore undeletes the soft deleted records, bean's non-empty fields are
+// conditions
+func (engine *Engine) Restore(bean interface{}) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.Restore(bean)
+}
@@ -0,0 +1,256 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// optionNames sorts the options of an enum or set in their order
+type optionNames struct {
+	names   []string
+	options map[string]int
+}
+
+func (o optionNames) Len() int {
+	return len(o.names)
+}
+
+func (o optionNames) Swap(i, j int) {
+	o.names[i], o.names[j] = o.names[j], o.names[i]
+}
+
+func (o optionNames) Less(i, j int) bool {
+	if o.options[o.names[i]] != o.options[o.names[j]] {
+		return o.options[o.names[i]] < o.options[o.names[j]]
+	}
+	return o.names[i] < o.names[j]
+}
+
+// sortedOptions returns the options of an enum or set in their order
+func sortedOptions(options map[string]int) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Sort(optionNames{names, options})
+	return names
+}
+
+// columnOptions returns the options of an enum or set column, nil for the
+// other columns
+func columnOptions(col *core.Column) map[string]int {
+	switch col.SQLType.Name {
+	case core.Enum:
+		return col.EnumOptions
+	case core.Set:
+		return col.SetOptions
+	}
+	return nil
+}
+
+// optionsSQL returns the options of col like 'a','b'
+func optionsSQL(col *core.Column) string {
+	options := sortedOptions(columnOptions(col))
+	for i, option := range options {
+		options[i] = sqlString(option)
+	}
+	return strings.Join(options, ",")
+}
+
+// optionsLength returns the length of the varchar keeping the values of an
+// enum or set column on the dialects which have no enum
+func optionsLength(col *core.Column) int {
+	var length int
+	for option := range columnOptions(col) {
+		if col.SQLType.Name == core.Set {
+			length += len(option) + 1
+		} else if len(option) > length {
+			length = len(option)
+		}
+	}
+	if length == 0 {
+		return 255
+	}
+	return length
+}
+
+// emulatesEnum reports whether the enums and sets of the dialect are
+// varchars with checks
+func emulatesEnum(dbType core.DbType) bool {
+	switch dbType {
+	case core.POSTGRES, core.SQLITE, core.MSSQL, core.ORACLE:
+		return true
+	}
+	return false
+}
+
+// optionsCheck returns the check which keeps the values of enum or set
+// column col in its options. The values of a set are separated by commas,
+// they're removed one by one from the value and nothing should be left.
+func (engine *Engine) optionsCheck(col *core.Column) *Check {
+	quoted := engine.quoteName(col.Name)
+	if col.SQLType.Name == core.Enum {
+		return &Check{
+			Col:     col.Name,
+			Expr:    fmt.Sprintf("%v IN (%v)", quoted, optionsSQL(col)),
+			options: "enum",
+		}
+	}
+
+	concat := func(parts ...string) string {
+		if engine.dialect.DBType() == core.MSSQL {
+			return strings.Join(parts, " + ")
+		}
+		return strings.Join(parts, " || ")
+	}
+	rest := concat("','", quoted, "','")
+	for _, option := range sortedOptions(col.SetOptions) {
+		rest = fmt.Sprintf("REPLACE(%v, %v, ',')", rest, sqlString(","+option+","))
+	}
+	return &Check{
+		Col:     col.Name,
+		Expr:    fmt.Sprintf("%v = '' OR %v = ','", quoted, rest),
+		options: "set",
+	}
+}
+
+// literalRe matches the string literals of an expression
+var literalRe = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// sameOptions reports whether the checks of an enum or set have the same options,
+// the databases rewrite IN to = ANY or ORs but keep the literals
+func sameOptions(expr, oriExpr string) bool {
+	literals := literalRe.FindAllString(expr, -1)
+	oriLiterals := literalRe.FindAllString(oriExpr, -1)
+	sort.Strings(literals)
+	sort.Strings(oriLiterals)
+	return strings.Join(literals, ",") == strings.Join(oriLiterals, ",")
+}
+
+// optionArg returns the argument of an enum or set column, the string
+// slices are the values of a set. ok is false if the value is not one of
+// them.
+func optionArg(col *core.Column, v reflect.Value) (arg interface{}, ok bool) {
+	if columnOptions(col) == nil {
+		return nil, false
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, true
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		return v.String(), true
+	case col.SQLType.Name == core.Set && v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).String()
+		}
+		return strings.Join(values, ","), true
+	}
+	return nil, false
+}
+
+// checkOption returns an error if arg is not an option of the enum column
+// col, or not the options of the set column col
+func checkOption(col *core.Column, arg interface{}) error {
+	options := columnOptions(col)
+	if options == nil {
+		return nil
+	}
+	var s string
+	switch x := arg.(type) {
+	case nil:
+		return nil
+	case string:
+		s = x
+	case []string:
+		s = strings.Join(x, ",")
+	case *string:
+		if x == nil {
+			return nil
+		}
+		s = *x
+	default:
+		return nil
+	}
+
+	if col.SQLType.Name == core.Enum {
+		if _, ok := options[s]; !ok {
+			return fmt.Errorf("%q is not an option of column %v", s, col.Name)
+		}
+		return nil
+	}
+	if s == "" {
+		return nil
+	}
+	for _, value := range strings.Split(s, ",") {
+		if _, ok := options[value]; !ok {
+			return fmt.Errorf("%q is not an option of column %v", value, col.Name)
+		}
+	}
+	return nil
+}
+
+// checkUpdateOptions checks the values of the enum and set columns updated,
+// the string slices of the maps are joined
+func (session *Session) checkUpdateOptions(table *core.Table, colNames []string, args []interface{}) error {
+	for i, colName := range colNames {
+		if i >= len(args) {
+			break
+		}
+		name := strings.TrimSpace(strings.SplitN(colName, "=", 2)[0])
+		col := table.GetColumn(strings.Trim(name, "`[]\" "))
+		if col == nil || columnOptions(col) == nil {
+			continue
+		}
+		if values, ok := args[i].([]string); ok {
+			args[i] = strings.Join(values, ",")
+		}
+		if err := checkOption(col, args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDecode sets a string slice field of a set column, ok is false for the
+// other fields
+func setDecode(col *core.Column, fieldValue *reflect.Value, data []byte) bool {
+	if col.SQLType.Name != core.Set {
+		return false
+	}
+	v := *fieldValue
+	if v.Kind() == reflect.Ptr {
+		if v.Type().Elem().Kind() != reflect.Slice {
+			return false
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.String {
+		return false
+	}
+
+	values := reflect.MakeSlice(v.Type(), 0, 0)
+	if len(data) > 0 {
+		for _, value := range strings.Split(string(data), ",") {
+			values = reflect.Append(values, reflect.ValueOf(value).Convert(v.Type().Elem()))
+		}
+	}
+	v.Set(values)
+	return true
+}
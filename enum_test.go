@@ -0,0 +1,134 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type EnumPost struct {
+	Id     int64
+	Status string   `xorm:"enum('draft','published')"`
+	Tags   []string `xorm:"set('go','db','web')"`
+}
+
+type EnumPost2 struct {
+	Id     int64
+	Status string   `xorm:"enum('draft','published','archived')"`
+	Tags   []string `xorm:"set('go','db','web')"`
+}
+
+func (EnumPost2) TableName() string {
+	return "enum_post"
+}
+
+func TestSortedOptions(t *testing.T) {
+	names := sortedOptions(map[string]int{"c": 0, "a": 2, "b": 1, "d": 1})
+	if expected := []string{"c", "b", "d", "a"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestOptionsCheckSQL(t *testing.T) {
+	for dbType, expected := range map[core.DbType][]string{
+		core.POSTGRES: {
+			`CONSTRAINT "CK_enum_post_status_enum" CHECK ("status" IN ('draft','published'))`,
+			`CONSTRAINT "CK_enum_post_tags_set" CHECK ("tags" = '' OR REPLACE(REPLACE(REPLACE(',' || "tags" || ',', ` +
+				`',go,', ','), ',db,', ','), ',web,', ',') = ',')`,
+		},
+		core.MSSQL: {
+			`CONSTRAINT "CK_enum_post_status_enum" CHECK ("status" IN ('draft','published'))`,
+			`CONSTRAINT "CK_enum_post_tags_set" CHECK ("tags" = '' OR REPLACE(REPLACE(REPLACE(',' + "tags" + ',', ` +
+				`',go,', ','), ',db,', ','), ',web,', ',') = ',')`,
+		},
+	} {
+		engine := testEngine(t, dbType)
+		table := engine.TableInfo(new(EnumPost))
+		checks := engine.tableExts[table].checks
+		if len(checks) != 2 {
+			t.Fatalf("expected the checks of status and tags on %v, got %v", dbType, checks)
+		}
+		for i, check := range checks {
+			assertSQL(t, expected[i], engine.checkSQL(table.Name, check))
+		}
+	}
+
+	// mysql has enums and sets
+	engine := testEngine(t, core.MYSQL)
+	if checks := engine.tableExts[engine.TableInfo(new(EnumPost))].checks; len(checks) != 0 {
+		t.Errorf("expected no check on mysql, got %v", checks)
+	}
+}
+
+func TestEnumSet(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(EnumPost)))
+
+	post := &EnumPost{Status: "draft", Tags: []string{"go", "web"}}
+	_, err := engine.Insert(post)
+	assertNoErr(t, err)
+	_, err = engine.Insert(&EnumPost{Status: "published"})
+	assertNoErr(t, err)
+
+	got := new(EnumPost)
+	has, err := engine.Id(post.Id).Get(got)
+	assertNoErr(t, err)
+	if !has || got.Status != "draft" || !reflect.DeepEqual(got.Tags, []string{"go", "web"}) {
+		t.Errorf("unexpected post %+v", got)
+	}
+
+	// the invalid values are rejected before and by the database
+	if _, err = engine.Insert(&EnumPost{Status: "deleted"}); err == nil {
+		t.Error("expected the error of an invalid enum")
+	}
+	if _, err = engine.Id(post.Id).Update(&EnumPost{Tags: []string{"go", "rust"}}); err == nil {
+		t.Error("expected the error of an invalid set")
+	}
+	for _, sqlStr := range []string{
+		"INSERT INTO enum_post (status, tags) VALUES ('deleted', '')",
+		"INSERT INTO enum_post (status, tags) VALUES ('draft', 'go,rust')",
+		"INSERT INTO enum_post (status, tags) VALUES ('draft', 'go,')",
+	} {
+		if _, err = engine.Exec(sqlStr); err == nil {
+			t.Errorf("expected the check to reject %v", sqlStr)
+		}
+	}
+	_, err = engine.Exec("INSERT INTO enum_post (status, tags) VALUES ('draft', 'web,db,go')")
+	assertNoErr(t, err)
+	count, err := engine.Count(new(EnumPost))
+	assertNoErr(t, err)
+	if count != 3 {
+		t.Errorf("expected 3 posts, got %v", count)
+	}
+
+	// the table is rebuilt with the new options
+	log.reset()
+	assertNoErr(t, engine.Sync2(new(EnumPost2)))
+	if !strings.Contains(strings.Join(log.sqls, "\n"), "'archived'") {
+		t.Errorf("expected the check of the new options, got %v", log.sqls)
+	}
+	_, err = engine.Id(post.Id).Update(&EnumPost2{Status: "archived"})
+	assertNoErr(t, err)
+	got2 := new(EnumPost2)
+	has, err = engine.Id(post.Id).Get(got2)
+	assertNoErr(t, err)
+	if !has || got2.Status != "archived" || !reflect.DeepEqual(got2.Tags, []string{"go", "web"}) {
+		t.Errorf("unexpected post %+v", got2)
+	}
+
+	// nothing is changed once synced
+	log.reset()
+	assertNoErr(t, engine.Sync2(new(EnumPost2)))
+	for _, sqlStr := range log.sqls {
+		if strings.HasPrefix(sqlStr, "CREATE") || strings.HasPrefix(sqlStr, "ALTER") {
+			t.Errorf("unexpected sql %v", sqlStr)
+		}
+	}
+}
@@ -16,4 +16,8 @@ var (
 	ErrCacheFailed     error = errors.New("Cache failed")
 	ErrNeedDeletedCond error = errors.New("Delete need at least one condition")
 	ErrNotImplemented  error = errors.New("Not implemented.")
+	ErrNoPrimaryKey    error = errors.New("Table has no primary key")
+	ErrReturningRows   error = errors.New("Returning reads back one row only")
+	ErrNoDeletedColumn error = errors.New("Table has no deleted column")
+	ErrTenantChanged   error = errors.New("Tenant could not be changed")
 )
@@ -0,0 +1,149 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// exprTokenRe matches the string literals and the words of an expression
+var exprTokenRe = regexp.MustCompile(`'(?:[^']|'')*'|[A-Za-z_][A-Za-z0-9_$]*`)
+
+// prefixColumns prefixes the words of expr which are in names, the string
+// literals are kept
+func prefixColumns(expr, prefix string, names map[string]bool) string {
+	return exprTokenRe.ReplaceAllStringFunc(expr, func(token string) string {
+		if names[strings.ToLower(token)] {
+			return prefix + token
+		}
+		return token
+	})
+}
+
+// isEmbeddedStruct reports whether the anonymous field is a struct which is
+// flattened without extends. The times and the Conversions are mapped to
+// columns as before.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !field.Anonymous || field.PkgPath != "" || t.Kind() != reflect.Struct {
+		return false
+	}
+	if t.ConvertibleTo(core.TimeType) {
+		return false
+	}
+	conversion := reflect.TypeOf((*core.Conversion)(nil)).Elem()
+	return !t.Implements(conversion) && !reflect.PtrTo(t).Implements(conversion)
+}
+
+// mapExtends maps the struct of field, which is tagged with extends or
+// embedded anonymously, and adds its columns to table. Their FieldNames
+// begin with the field's name and their names with prefix, so a struct
+// could be extended twice like prefix(home_) and prefix(work_). It returns
+// false if the field is not a struct.
+func (engine *Engine) mapExtends(table *core.Table, ext *tableExt, field reflect.StructField, fieldValue reflect.Value, prefix string) bool {
+	if fieldValue.Kind() == reflect.Ptr {
+		if fieldValue.Type().Elem().Kind() != reflect.Struct {
+			return false
+		}
+		if fieldValue.IsNil() {
+			fieldValue = reflect.New(fieldValue.Type().Elem()).Elem()
+		} else {
+			fieldValue = fieldValue.Elem()
+		}
+	}
+	if fieldValue.Kind() != reflect.Struct {
+		return false
+	}
+
+	parentTable := engine.mapType(fieldValue)
+	parentExt := engine.tableExts[parentTable]
+	delete(engine.tableExts, parentTable)
+	if prefix != "" {
+		parentExt.addPrefix(prefix, parentTable)
+	}
+
+	for _, col := range parentTable.Columns() {
+		col.FieldName = field.Name + "." + col.FieldName
+		if prefix != "" {
+			col.Name = prefix + col.Name
+			indexes := make(map[string]bool, len(col.Indexes))
+			for name := range col.Indexes {
+				indexes[prefix+name] = true
+			}
+			col.Indexes = indexes
+		}
+		table.AddColumn(col)
+	}
+	for _, index := range parentTable.Indexes {
+		if prefix != "" {
+			index.Name = prefix + index.Name
+			for i, col := range index.Cols {
+				index.Cols[i] = prefix + col
+			}
+		}
+		if exist, ok := table.Indexes[index.Name]; ok {
+			exist.Cols = append(exist.Cols, index.Cols...)
+		} else {
+			table.AddIndex(index)
+		}
+	}
+	ext.merge(parentExt)
+	return true
+}
+
+// addPrefix prefixes the names of table's columns in the ext, including the
+// ones in the expressions of checks, generated columns and indexes
+func (ext *tableExt) addPrefix(prefix string, table *core.Table) {
+	names := make(map[string]bool)
+	for _, col := range table.Columns() {
+		names[strings.ToLower(col.Name)] = true
+	}
+
+	columns := make(map[string]*columnExt, len(ext.columns))
+	for name, colExt := range ext.columns {
+		if colExt.generated != "" {
+			colExt.generated = prefixColumns(colExt.generated, prefix, names)
+		}
+		columns[strings.ToLower(prefix+name)] = colExt
+	}
+	ext.columns = columns
+
+	if ext.deletedBy != "" {
+		ext.deletedBy = prefix + ext.deletedBy
+	}
+	if ext.tenant != "" {
+		ext.tenant = prefix + ext.tenant
+	}
+	for _, fk := range ext.foreignKeys {
+		for i, col := range fk.Cols {
+			fk.Cols[i] = prefix + col
+		}
+	}
+	for _, check := range ext.checks {
+		check.Col = prefix + check.Col
+		check.Expr = prefixColumns(check.Expr, prefix, names)
+	}
+
+	indexes := make(map[string]*Index, len(ext.indexes))
+	for name, index := range ext.indexes {
+		index.Name = prefix + index.Name
+		for i, col := range index.Cols {
+			index.Cols[i] = prefixColumns(col, prefix, names)
+		}
+		for i, col := range index.Include {
+			index.Include[i] = prefix + col
+		}
+		index.Where = prefixColumns(index.Where, prefix, names)
+		indexes[prefix+name] = index
+	}
+	ext.indexes = indexes
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+)
+
+type ExtAddress struct {
+	City string
+	Zip  string
+}
+
+type ExtBase struct {
+	Created int64
+}
+
+type ExtPerson struct {
+	Id int64
+	ExtBase
+	Home ExtAddress `xorm:"extends prefix(home_)"`
+	Work ExtAddress `xorm:"extends prefix(work_)"`
+}
+
+func TestExtendsPrefix(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+
+	table := engine.TableInfo(new(ExtPerson))
+	assertSQL(t, "id, created, home_city, home_zip, work_city, work_zip", strings.Join(table.ColumnsSeq(), ", "))
+	if col := table.GetColumn("work_zip"); col == nil || col.FieldName != "Work.Zip" {
+		t.Errorf("expected work_zip of Work.Zip, got %v", col)
+	}
+
+	assertNoErr(t, engine.Sync2(new(ExtPerson)))
+	person := &ExtPerson{ExtBase: ExtBase{Created: 1}, Home: ExtAddress{"Paris", "75001"}, Work: ExtAddress{"Lyon", "69001"}}
+	_, err := engine.Insert(person)
+	assertNoErr(t, err)
+
+	_, err = engine.Id(person.Id).Update(&ExtPerson{Work: ExtAddress{City: "Nice"}})
+	assertNoErr(t, err)
+
+	got := new(ExtPerson)
+	has, err := engine.Id(person.Id).Get(got)
+	assertNoErr(t, err)
+	if !has || got.Created != 1 || got.Home != (ExtAddress{"Paris", "75001"}) || got.Work != (ExtAddress{"Nice", "69001"}) {
+		t.Errorf("unexpected person %+v", got)
+	}
+
+	var persons []ExtPerson
+	assertNoErr(t, engine.Find(&persons, &ExtPerson{Home: ExtAddress{City: "Paris"}}))
+	if len(persons) != 1 || persons[0].Work.City != "Nice" {
+		t.Errorf("expected the person in Paris, got %+v", persons)
+	}
+	persons = nil
+	assertNoErr(t, engine.Find(&persons, &ExtPerson{Work: ExtAddress{City: "Paris"}}))
+	if len(persons) != 0 {
+		t.Errorf("expected nobody working in Paris, got %+v", persons)
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// ForeignKey is a foreign key constraint, which is declared by tag
+// fk(table.col) with on_delete(action) and on_update(action), or read by
+// DBMetas.
+type ForeignKey struct {
+	Name     string // empty for the declared ones, see XName
+	Cols     []string
+	RefTable string
+	RefCols  []string
+	OnDelete string // i.e. CASCADE, SET NULL, empty means the default
+	OnUpdate string
+}
+
+// XName returns the constraint name of the foreign key in table tableName
+func (fk *ForeignKey) XName(tableName string) string {
+	if fk.Name != "" {
+		return fk.Name
+	}
+	// the constraints are in the schema of the table
+	_, tableName = splitSchema(tableName, "")
+	return fmt.Sprintf("FK_%v_%v", tableName, strings.Join(fk.Cols, "_"))
+}
+
+// fkAction normalizes an action of tag on_delete or on_update, i.e.
+// set_null to SET NULL
+func fkAction(action string) string {
+	return strings.TrimSpace(strings.Replace(strings.ToUpper(action), "_", " ", -1))
+}
+
+// sameAction reports whether the actions are the same, the default is the
+// same as NO ACTION and RESTRICT
+func sameAction(a, b string) bool {
+	isDefault := func(action string) bool {
+		return action == "" || action == "NO ACTION" || action == "RESTRICT"
+	}
+	return fkAction(a) == fkAction(b) || (isDefault(fkAction(a)) && isDefault(fkAction(b)))
+}
+
+// Equal reports whether the foreign keys reference the same columns with
+// the same actions, the names are not compared.
+func (fk *ForeignKey) Equal(other *ForeignKey) bool {
+	if !strings.EqualFold(fk.RefTable, other.RefTable) ||
+		len(fk.Cols) != len(other.Cols) || len(fk.RefCols) != len(other.RefCols) {
+		return false
+	}
+	for i := range fk.Cols {
+		if !strings.EqualFold(fk.Cols[i], other.Cols[i]) {
+			return false
+		}
+	}
+	for i := range fk.RefCols {
+		if !strings.EqualFold(fk.RefCols[i], other.RefCols[i]) {
+			return false
+		}
+	}
+	return sameAction(fk.OnDelete, other.OnDelete) && sameAction(fk.OnUpdate, other.OnUpdate)
+}
+
+// addForeignKeyColumn adds a column read from database to the foreign key
+// named name, it's used by the dialects
+func addForeignKeyColumn(fks []*ForeignKey, name, col, refTable, refCol, onDelete, onUpdate string) []*ForeignKey {
+	for _, fk := range fks {
+		if fk.Name == name {
+			fk.Cols = append(fk.Cols, col)
+			fk.RefCols = append(fk.RefCols, refCol)
+			return fks
+		}
+	}
+	return append(fks, &ForeignKey{
+		Name:     name,
+		Cols:     []string{col},
+		RefTable: refTable,
+		RefCols:  []string{refCol},
+		OnDelete: fkAction(onDelete),
+		OnUpdate: fkAction(onUpdate),
+	})
+}
+
+// ForeignKeys returns the foreign keys of a mapped table, or of a table
+// returned by the last DBMetas
+func (engine *Engine) ForeignKeys(table *core.Table) []*ForeignKey {
+	return engine.tableExt(table).foreignKeys
+}
+
+// foreignKeySQL returns the definition of a foreign key which is used by
+// CREATE TABLE and ALTER TABLE
+func (engine *Engine) foreignKeySQL(tableName string, fk *ForeignKey) string {
+	quote := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = engine.quoteName(name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	sqlStr := fmt.Sprintf("CONSTRAINT %v FOREIGN KEY (%v) REFERENCES %v (%v)",
+		engine.quoteName(fk.XName(tableName)), quote(fk.Cols), engine.quoteName(engine.tbNameWithSchema(fk.RefTable)), quote(fk.RefCols))
+
+	onDelete, onUpdate := fkAction(fk.OnDelete), fkAction(fk.OnUpdate)
+	switch engine.dialect.DBType() {
+	case core.MSSQL:
+		if onDelete == "RESTRICT" {
+			onDelete = "NO ACTION"
+		}
+		if onUpdate == "RESTRICT" {
+			onUpdate = "NO ACTION"
+		}
+	case core.ORACLE:
+		// oracle has ON DELETE CASCADE and SET NULL only
+		if onDelete == "RESTRICT" || onDelete == "NO ACTION" {
+			onDelete = ""
+		}
+		if onUpdate != "" {
+			engine.LogWarnf("oracle has no ON UPDATE, foreign key %v ignores it", fk.XName(tableName))
+			onUpdate = ""
+		}
+	}
+	if onDelete != "" {
+		sqlStr += " ON DELETE " + onDelete
+	}
+	if onUpdate != "" {
+		sqlStr += " ON UPDATE " + onUpdate
+	}
+	return sqlStr
+}
+
+// sortByDependency sorts beans so the tables referenced by foreign keys are
+// before the tables referencing them, the cycles are kept in their order.
+// The table names in beans are kept in their order too.
+func (engine *Engine) sortByDependency(beans []interface{}) []interface{} {
+	tables := make([]*core.Table, len(beans))
+	indexes := make(map[string]int)
+	for i, bean := range beans {
+		if _, ok := bean.(string); ok {
+			continue
+		}
+		tables[i] = engine.TableInfo(bean)
+		indexes[strings.ToLower(tables[i].Name)] = i
+	}
+
+	visited := make([]bool, len(beans))
+	sorted := make([]interface{}, 0, len(beans))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if tables[i] == nil {
+			sorted = append(sorted, beans[i])
+			return
+		}
+		for _, fk := range engine.tableExt(tables[i]).foreignKeys {
+			if j, ok := indexes[strings.ToLower(fk.RefTable)]; ok {
+				visit(j)
+			}
+		}
+		sorted = append(sorted, beans[i])
+	}
+	for i := range beans {
+		visit(i)
+	}
+	return sorted
+}
+
+// syncForeignKeys adds the foreign keys of table which oriTable, read from
+// database, hasn't and drops the ones which table hasn't
+func (session *Session) syncForeignKeys(table, oriTable *core.Table) error {
+	engine := session.Engine
+	if _, ok := engine.dialect.(columnOptionDialect); !ok {
+		return nil
+	}
+	expected := engine.tableExt(table).foreignKeys
+	current := engine.tableExt(oriTable).foreignKeys
+
+	var added, dropped []*ForeignKey
+	for _, fk := range expected {
+		found := false
+		for _, fk2 := range current {
+			if fk.Equal(fk2) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			added = append(added, fk)
+		}
+	}
+	for _, fk2 := range current {
+		found := false
+		for _, fk := range expected {
+			if fk.Equal(fk2) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dropped = append(dropped, fk2)
+		}
+	}
+	if len(added) == 0 && len(dropped) == 0 {
+		return nil
+	}
+
+	// sqlite3 could not alter the constraints of a table
+	if engine.dialect.DBType() == core.SQLITE {
+		return session.rebuildTable(table, oriTable)
+	}
+
+	tableName := engine.Quote(engine.tbNameWithSchema(table.Name))
+	for _, fk := range dropped {
+		var sqlStr string
+		if engine.dialect.DBType() == core.MYSQL {
+			sqlStr = fmt.Sprintf("ALTER TABLE %v DROP FOREIGN KEY %v", tableName, engine.Quote(fk.XName(table.Name)))
+		} else {
+			sqlStr = fmt.Sprintf("ALTER TABLE %v DROP CONSTRAINT %v", tableName, engine.Quote(fk.XName(table.Name)))
+		}
+		if _, err := session.exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	for _, fk := range added {
+		sqlStr := fmt.Sprintf("ALTER TABLE %v ADD %v", tableName, engine.foreignKeySQL(table.Name, fk))
+		if _, err := session.exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildTable recreates a sqlite3 table with the struct's definition and
+// copies the records, it's how sqlite3 changes the constraints.
+func (session *Session) rebuildTable(table, oriTable *core.Table) error {
+	engine := session.Engine
+	for _, colName := range oriTable.ColumnsSeq() {
+		if table.GetColumn(colName) == nil {
+			engine.LogWarnf("Table %s has column %s but struct has not, the constraints are not changed",
+				table.Name, colName)
+			return nil
+		}
+	}
+
+	tmpName := "_xorm_tmp_" + table.Name
+	ddl := engine.NewSession()
+	defer ddl.Close()
+	ddl.Statement.RefTable = table
+	createSQL := ddl.Statement.createTableSQL(tmpName)
+
+	cols := make([]string, 0, len(oriTable.ColumnsSeq()))
+	for _, colName := range oriTable.ColumnsSeq() {
+		// the generated columns are computed again
+		if expr, _ := engine.Generated(table, colName); expr != "" {
+			continue
+		}
+		if expr, _ := engine.Generated(oriTable, colName); expr != "" {
+			continue
+		}
+		cols = append(cols, engine.Quote(colName))
+	}
+	colStr := strings.Join(cols, ", ")
+
+	sqls := []string{
+		createSQL,
+		fmt.Sprintf("INSERT INTO %v (%v) SELECT %v FROM %v", engine.Quote(tmpName), colStr, colStr, engine.Quote(table.Name)),
+		fmt.Sprintf("DROP TABLE %v", engine.Quote(table.Name)),
+		fmt.Sprintf("ALTER TABLE %v RENAME TO %v", engine.Quote(tmpName), engine.Quote(table.Name)),
+	}
+	for _, index := range table.Indexes {
+		sqls = append(sqls, engine.indexSQL(table.Name, table, index))
+	}
+
+	// the pragma has no effect in a transaction and it's per connection, so
+	// it's turned off and restored on the connection which rebuilds the table
+	ctx := context.Background()
+	conn, err := session.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var enabled int
+	engine.logSQL("PRAGMA foreign_keys")
+	if err = conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		return err
+	}
+	if enabled == 1 {
+		engine.logSQL("PRAGMA foreign_keys = OFF")
+		if _, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer func() {
+			engine.logSQL("PRAGMA foreign_keys = ON")
+			conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+		}()
+	}
+
+	engine.logSQL("BEGIN TRANSACTION")
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, sqlStr := range sqls {
+		engine.logSQL(sqlStr)
+		if _, err = tx.ExecContext(ctx, sqlStr); err != nil {
+			engine.logSQL(engine.dialect.RollBackStr())
+			tx.Rollback()
+			return err
+		}
+	}
+	engine.logSQL("COMMIT")
+	return tx.Commit()
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type FkOwner struct {
+	Id int64
+}
+
+type FkGroup struct {
+	Id      int64
+	OwnerId int64 `xorm:"fk(fk_owner.id)"`
+}
+
+type FkItem struct {
+	Id      int64
+	GroupId int64 `xorm:"fk(fk_group.id) on_delete(cascade)"`
+}
+
+func TestForeignKeySQL(t *testing.T) {
+	engine := testEngine(t, "mysql")
+	fk := &ForeignKey{Cols: []string{"group_id"}, RefTable: "fk_group", RefCols: []string{"id"}, OnDelete: "set_null"}
+	assertSQL(t, "CONSTRAINT `FK_fk_item_group_id` FOREIGN KEY (`group_id`) REFERENCES `fk_group` (`id`) ON DELETE SET NULL",
+		engine.foreignKeySQL("fk_item", fk))
+}
+
+// TestSyncForeignKeys adds a foreign key to a sqlite3 table referenced by a
+// cascade one, the rows are kept and the pragma is restored
+func TestSyncForeignKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xorm")
+	assertNoErr(t, err)
+	defer os.RemoveAll(dir)
+	engine, err := NewEngine("sqlite3", filepath.Join(dir, "test.db")+"?_foreign_keys=1")
+	assertNoErr(t, err)
+	defer engine.Close()
+
+	for _, sqlStr := range []string{
+		"CREATE TABLE fk_owner (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL)",
+		"CREATE TABLE fk_group (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, owner_id INTEGER NULL)",
+		"CREATE TABLE fk_item (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, group_id INTEGER NULL " +
+			"REFERENCES fk_group (id) ON DELETE CASCADE)",
+		"INSERT INTO fk_owner (id) VALUES (1)",
+		"INSERT INTO fk_group (id, owner_id) VALUES (1, 1)",
+		"INSERT INTO fk_item (id, group_id) VALUES (1, 1), (2, 1)",
+	} {
+		_, err = engine.Exec(sqlStr)
+		assertNoErr(t, err)
+	}
+
+	assertNoErr(t, engine.Sync2(new(FkOwner), new(FkGroup), new(FkItem)))
+
+	// dropping fk_group must not delete the items by cascade
+	count, err := engine.Count(new(FkItem))
+	assertNoErr(t, err)
+	if count != 2 {
+		t.Errorf("expected 2 items after rebuild, got %d", count)
+	}
+
+	tables, err := engine.DBMetas()
+	assertNoErr(t, err)
+	for _, table := range tables {
+		if table.Name == "fk_group" {
+			fks := engine.ForeignKeys(table)
+			if len(fks) != 1 || fks[0].RefTable != "fk_owner" {
+				t.Errorf("expected the foreign key to fk_owner, got %v", fks)
+			}
+		}
+	}
+
+	results, err := engine.Query("PRAGMA foreign_keys")
+	assertNoErr(t, err)
+	if len(results) == 0 || string(results[0]["foreign_keys"]) != "1" {
+		t.Errorf("expected foreign_keys restored, got %v", results)
+	}
+}
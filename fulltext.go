@@ -0,0 +1,337 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// fulltextCatalog is the catalog of the full-text indexes on mssql
+const fulltextCatalog = "xorm_fulltext"
+
+// SetFullTextConfig sets the text search configuration of postgres, like
+// english, which is used by both the full-text indexes and Match. The
+// default is simple.
+func (engine *Engine) SetFullTextConfig(config string) {
+	engine.fulltextConfig = config
+}
+
+// tsConfig returns the text search configuration as a literal
+func (engine *Engine) tsConfig() string {
+	if engine.fulltextConfig == "" {
+		return "'simple'"
+	}
+	return sqlString(engine.fulltextConfig)
+}
+
+// tsVector returns the document of cols on postgres, qualifier is the
+// quoted table of the columns or empty. The indexes and the conditions have
+// the same expression so the indexes are used.
+func (engine *Engine) tsVector(cols []string, qualifier string) string {
+	docs := make([]string, len(cols))
+	for i, col := range cols {
+		docs[i] = "coalesce(" + qualifier + engine.Quote(col) + ", '')"
+	}
+	return "to_tsvector(" + engine.tsConfig() + ", " + strings.Join(docs, " || ' ' || ") + ")"
+}
+
+// fulltextCols returns the columns of a full-text index
+func fulltextCols(def *Index) []string {
+	cols := make([]string, 0, len(def.Cols))
+	for _, col := range def.Cols {
+		if name, _, isColumn := splitIndexCol(col); isColumn {
+			cols = append(cols, name)
+		}
+	}
+	return cols
+}
+
+// fulltextIndexSQL returns the sql which creates the full-text index of
+// table on postgres, sqlite3 and mssql, empty for the other dialects. The
+// ones of sqlite3 and mssql are not read back by GetIndexes, so they are
+// created only if they don't exist. mssql has one full-text index a table,
+// on the columns of the first one.
+func (engine *Engine) fulltextIndexSQL(tableName string, table *core.Table, index *core.Index, def *Index) string {
+	quote := engine.Quote
+	cols := fulltextCols(def)
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = quote(col)
+	}
+	// the index is in the schema of its table, its name has the table name only
+	_, name := splitSchema(tableName, "")
+	name = index.XName(name)
+
+	switch engine.dialect.DBType() {
+	case core.POSTGRES:
+		sqlStr := "CREATE INDEX "
+		if def.Concurrent {
+			sqlStr += "CONCURRENTLY "
+		}
+		return sqlStr + quote(name) + " ON " + quote(tableName) +
+			" USING gin ((" + engine.tsVector(cols, "") + "))"
+	case core.SQLITE:
+		// an external content fts5 table kept in sync by the triggers, it's
+		// rebuilt from the table when it's created
+		fts := quote(name)
+		newCols := make([]string, len(cols))
+		oldCols := make([]string, len(cols))
+		for i, col := range quoted {
+			newCols[i] = "new." + col
+			oldCols[i] = "old." + col
+		}
+		colStr := strings.Join(quoted, ", ")
+		insert := "INSERT INTO " + fts + "(rowid, " + colStr + ") VALUES (new.rowid, " + strings.Join(newCols, ", ") + ");"
+		remove := "INSERT INTO " + fts + "(" + fts + ", rowid, " + colStr + ") VALUES ('delete', old.rowid, " + strings.Join(oldCols, ", ") + ");"
+		trigger := func(suffix, event, body string) string {
+			return "CREATE TRIGGER IF NOT EXISTS " + quote(name+suffix) + " AFTER " + event + " ON " + quote(tableName) +
+				" BEGIN " + body + " END"
+		}
+		return strings.Join([]string{
+			"CREATE VIRTUAL TABLE IF NOT EXISTS " + fts + " USING fts5(" + colStr + ", content=" + sqlString(tableName) + ")",
+			"INSERT INTO " + fts + "(" + fts + ") SELECT 'rebuild' WHERE NOT EXISTS " +
+				"(SELECT 1 FROM sqlite_master WHERE type='trigger' AND name=" + sqlString(name+"_ai") + ")",
+			trigger("_ai", "INSERT", insert),
+			trigger("_ad", "DELETE", remove),
+			trigger("_au", "UPDATE", remove+" "+insert),
+		}, ";\n")
+	case core.MSSQL:
+		// the key index is the primary key, whose name is generated
+		return "IF NOT EXISTS (SELECT * FROM sys.fulltext_catalogs WHERE name = " + sqlString(fulltextCatalog) + ")\n" +
+			"\tCREATE FULLTEXT CATALOG " + quote(fulltextCatalog) + ";\n" +
+			"IF NOT EXISTS (SELECT * FROM sys.fulltext_indexes WHERE object_id = OBJECT_ID(" + sqlString(tableName) + "))\n" +
+			"BEGIN\n" +
+			"\tDECLARE @key sysname, @sql nvarchar(max);\n" +
+			"\tSELECT @key = name FROM sys.indexes WHERE object_id = OBJECT_ID(" + sqlString(tableName) + ") AND is_primary_key = 1;\n" +
+			"\tSET @sql = " + sqlString("CREATE FULLTEXT INDEX ON "+quote(tableName)+" ("+strings.Join(quoted, ",")+") KEY INDEX ") +
+			" + QUOTENAME(@key) + " + sqlString(" ON "+quote(fulltextCatalog)) + ";\n" +
+			"\tEXEC(@sql);\n" +
+			"END"
+	}
+	return ""
+}
+
+// execIndexSQL executes the sql of an index. The full-text index of sqlite3
+// is several statements separated by ";\n", they're executed one by one
+// since a prepared statement runs the first one only.
+func (session *Session) execIndexSQL(sqlStr string) error {
+	sqls := []string{sqlStr}
+	if session.Engine.dialect.DBType() == core.SQLITE {
+		sqls = strings.Split(sqlStr, ";\n")
+	}
+	for _, s := range sqls {
+		if _, err := session.exec(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tsVectorRe matches the columns of to_tsvector read from postgres
+var tsVectorRe = regexp.MustCompile(`(?i)coalesce\(\s*\(*\s*"?([A-Za-z_][A-Za-z0-9_$]*)"?`)
+
+// parseFulltextDef turns the gin index of to_tsvector read from postgres
+// back into the full-text index of its columns, so Sync2 finds it
+func parseFulltextDef(def *Index) {
+	if !strings.EqualFold(def.Method, "gin") || len(def.Cols) != 1 ||
+		!strings.HasPrefix(strings.ToLower(strings.TrimLeft(def.Cols[0], "(")), "to_tsvector(") {
+		return
+	}
+	cols := make([]string, 0)
+	for _, m := range tsVectorRe.FindAllStringSubmatch(def.Cols[0], -1) {
+		cols = append(cols, m[1])
+	}
+	if len(cols) > 0 {
+		def.Method = "FULLTEXT"
+		def.Cols = cols
+	}
+}
+
+// matchParam is a full-text condition of Match
+type matchParam struct {
+	cols  []string
+	query string
+}
+
+// Match adds the full-text condition that cols, separated by commas, match
+// query. The columns should be the ones of an index tagged with fulltext,
+// they could be omitted if the table has one full-text index. The query is
+// in natural language on mysql, postgres and mssql, and it's a query of
+// fts5 on sqlite3. The other dialects search the columns by LIKE.
+func (statement *Statement) Match(cols string, query string) *Statement {
+	names := make([]string, 0)
+	for _, col := range strings.Split(cols, ",") {
+		if col = strings.Trim(strings.TrimSpace(col), "`[]\""); col != "" {
+			names = append(names, col)
+		}
+	}
+	statement.matches = append(statement.matches, matchParam{cols: names, query: query})
+	return statement
+}
+
+// OrderByRelevance orders the records by the relevance of the conditions
+// of Match, the most relevant ones first, before the other orders
+func (statement *Statement) OrderByRelevance() *Statement {
+	statement.relevance = true
+	return statement
+}
+
+// fulltextIndex returns the full-text index of table on cols, or the first
+// one by name if cols is empty
+func (engine *Engine) fulltextIndex(table *core.Table, cols []string) (*core.Index, *Index) {
+	if table == nil {
+		return nil, nil
+	}
+	indexes := engine.tableExt(table).indexes
+	names := make([]string, 0, len(indexes))
+	for name, def := range indexes {
+		if strings.ToUpper(def.Method) != "FULLTEXT" {
+			continue
+		}
+		indexCols := fulltextCols(def)
+		if len(cols) > 0 && strings.ToLower(strings.Join(indexCols, ",")) != strings.ToLower(strings.Join(cols, ",")) {
+			continue
+		}
+		if _, ok := table.Indexes[name]; ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+	if len(names) > 1 {
+		engine.LogWarnf("table %v has %d full-text indexes, Match uses %v", table.Name, len(names), names[0])
+	}
+	return table.Indexes[names[0]], indexes[names[0]]
+}
+
+// textLiteral returns the string literal of s for the dialect, the scores
+// are in ORDER BY whose arguments would be out of order
+func (engine *Engine) textLiteral(s string) string {
+	switch engine.dialect.DBType() {
+	case core.MYSQL:
+		return sqlString(strings.Replace(s, `\`, `\\`, -1))
+	case core.MSSQL:
+		return "N" + sqlString(s)
+	}
+	return sqlString(s)
+}
+
+// matchSQL returns the condition of a Match, its arguments and the score
+// of the relevance, score is empty if the dialect has none
+func (statement *Statement) matchSQL(match matchParam) (cond string, args []interface{}, score string) {
+	engine := statement.Engine
+	quote := engine.quoteName
+	tableName := statement.TableName()
+	ref := quote(statement.refName())
+
+	cols := match.cols
+	index, def := engine.fulltextIndex(statement.RefTable, cols)
+	if len(cols) == 0 && def != nil {
+		cols = fulltextCols(def)
+	}
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = ref + "." + quote(col)
+	}
+	colStr := strings.Join(quoted, ",")
+
+	switch engine.dialect.DBType() {
+	case core.MYSQL:
+		against := func(query string) string {
+			return "MATCH (" + colStr + ") AGAINST (" + query + " IN NATURAL LANGUAGE MODE)"
+		}
+		return against("?"), []interface{}{match.query}, against(engine.textLiteral(match.query))
+	case core.POSTGRES:
+		doc := engine.tsVector(cols, ref+".")
+		tsQuery := func(query string) string {
+			return "plainto_tsquery(" + engine.tsConfig() + ", " + query + ")"
+		}
+		return doc + " @@ " + tsQuery("?"), []interface{}{match.query},
+			"ts_rank(" + doc + ", " + tsQuery(engine.textLiteral(match.query)) + ")"
+	case core.SQLITE:
+		if index == nil {
+			break
+		}
+		_, name := splitSchema(tableName, "")
+		fts := quote(index.XName(name))
+		cond = ref + ".rowid IN (SELECT rowid FROM " + fts + " WHERE " + fts + " MATCH ?)"
+		// bm25 is lower for the more relevant ones
+		score = "(SELECT -bm25(" + fts + ") FROM " + fts + " WHERE " + fts + " MATCH " +
+			engine.textLiteral(match.query) + " AND " + fts + ".rowid = " + ref + ".rowid)"
+		return cond, []interface{}{match.query}, score
+	case core.MSSQL:
+		// the columns of FREETEXT are not qualified
+		names := make([]string, len(cols))
+		for i, col := range cols {
+			names[i] = quote(col)
+		}
+		nameStr := strings.Join(names, ",")
+		cond = "FREETEXT((" + nameStr + "), ?)"
+		if pkCols := statement.RefTable.PKColumns(); len(pkCols) == 1 {
+			score = "(SELECT ft.[RANK] FROM FREETEXTTABLE(" + quote(tableName) + ", (" + nameStr +
+				"), " + engine.textLiteral(match.query) + ") ft WHERE ft.[KEY] = " + ref + "." + quote(pkCols[0].Name) + ")"
+		}
+		return cond, []interface{}{match.query}, score
+	}
+
+	engine.LogWarnf("%v has no full-text index on %v, Match searches them by LIKE", engine.dialect.DBType(), cols)
+	likes := make([]string, len(quoted))
+	for i, col := range quoted {
+		likes[i] = col + " LIKE ?"
+		args = append(args, "%"+match.query+"%")
+	}
+	return "(" + strings.Join(likes, " "+engine.dialect.OrStr()+" ") + ")", args, ""
+}
+
+// processMatches adds the conditions of Match, and the order of relevance
+// if it's asked, when the table is known
+func (statement *Statement) processMatches() {
+	if len(statement.matches) == 0 {
+		return
+	}
+	matches := statement.matches
+	statement.matches = nil
+
+	scores := make([]string, 0, len(matches))
+	for _, match := range matches {
+		cond, args, score := statement.matchSQL(match)
+		statement.And(cond, args...)
+		if score != "" {
+			scores = append(scores, score)
+		}
+	}
+	if statement.relevance && len(scores) > 0 {
+		order := strings.Join(scores, " + ") + " DESC"
+		if statement.OrderStr != "" {
+			order += ", " + statement.OrderStr
+		}
+		statement.OrderStr = order
+	}
+}
+
+// Match adds the full-text condition that cols match query
+func (session *Session) Match(cols string, query string) *Session {
+	session.Statement.Match(cols, query)
+	return session
+}
+
+// OrderByRelevance orders the records by the relevance of Match
+func (session *Session) OrderByRelevance() *Session {
+	session.Statement.OrderByRelevance()
+	return session
+}
+
+// Match adds the full-text condition that cols match query
+func (engine *Engine) Match(cols string, query string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Match(cols, query)
+}
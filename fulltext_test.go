@@ -0,0 +1,83 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type FtProduct struct {
+	Id   int64
+	Name string `xorm:"fulltext(text)"`
+	Body string `xorm:"fulltext(text)"`
+}
+
+func TestFulltextSQL(t *testing.T) {
+	for dbType, expected := range map[core.DbType][]string{
+		core.MYSQL: {
+			"CREATE FULLTEXT INDEX `IDX_ft_product_TEXT` ON `ft_product` (`name`,`body`)",
+			"SELECT `id`, `name`, `body` FROM `ft_product` WHERE MATCH (`ft_product`.`name`,`ft_product`.`body`) " +
+				"AGAINST (? IN NATURAL LANGUAGE MODE) ORDER BY MATCH (`ft_product`.`name`,`ft_product`.`body`) " +
+				"AGAINST ('phone' IN NATURAL LANGUAGE MODE) DESC",
+		},
+		core.POSTGRES: {
+			`CREATE INDEX "IDX_ft_product_TEXT" ON "ft_product" USING gin ((to_tsvector('simple', ` +
+				`coalesce("name", '') || ' ' || coalesce("body", ''))))`,
+			`SELECT "id", "name", "body" FROM "ft_product" WHERE to_tsvector('simple', coalesce("ft_product"."name", '') || ' ' || ` +
+				`coalesce("ft_product"."body", '')) @@ plainto_tsquery('simple', ?) ORDER BY ts_rank(to_tsvector('simple', ` +
+				`coalesce("ft_product"."name", '') || ' ' || coalesce("ft_product"."body", '')), plainto_tsquery('simple', 'phone')) DESC`,
+		},
+	} {
+		engine := testEngine(t, dbType)
+		table := engine.TableInfo(new(FtProduct))
+		assertSQL(t, expected[0], engine.indexSQL(table.Name, table, table.Indexes["TEXT"]))
+
+		session := engine.NewSession()
+		session.Match("", "phone").OrderByRelevance()
+		sqlStr, _, err := session.Statement.genGetSql(new(FtProduct))
+		assertNoErr(t, err)
+		assertSQL(t, expected[1], sqlStr)
+		session.Close()
+	}
+}
+
+func TestMatch(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	if _, err := engine.Exec("CREATE VIRTUAL TABLE temp.fts_probe USING fts5(x)"); err != nil {
+		t.Skip("sqlite3 is built without fts5")
+	}
+	assertNoErr(t, engine.Sync2(new(FtProduct)))
+	// the fts5 table and its triggers are created once
+	assertNoErr(t, engine.Sync2(new(FtProduct)))
+
+	for _, p := range []*FtProduct{
+		{Name: "phone case", Body: "a case"},
+		{Name: "phone", Body: "a phone, the best phone"},
+		{Name: "laptop", Body: "no match"},
+	} {
+		_, err := engine.Insert(p)
+		assertNoErr(t, err)
+	}
+
+	var products []FtProduct
+	assertNoErr(t, engine.Match("", "phone").OrderByRelevance().Find(&products))
+	if len(products) != 2 || products[0].Id != 2 {
+		t.Errorf("expected the phones by relevance, got %v", products)
+	}
+
+	// the index is kept in sync by the triggers
+	_, err := engine.Id(3).Update(&FtProduct{Body: "a laptop, not a phone"})
+	assertNoErr(t, err)
+	_, err = engine.Id(1).Delete(new(FtProduct))
+	assertNoErr(t, err)
+	products = nil
+	assertNoErr(t, engine.Match("name, body", "phone").Asc("id").Find(&products))
+	if len(products) != 2 || products[0].Id != 2 || products[1].Id != 3 {
+		t.Errorf("expected the phones 2 and 3, got %v", products)
+	}
+}
@@ -51,6 +51,79 @@ func isZero(k interface{}) bool {
 	return false
 }
 
+// isZeroValue reports whether v is the zero value of its type
+func isZeroValue(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// isEmptyValue reports whether v is zero or an empty slice or map
+func isEmptyValue(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+		return v.Len() == 0
+	}
+	return isZeroValue(v)
+}
+
+// fieldByPath returns the struct field of a dotted field name like "Parent.Name"
+func fieldByPath(t reflect.Type, path string) (reflect.StructField, bool) {
+	var field reflect.StructField
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return field, false
+		}
+		var ok bool
+		if field, ok = t.FieldByName(name); !ok {
+			return field, false
+		}
+		t = field.Type
+	}
+	return field, true
+}
+
+// colValueOf returns the field of col in bean, see colValueOfV
+func colValueOf(col *core.Column, bean interface{}) (*reflect.Value, error) {
+	dataStruct := reflect.Indirect(reflect.ValueOf(bean))
+	return colValueOfV(col, &dataStruct)
+}
+
+// colValueOfV returns the field of col in the struct. The FieldName of the
+// extended columns is a path like Home.Geo.Lat which could be deeper than
+// core.Column.ValueOfV supports, the nil pointers on the path are allocated.
+func colValueOfV(col *core.Column, dataStruct *reflect.Value) (*reflect.Value, error) {
+	fieldPath := strings.Split(col.FieldName, ".")
+
+	v := *dataStruct
+	if v.Kind() == reflect.Interface {
+		v = reflect.ValueOf(v.Interface())
+	}
+	if v.Kind() == reflect.Map {
+		fieldValue := v.MapIndex(reflect.ValueOf(fieldPath[len(fieldPath)-1]))
+		return &fieldValue, nil
+	}
+
+	for i, name := range fieldPath {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return nil, fmt.Errorf("field %v is not valid", col.FieldName)
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field %v is not valid", col.FieldName)
+		}
+		if v = v.FieldByName(name); !v.IsValid() {
+			return nil, fmt.Errorf("field %v is not valid", col.FieldName)
+		}
+	}
+	return &v, nil
+}
+
 func isPKZero(pk core.PK) bool {
 	for _, k := range pk {
 		if isZero(k) {
@@ -294,7 +367,7 @@ func query2(db *core.DB, sqlStr string, params ...interface{}) (resultsSlice []m
 }
 
 func setColumnTime(bean interface{}, col *core.Column, t time.Time) {
-	v, err := col.ValueOf(bean)
+	v, err := colValueOf(col, bean)
 	if err != nil {
 		return
 	}
@@ -325,7 +398,7 @@ func genCols(table *core.Table, session *Session, bean interface{}, useCol bool,
 			continue
 		}
 
-		fieldValuePtr, err := col.ValueOf(bean)
+		fieldValuePtr, err := colValueOf(col, bean)
 		if err != nil {
 			session.Engine.LogError(err)
 			continue
@@ -381,7 +454,7 @@ func genCols(table *core.Table, session *Session, bean interface{}, useCol bool,
 				col := table.GetColumn(colName)
 				setColumnTime(bean, col, t)
 			})
-		} else if col.IsVersion && session.Statement.checkVersion {
+		} else if col.IsVersion && session.Statement.checkVersion && !isTimeVersion(col) {
 			args = append(args, 1)
 		} else {
 			arg, err := session.value2Interface(col, fieldValue)
@@ -399,3 +472,31 @@ func genCols(table *core.Table, session *Session, bean interface{}, useCol bool,
 	}
 	return colNames, args, nil
 }
+
+// splitTopLevel splits s by sep which is neither in parentheses nor in
+// single quotes, the empty parts are dropped. It's used by tags like
+// check(a > 0) and comment('a comment').
+func splitTopLevel(s string, sep byte) []string {
+	parts := make([]string, 0)
+	depth, inQuote, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth <= 0:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
@@ -0,0 +1,185 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// supportSequence reports whether the database has sequences which could
+// back the `seq(name)` tag
+func supportSequence(dbType core.DbType) bool {
+	return dbType == core.POSTGRES || dbType == core.ORACLE || dbType == core.MSSQL
+}
+
+// sequenceName returns the sequence which generates col's values before
+// insert, empty if the column has none. Oracle has no autoincrement, so an
+// autoincrement column without `seq(name)` is backed by sequence SEQ_<TABLE>.
+func (engine *Engine) sequenceName(table *core.Table, col *core.Column) string {
+	dbType := engine.dialect.DBType()
+	if !supportSequence(dbType) {
+		return ""
+	}
+	if colExt := engine.columnExt(table, col); colExt != nil && colExt.sequence != "" {
+		return colExt.sequence
+	}
+	if dbType == core.ORACLE && col.IsAutoIncrement {
+		// the sequence is in the schema of the table
+		schema, name := splitSchema(engine.tbNameWithSchema(table.Name), "")
+		if schema != "" {
+			return schema + ".SEQ_" + strings.ToUpper(name)
+		}
+		return "SEQ_" + strings.ToUpper(name)
+	}
+	return ""
+}
+
+// nextSequenceValues reads the next n values of the sequence by one query,
+// mssql has no row generator so its values are read one by one
+func (session *Session) nextSequenceValues(seqName string, n int) ([]int64, error) {
+	var sqlStr string
+	queries := 1
+	switch session.Engine.dialect.DBType() {
+	case core.POSTGRES:
+		sqlStr = fmt.Sprintf("SELECT nextval('%v') FROM generate_series(1, %d)", seqName, n)
+	case core.ORACLE:
+		sqlStr = fmt.Sprintf("SELECT %v.NEXTVAL FROM DUAL CONNECT BY LEVEL <= %d", seqName, n)
+	case core.MSSQL:
+		sqlStr = fmt.Sprintf("SELECT NEXT VALUE FOR %v", session.Engine.Quote(seqName))
+		queries = n
+	default:
+		return nil, ErrNotImplemented
+	}
+
+	ids := make([]int64, 0, n)
+	for i := 0; i < queries; i++ {
+		results, err := session.query(sqlStr)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			for _, v := range result {
+				id, err := strconv.ParseInt(string(v), 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) < n {
+		return nil, fmt.Errorf("sequence %v returns %d values, expected %d", seqName, len(ids), n)
+	}
+	return ids, nil
+}
+
+// autoIncrOf returns the start value and the increment of col, 0 if not given
+func (engine *Engine) autoIncrOf(table *core.Table, col *core.Column) (start int64, step int64) {
+	if colExt := engine.columnExt(table, col); colExt != nil {
+		return colExt.autoIncrStart, colExt.autoIncrStep
+	}
+	return 0, 0
+}
+
+// genIdentitySQLs generates the sqls which set the start values and
+// increments of the identity columns after the table is created.
+func (statement *Statement) genIdentitySQLs() []string {
+	engine := statement.Engine
+	table := statement.RefTable
+	tableName := statement.TableName()
+	sqls := make([]string, 0)
+
+	for _, col := range table.Columns() {
+		if !col.IsAutoIncrement || engine.sequenceName(table, col) != "" {
+			continue
+		}
+		start, step := engine.autoIncrOf(table, col)
+		if start == 0 && step == 0 {
+			continue
+		}
+
+		switch engine.dialect.DBType() {
+		case core.POSTGRES:
+			// the sequence created by SERIAL
+			sqlStr := fmt.Sprintf("ALTER SEQUENCE %v", engine.Quote(tableName+"_"+col.Name+"_seq"))
+			if step != 0 {
+				sqlStr += fmt.Sprintf(" INCREMENT BY %d", step)
+			}
+			if start != 0 {
+				sqlStr += fmt.Sprintf(" RESTART WITH %d", start)
+			}
+			sqls = append(sqls, sqlStr)
+		case core.SQLITE:
+			if step != 0 {
+				engine.LogWarnf("sqlite3 has no increment of table %v", tableName)
+			}
+			if start != 0 {
+				sqls = append(sqls, fmt.Sprintf("INSERT INTO sqlite_sequence (name, seq) "+
+					"SELECT '%v', %d WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = '%v')",
+					tableName, start-1, tableName))
+			}
+		}
+	}
+	return sqls
+}
+
+func (statement *Statement) genCreateSequenceSQL(seqName string, start, step int64) string {
+	if start == 0 {
+		start = 1
+	}
+	if step == 0 {
+		step = 1
+	}
+	if statement.Engine.dialect.DBType() == core.MSSQL {
+		seqName = statement.Engine.Quote(seqName)
+	}
+	return fmt.Sprintf("CREATE SEQUENCE %v START WITH %d INCREMENT BY %d", seqName, start, step)
+}
+
+// isSequenceExist checks if the sequence exists
+func (session *Session) isSequenceExist(seqName string) (bool, error) {
+	_, seqName = splitSchema(seqName, "")
+	var sqlStr string
+	switch session.Engine.dialect.DBType() {
+	case core.POSTGRES:
+		sqlStr = "SELECT relname FROM pg_class WHERE relkind = 'S' AND relname = ?"
+	case core.ORACLE:
+		sqlStr = "SELECT sequence_name FROM user_sequences WHERE sequence_name = ?"
+		seqName = strings.ToUpper(seqName)
+	case core.MSSQL:
+		sqlStr = "SELECT name FROM sys.sequences WHERE name = ?"
+	default:
+		return false, ErrNotImplemented
+	}
+	results, err := session.query(sqlStr, seqName)
+	return len(results) > 0, err
+}
+
+// createSequences creates the table's sequences which do not exist
+func (session *Session) createSequences() error {
+	table := session.Statement.RefTable
+	for _, col := range table.Columns() {
+		seqName := session.Engine.sequenceName(table, col)
+		if seqName == "" {
+			continue
+		}
+		isExist, err := session.isSequenceExist(seqName)
+		if err != nil {
+			return err
+		}
+		if isExist {
+			continue
+		}
+		start, step := session.Engine.autoIncrOf(table, col)
+		if _, err = session.exec(session.Statement.genCreateSequenceSQL(seqName, start, step)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
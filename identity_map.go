@@ -0,0 +1,154 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// UseIdentityMap makes the session load a record only once. The records
+// loaded by Find into slices or maps of pointers and by cascade share the
+// same pointer, Get by Id copies the loaded record without query. Find into
+// slices or maps of structs neither uses nor fills it since their elements
+// are copies. Update, Delete and Exec forget the loaded records of the table,
+// NoCache and Unscoped bypass it.
+func (session *Session) UseIdentityMap() *Session {
+	if session.identities == nil {
+		session.identities = make(map[string]map[string]interface{})
+	}
+	return session
+}
+
+// useIdentities reports whether the current statement uses the identity map
+func (session *Session) useIdentities() bool {
+	return session.identities != nil && session.Statement.UseCache &&
+		!session.Statement.unscoped && !session.Statement.onlyDeleted
+}
+
+func (session *Session) identityOf(tableName string, pk core.PK) (interface{}, bool) {
+	sid, err := pk.ToString()
+	if err != nil {
+		return nil, false
+	}
+	bean, ok := session.identities[tableName][sid]
+	return bean, ok
+}
+
+// putIdentity remembers the loaded bean, it returns the bean loaded before
+// for the same record if there's one.
+func (session *Session) putIdentity(tableName string, bean interface{}) interface{} {
+	table := session.Engine.autoMapType(rValue(bean))
+	if len(table.PrimaryKeys) == 0 {
+		return bean
+	}
+	pk := session.Engine.IdOf(bean)
+	if isPKZero(pk) {
+		return bean
+	}
+	sid, err := pk.ToString()
+	if err != nil {
+		return bean
+	}
+
+	beans, ok := session.identities[tableName]
+	if !ok {
+		beans = make(map[string]interface{})
+		session.identities[tableName] = beans
+	}
+	if loaded, ok := beans[sid]; ok {
+		return loaded
+	}
+	beans[sid] = bean
+	return bean
+}
+
+// putIdentities remembers the beans loaded by Find, the elements of a slice
+// or a map of pointers are replaced by the beans loaded before. The slices
+// and maps of structs are skipped.
+func (session *Session) putIdentities(tableName string, rowsSlicePtr interface{}) {
+	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
+	if sliceValue.Type().Elem().Kind() != reflect.Ptr {
+		return
+	}
+	switch sliceValue.Kind() {
+	case reflect.Slice:
+		for i := 0; i < sliceValue.Len(); i++ {
+			elem := sliceValue.Index(i)
+			if elem.IsNil() {
+				continue
+			}
+			loaded := session.putIdentity(tableName, elem.Interface())
+			elem.Set(reflect.ValueOf(loaded))
+		}
+	case reflect.Map:
+		for _, key := range sliceValue.MapKeys() {
+			elem := sliceValue.MapIndex(key)
+			if elem.IsNil() {
+				continue
+			}
+			loaded := session.putIdentity(tableName, elem.Interface())
+			sliceValue.SetMapIndex(key, reflect.ValueOf(loaded))
+		}
+	}
+}
+
+// clearIdentities forgets the loaded records of the table, or all of them
+// if tableName is empty
+func (session *Session) clearIdentities(tableName string) {
+	if session.identities == nil {
+		return
+	}
+	if tableName == "" {
+		session.identities = make(map[string]map[string]interface{})
+		return
+	}
+	delete(session.identities, tableName)
+}
+
+// getIdentity copies the loaded record to bean for Get by Id
+func (session *Session) getIdentity(bean interface{}) bool {
+	st := session.Statement
+	if !session.useIdentities() || st.IdParam == nil || st.WhereStr != "" || st.RawSQL != "" ||
+		st.JoinStr != "" || len(st.inColumns) > 0 || !isZeroValue(rValue(bean)) {
+		return false
+	}
+	loaded, ok := session.identityOf(st.TableName(), *st.IdParam)
+	if !ok {
+		return false
+	}
+	rValue(bean).Set(rValue(loaded))
+	return true
+}
+
+// cascadeGet loads the record referenced by a cascade field and returns the
+// pointer to it
+func (session *Session) cascadeGet(structType reflect.Type, pk core.PK) (reflect.Value, error) {
+	useIdentities := session.useIdentities()
+	tableName := ""
+	if useIdentities {
+		tableName = session.Engine.autoMapType(reflect.New(structType).Elem()).Name
+		if loaded, ok := session.identityOf(tableName, pk); ok {
+			return reflect.ValueOf(loaded), nil
+		}
+	}
+
+	structInter := reflect.New(structType)
+	related := session.relatedSession()
+	defer session.closeRelated(related)
+	has, err := related.Id(pk).Get(structInter.Interface())
+	if err != nil {
+		return structInter, err
+	}
+	if !has {
+		return structInter, errors.New("cascade obj is not exist!")
+	}
+	if useIdentities {
+		return reflect.ValueOf(session.putIdentity(tableName, structInter.Interface())), nil
+	}
+	return structInter, nil
+}
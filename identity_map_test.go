@@ -0,0 +1,102 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+)
+
+type IdentityUser struct {
+	Id   int64
+	Name string
+}
+
+func TestIdentityMap(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(IdentityUser)))
+	_, err := engine.Insert(&IdentityUser{Name: "a"}, &IdentityUser{Name: "b"})
+	assertNoErr(t, err)
+
+	session := engine.NewSession().UseIdentityMap()
+	defer session.Close()
+	var users, again []*IdentityUser
+	assertNoErr(t, session.Asc("id").Find(&users))
+	assertNoErr(t, session.Asc("id").Find(&again))
+	if len(users) != 2 || len(again) != 2 || users[0] != again[0] || users[1] != again[1] {
+		t.Errorf("the same records are loaded twice: %v, %v", users, again)
+	}
+
+	log.reset()
+	var user IdentityUser
+	has, err := session.Id(users[0].Id).Get(&user)
+	assertNoErr(t, err)
+	if !has || user.Name != "a" || len(log.sqls) != 0 {
+		t.Errorf("Get queries the loaded record %+v: %v", user, log.sqls)
+	}
+
+	// NoCache bypasses the loaded records
+	user = IdentityUser{}
+	has, err = session.NoCache().Id(users[0].Id).Get(&user)
+	assertNoErr(t, err)
+	if !has || len(log.sqls) != 1 {
+		t.Errorf("NoCache doesn't query: %v", log.sqls)
+	}
+
+	// the loaded records are forgotten after update
+	_, err = session.Id(users[0].Id).Update(&IdentityUser{Name: "x"})
+	assertNoErr(t, err)
+	log.reset()
+	user = IdentityUser{}
+	has, err = session.Id(users[0].Id).Get(&user)
+	assertNoErr(t, err)
+	if !has || user.Name != "x" || len(log.sqls) != 1 {
+		t.Errorf("the updated record is not queried again: %+v, %v", user, log.sqls)
+	}
+}
+
+func TestIdentityMapFindKinds(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(IdentityUser)))
+	_, err := engine.Insert(&IdentityUser{Name: "a"}, &IdentityUser{Name: "b"})
+	assertNoErr(t, err)
+
+	session := engine.NewSession().UseIdentityMap()
+	defer session.Close()
+
+	// the structs are copies, they neither use nor fill the loaded records
+	var values []IdentityUser
+	assertNoErr(t, session.Find(&values))
+	valueMap := make(map[int64]IdentityUser)
+	assertNoErr(t, session.Find(&valueMap))
+	if len(values) != 2 || len(valueMap) != 2 || len(session.identities) != 0 {
+		t.Errorf("the structs are loaded as records: %v", session.identities)
+	}
+
+	// the maps of pointers share the pointers with the slices
+	ptrMap := make(map[int64]*IdentityUser)
+	assertNoErr(t, session.Find(&ptrMap))
+	var ptrs []*IdentityUser
+	assertNoErr(t, session.Asc("id").Find(&ptrs))
+	again := make(map[int64]*IdentityUser)
+	assertNoErr(t, session.Find(&again))
+	if len(ptrs) != 2 || len(ptrMap) != 2 {
+		t.Fatalf("unexpected records %v, %v", ptrs, ptrMap)
+	}
+	for _, user := range ptrs {
+		if ptrMap[user.Id] != user || again[user.Id] != user {
+			t.Errorf("record %v is loaded twice", user.Id)
+		}
+	}
+
+	log.reset()
+	var user IdentityUser
+	has, err := session.Id(ptrs[1].Id).Get(&user)
+	assertNoErr(t, err)
+	if !has || user.Name != "b" || len(log.sqls) != 0 {
+		t.Errorf("Get queries the record loaded by the map %+v: %v", user, log.sqls)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type IdentityStart struct {
+	Id   int64 `xorm:"pk autoincr(100, 5)"`
+	Name string
+}
+
+type IdentitySeq struct {
+	Id   int64 `xorm:"pk autoincr seq(identity_seq_id)"`
+	Name string
+}
+
+func TestIdentityTableSQL(t *testing.T) {
+	expected := map[core.DbType][2]string{
+		core.MYSQL: {
+			"CREATE TABLE IF NOT EXISTS `identity_start` (`id` BIGINT(20) PRIMARY KEY AUTO_INCREMENT NOT NULL, `name` VARCHAR(255) NULL) AUTO_INCREMENT=100",
+			"CREATE TABLE IF NOT EXISTS `identity_seq` (`id` BIGINT(20) PRIMARY KEY AUTO_INCREMENT NOT NULL, `name` VARCHAR(255) NULL)",
+		},
+		core.POSTGRES: {
+			`CREATE TABLE IF NOT EXISTS "identity_start" ("id" SERIAL PRIMARY KEY NOT NULL, "name" VARCHAR(255) NULL)`,
+			`CREATE TABLE IF NOT EXISTS "identity_seq" ("id" BIGINT PRIMARY KEY DEFAULT nextval('identity_seq_id') NOT NULL, "name" VARCHAR(255) NULL)`,
+		},
+		core.MSSQL: {
+			`IF NOT EXISTS (SELECT [name] FROM sys.tables WHERE [object_id] = OBJECT_ID(N'identity_start') ) CREATE TABLE "identity_start" ("id" BIGINT PRIMARY KEY IDENTITY(100,5) NOT NULL, "name" VARCHAR(255) NULL);`,
+			`IF NOT EXISTS (SELECT [name] FROM sys.tables WHERE [object_id] = OBJECT_ID(N'identity_seq') ) CREATE TABLE "identity_seq" ("id" BIGINT PRIMARY KEY DEFAULT NEXT VALUE FOR "identity_seq_id" NOT NULL, "name" VARCHAR(255) NULL);`,
+		},
+		core.ORACLE: {
+			"CREATE TABLE identity_start (id NUMBER NOT NULL, name VARCHAR2(255) NULL, PRIMARY KEY (id))",
+			"CREATE TABLE identity_seq (id NUMBER NOT NULL, name VARCHAR2(255) NULL, PRIMARY KEY (id))",
+		},
+	}
+	for dbType, sqls := range expected {
+		engine := testEngine(t, dbType)
+		for i, bean := range []interface{}{new(IdentityStart), new(IdentitySeq)} {
+			session := engine.NewSession()
+			session.Statement.RefTable = engine.TableInfo(bean)
+			assertSQL(t, sqls[i], session.Statement.genCreateTableSQL())
+			session.Close()
+		}
+	}
+}
+
+func TestIdentitySQLs(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	session := engine.NewSession()
+	defer session.Close()
+	session.Statement.RefTable = engine.TableInfo(new(IdentityStart))
+	sqls := session.Statement.genIdentitySQLs()
+	if len(sqls) != 1 {
+		t.Fatalf("expected 1 sql, got %v", sqls)
+	}
+	assertSQL(t, `ALTER SEQUENCE "identity_start_id_seq" INCREMENT BY 5 RESTART WITH 100`, sqls[0])
+
+	assertSQL(t, "CREATE SEQUENCE identity_seq_id START WITH 1 INCREMENT BY 1",
+		session.Statement.genCreateSequenceSQL("identity_seq_id", 0, 0))
+}
+
+func TestIdentityStart(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(IdentityStart)))
+
+	records := []IdentityStart{{Name: "a"}, {Name: "b"}}
+	_, err := engine.Insert(&records)
+	assertNoErr(t, err)
+	records = nil
+	assertNoErr(t, engine.Asc("id").Find(&records))
+	if len(records) != 2 || records[0].Id != 100 || records[1].Id != 101 {
+		t.Errorf("the records are %+v, expected the ids 100 and 101", records)
+	}
+}
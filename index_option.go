@@ -0,0 +1,346 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// Index is an index with the options which core.Index has no place for. It's
+// declared by tags like index(name, desc, where(deleted IS NULL)), returned
+// by method Indexes of a bean or read by DBMetas.
+type Index struct {
+	Name       string
+	Unique     bool
+	Cols       []string // columns, "col DESC" for the descending ones, or expressions like lower(email)
+	Method     string   // i.e. BTREE, HASH, GIN, GIST, FULLTEXT, empty for the default
+	Where      string   // condition of a partial index
+	Include    []string // columns stored in the index but not indexed
+	Concurrent bool     // created without locking the table, postgres only
+}
+
+// indexOptionDialect is implemented by the dialects which read the options
+// of indexes, the indexes are named the same as GetIndexes names them.
+type indexOptionDialect interface {
+	GetIndexOptions(tableName string) (map[string]*Index, error)
+}
+
+// splitIndexCol splits a column of Index into the column or expression and
+// its sort direction, isColumn is false for an expression
+func splitIndexCol(s string) (expr string, desc bool, isColumn bool) {
+	expr = strings.TrimSpace(s)
+	upper := strings.ToUpper(expr)
+	if strings.HasSuffix(upper, " DESC") {
+		expr, desc = strings.TrimSpace(expr[:len(expr)-len(" DESC")]), true
+	} else if strings.HasSuffix(upper, " ASC") {
+		expr = strings.TrimSpace(expr[:len(expr)-len(" ASC")])
+	}
+	isColumn = strings.IndexAny(expr, "()+-*/|' ,:") < 0
+	if isColumn {
+		expr = strings.Trim(expr, "`[]\"")
+	}
+	return
+}
+
+// plain reports whether the index has the columns only, it's what
+// core.Index could define
+func (index *Index) plain() bool {
+	if normalizeIndexMethod(index.Method) != "" || index.Where != "" || len(index.Include) > 0 || index.Concurrent {
+		return false
+	}
+	for _, col := range index.Cols {
+		if _, desc, isColumn := splitIndexCol(col); desc || !isColumn {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeIndexMethod returns the method in upper case, empty for the
+// default ones
+func normalizeIndexMethod(method string) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	switch method {
+	case "BTREE", "NORMAL", "NONCLUSTERED":
+		return ""
+	}
+	return method
+}
+
+// signature returns what's compared by Sync2
+func (index *Index) signature() string {
+	cols := make([]string, len(index.Cols))
+	for i, col := range index.Cols {
+		expr, desc, _ := splitIndexCol(col)
+		cols[i] = normalizeExpr(expr)
+		if desc {
+			cols[i] += " desc"
+		}
+	}
+	include := make([]string, len(index.Include))
+	for i, col := range index.Include {
+		include[i] = normalizeExpr(col)
+	}
+	sort.Strings(include)
+	return strings.Join([]string{strings.Join(cols, ","), normalizeIndexMethod(index.Method),
+		normalizeExpr(index.Where), strings.Join(include, ",")}, ";")
+}
+
+// coreIndex returns the core index of the definition and marks its columns,
+// the expressions are not in the core index
+func (index *Index) coreIndex(table *core.Table) *core.Index {
+	indexType := core.IndexType
+	if index.Unique {
+		indexType = core.UniqueType
+	}
+	coreIndex := core.NewIndex(index.Name, indexType)
+	for _, col := range index.Cols {
+		if name, _, isColumn := splitIndexCol(col); isColumn {
+			coreIndex.AddColumn(name)
+			if column := table.GetColumn(name); column != nil {
+				column.Indexes[coreIndex.Name] = true
+			}
+		}
+	}
+	return coreIndex
+}
+
+// parseIndexTag parses the tag index(name, option...) or unique(name,
+// option...) into the index in indexes. It returns the name of the index
+// and how the column is indexed, expr is the expression indexed instead of
+// the column, order is " DESC", " ASC" or empty.
+func parseIndexTag(indexes map[string]*Index, tag string, unique bool) (name, order, expr string) {
+	parts := splitTopLevel(tag, ',')
+	if len(parts) == 0 {
+		return "", "", ""
+	}
+	// the names in tags are in upper case
+	name = strings.ToUpper(parts[0])
+	index, ok := indexes[name]
+	if !ok {
+		index = &Index{Name: name, Unique: unique}
+		indexes[name] = index
+	}
+
+	for _, part := range parts[1:] {
+		k := strings.ToUpper(part)
+		switch {
+		case k == "DESC" || k == "ASC":
+			order = " " + k
+		case strings.HasPrefix(k, "EXPR(") && strings.HasSuffix(k, ")"):
+			expr = part[len("EXPR")+1 : len(part)-1]
+		case strings.HasPrefix(k, "USING(") && strings.HasSuffix(k, ")"):
+			index.Method = part[len("USING")+1 : len(part)-1]
+		case strings.HasPrefix(k, "WHERE(") && strings.HasSuffix(k, ")"):
+			index.Where = part[len("WHERE")+1 : len(part)-1]
+		case strings.HasPrefix(k, "INCLUDE(") && strings.HasSuffix(k, ")"):
+			index.Include = append(index.Include, splitTopLevel(part[len("INCLUDE")+1:len(part)-1], ' ')...)
+		case k == "CONCURRENT":
+			index.Concurrent = true
+		}
+	}
+	return
+}
+
+// Indexes returns the indexes of a mapped table, or of a table returned by
+// the last DBMetas, with their options
+func (engine *Engine) Indexes(table *core.Table) map[string]*Index {
+	ext := engine.tableExt(table)
+	indexes := make(map[string]*Index, len(table.Indexes))
+	for name, index := range table.Indexes {
+		indexes[name] = engine.indexDef(ext, index)
+	}
+	return indexes
+}
+
+// indexDef returns the definition of a core index, its options are in ext
+func (engine *Engine) indexDef(ext *tableExt, index *core.Index) *Index {
+	if def, ok := ext.indexes[index.Name]; ok {
+		return def
+	}
+	cols := make([]string, len(index.Cols))
+	copy(cols, index.Cols)
+	return &Index{Name: index.Name, Unique: index.Type == core.UniqueType, Cols: cols}
+}
+
+// sameIndex reports whether the index of table and the index of oriTable,
+// read from database, have the same options. The columns of the plain ones
+// have been compared by core.Index.
+func (engine *Engine) sameIndex(table *core.Table, index *core.Index, oriTable *core.Table, oriIndex *core.Index) bool {
+	def := engine.indexDef(engine.tableExt(table), index)
+	oriDef := engine.indexDef(engine.tableExt(oriTable), oriIndex)
+	if def.plain() && oriDef.plain() {
+		return true
+	}
+	return def.signature() == oriDef.signature()
+}
+
+// indexSQL returns the sql which creates an index of table with its options
+func (engine *Engine) indexSQL(tableName string, table *core.Table, index *core.Index) string {
+	ext := engine.tableExt(table)
+	def, ok := ext.indexes[index.Name]
+	if !ok {
+		def = engine.indexDef(ext, index)
+	}
+	dbType := engine.dialect.DBType()
+	if strings.ToUpper(def.Method) == "FULLTEXT" && dbType != core.MYSQL {
+		if sqlStr := engine.fulltextIndexSQL(tableName, table, index, def); sqlStr != "" {
+			return sqlStr
+		}
+	}
+	quote := engine.Quote
+
+	cols := make([]string, 0, len(def.Cols))
+	for _, col := range def.Cols {
+		expr, desc, isColumn := splitIndexCol(col)
+		if isColumn {
+			expr = quote(expr)
+		} else if dbType == core.MSSQL {
+			engine.LogWarnf("mssql has no expression index, %v of index %v is ignored", expr, def.Name)
+			continue
+		} else {
+			expr = "(" + engine.jsonIndexExpr(expr) + ")"
+		}
+		if desc {
+			expr += " DESC"
+		}
+		cols = append(cols, expr)
+	}
+
+	kind := ""
+	if index.Type == core.UniqueType {
+		kind = "UNIQUE "
+	}
+	var using string
+	method := strings.ToUpper(def.Method)
+	switch {
+	case method == "":
+	case dbType == core.MYSQL && (method == "FULLTEXT" || method == "SPATIAL"):
+		kind = method + " "
+	case dbType == core.MYSQL:
+		using = " USING " + method
+	case dbType == core.POSTGRES:
+		using = " USING " + strings.ToLower(method)
+	case dbType == core.MSSQL && (method == "CLUSTERED" || method == "NONCLUSTERED"):
+		kind += method + " "
+	case dbType == core.ORACLE && method == "BITMAP":
+		kind = method + " "
+	default:
+		engine.LogWarnf("%v has no index method %v, index %v is a normal one", dbType, method, def.Name)
+	}
+
+	sqlStr := "CREATE " + kind + "INDEX "
+	if def.Concurrent {
+		if dbType == core.POSTGRES {
+			sqlStr += "CONCURRENTLY "
+		} else {
+			engine.LogWarnf("%v could not create index %v concurrently", dbType, def.Name)
+		}
+	}
+	// the index is in the schema of its table, its name has the table name
+	// only, oracle creates it in the schema of the index name
+	schema, name := splitSchema(tableName, "")
+	name = index.XName(name)
+	if dbType == core.ORACLE && schema != "" {
+		name = schema + "." + name
+	}
+	sqlStr += quote(name) + " ON " + quote(tableName)
+	if dbType == core.POSTGRES {
+		sqlStr += using
+	}
+	sqlStr += " (" + strings.Join(cols, ",") + ")"
+	if dbType == core.MYSQL {
+		sqlStr += using
+	}
+
+	if len(def.Include) > 0 {
+		if dbType == core.POSTGRES || dbType == core.MSSQL {
+			include := make([]string, len(def.Include))
+			for i, col := range def.Include {
+				include[i] = quote(col)
+			}
+			sqlStr += " INCLUDE (" + strings.Join(include, ",") + ")"
+		} else {
+			engine.LogWarnf("%v has no included columns, they are ignored by index %v", dbType, def.Name)
+		}
+	}
+	if def.Where != "" {
+		if dbType == core.POSTGRES || dbType == core.SQLITE || dbType == core.MSSQL {
+			sqlStr += " WHERE " + def.Where
+		} else {
+			engine.LogWarnf("%v has no partial index, index %v is on all records", dbType, def.Name)
+		}
+	}
+	return sqlStr
+}
+
+// dropIndexSQL returns the sql which drops an index of table, the index of a
+// qualified table is in its schema and named by the unqualified table name
+func (engine *Engine) dropIndexSQL(tableName string, index *core.Index) string {
+	schema, name := splitSchema(tableName, "")
+	if schema == "" {
+		return engine.dialect.DropIndexSql(tableName, index)
+	}
+	idxName := index.Name
+	if index.IsRegular {
+		idxName = index.XName(name)
+	}
+	if engine.dialect.IndexOnTable() {
+		return fmt.Sprintf("DROP INDEX %v ON %v", engine.Quote(idxName), engine.Quote(tableName))
+	}
+	return fmt.Sprintf("DROP INDEX %v", engine.Quote(schema+"."+idxName))
+}
+
+// parseIndexDef parses the columns, the included columns and the condition
+// of CREATE INDEX, it's used by the dialects which keep the definitions
+func parseIndexDef(def *Index, sqlStr string) {
+	upper := strings.ToUpper(sqlStr)
+	pos := strings.Index(upper, " ON ")
+	if pos < 0 {
+		return
+	}
+	if using := strings.Index(upper, " USING "); using > pos {
+		if fields := strings.Fields(sqlStr[using+len(" USING "):]); len(fields) > 0 {
+			def.Method = strings.TrimSuffix(fields[0], "(")
+		}
+	}
+	for _, col := range splitTopLevel(parenBody(sqlStr[pos:]), ',') {
+		expr, desc, isColumn := splitIndexCol(col)
+		// the expressions are kept in parentheses by some databases
+		if body := parenBody(expr); !isColumn && strings.HasPrefix(expr, "(") &&
+			strings.HasSuffix(expr, ")") && strings.TrimSpace(expr[1:len(expr)-1]) == body {
+			expr = body
+		}
+		if desc {
+			expr += " DESC"
+		}
+		def.Cols = append(def.Cols, expr)
+	}
+	if include := strings.Index(upper, " INCLUDE "); include > pos {
+		for _, col := range splitTopLevel(parenBody(sqlStr[include:]), ',') {
+			def.Include = append(def.Include, strings.Trim(col, "`[]\""))
+		}
+	}
+	if where := strings.Index(upper, " WHERE "); where > pos {
+		def.Where = strings.TrimSpace(sqlStr[where+len(" WHERE "):])
+	}
+	parseFulltextDef(def)
+}
+
+// indexColumns returns the columns of the definition which core.Index has,
+// the expressions are skipped
+func indexColumns(cols []string) []string {
+	columns := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if name, _, isColumn := splitIndexCol(col); isColumn {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,104 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+)
+
+type IdxUser struct {
+	Id      int64
+	Email   string `xorm:"unique(email, expr(lower(email)))"`
+	Name    string `xorm:"index(name_created)"`
+	Created int64  `xorm:"index(name_created, desc)"`
+	Deleted int64  `xorm:"index(alive, where(deleted = 0))"`
+}
+
+func (IdxUser) Indexes() []*Index {
+	return []*Index{{Name: "NAME_TRIM", Cols: []string{"trim(name)"}}}
+}
+
+type IdxHash struct {
+	Id    int64
+	Name  string `xorm:"index(name_hash, using(hash), include(email), concurrent)"`
+	Email string
+}
+
+func TestIndexSQL(t *testing.T) {
+	engine := testEngine(t, "postgres")
+	table := engine.TableInfo(new(IdxUser))
+	for name, expected := range map[string]string{
+		"EMAIL":        `CREATE UNIQUE INDEX "UQE_idx_user_EMAIL" ON "idx_user" ((lower(email)))`,
+		"NAME_CREATED": `CREATE INDEX "IDX_idx_user_NAME_CREATED" ON "idx_user" ("name","created" DESC)`,
+		"ALIVE":        `CREATE INDEX "IDX_idx_user_ALIVE" ON "idx_user" ("deleted") WHERE deleted = 0`,
+		"NAME_TRIM":    `CREATE INDEX "IDX_idx_user_NAME_TRIM" ON "idx_user" ((trim(name)))`,
+	} {
+		index, ok := table.Indexes[name]
+		if !ok {
+			t.Errorf("no index %v", name)
+			continue
+		}
+		assertSQL(t, expected, engine.indexSQL(table.Name, table, index))
+	}
+
+	table = engine.TableInfo(new(IdxHash))
+	assertSQL(t, `CREATE INDEX CONCURRENTLY "IDX_idx_hash_NAME_HASH" ON "idx_hash" USING hash ("name") INCLUDE ("email")`,
+		engine.indexSQL(table.Name, table, table.Indexes["NAME_HASH"]))
+
+	engine = testEngine(t, "mysql")
+	table = engine.TableInfo(new(IdxHash))
+	assertSQL(t, "CREATE INDEX `IDX_idx_hash_NAME_HASH` ON `idx_hash` (`name`) USING HASH",
+		engine.indexSQL(table.Name, table, table.Indexes["NAME_HASH"]))
+}
+
+func TestSyncIndexOptions(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+
+	assertNoErr(t, engine.Sync2(new(IdxUser)))
+	// the options read from database are the same, nothing is created again
+	log.reset()
+	assertNoErr(t, engine.Sync2(new(IdxUser)))
+	for _, sqlStr := range log.sqls {
+		if strings.Contains(sqlStr, "CREATE") || strings.Contains(sqlStr, "DROP") {
+			t.Errorf("unexpected sql %v", sqlStr)
+		}
+	}
+
+	tables, err := engine.DBMetas()
+	assertNoErr(t, err)
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %v", len(tables))
+	}
+	indexes := engine.Indexes(tables[0])
+	if alive, ok := indexes["ALIVE"]; !ok || alive.Where != "deleted = 0" {
+		t.Errorf("expected partial index ALIVE, got %v", alive)
+	}
+	if trim, ok := indexes["NAME_TRIM"]; !ok || len(trim.Cols) != 1 || trim.Cols[0] != "trim(name)" {
+		t.Errorf("expected expression index NAME_TRIM, got %v", trim)
+	}
+	if nameCreated, ok := indexes["NAME_CREATED"]; !ok || len(nameCreated.Cols) != 2 ||
+		!strings.EqualFold(nameCreated.Cols[1], "created DESC") {
+		t.Errorf("expected descending index NAME_CREATED, got %v", nameCreated)
+	}
+
+	// the partial index is changed
+	_, err = engine.Exec("DROP INDEX `IDX_idx_user_ALIVE`")
+	assertNoErr(t, err)
+	_, err = engine.Exec("CREATE INDEX `IDX_idx_user_ALIVE` ON `idx_user` (`deleted`)")
+	assertNoErr(t, err)
+	log.reset()
+	assertNoErr(t, engine.Sync2(new(IdxUser)))
+	var created bool
+	for _, sqlStr := range log.sqls {
+		if strings.HasPrefix(sqlStr, "CREATE INDEX `IDX_idx_user_ALIVE`") {
+			created = strings.Contains(sqlStr, "WHERE deleted = 0")
+		}
+	}
+	if !created {
+		t.Errorf("expected index ALIVE recreated, got %v", log.sqls)
+	}
+}
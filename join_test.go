@@ -0,0 +1,142 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type JoinUser struct {
+	Id      int64
+	Name    string
+	GroupId int64
+}
+
+type JoinGroup struct {
+	Id     int64
+	Active bool
+}
+
+type JoinUserCopy struct {
+	Id   int64
+	Name string
+}
+
+func TestJoinUpdateDeleteSQL(t *testing.T) {
+	updates := map[core.DbType]string{
+		core.MYSQL:    "UPDATE `join_user` INNER JOIN `join_group` ON join_group.id = join_user.group_id SET `name` = ? WHERE join_group.active = ?",
+		core.POSTGRES: `UPDATE "join_user" SET "name" = ? FROM "join_group" WHERE (join_group.id = join_user.group_id) AND (join_group.active = ?)`,
+		core.MSSQL:    `UPDATE "join_user" SET "name" = ? FROM "join_user" INNER JOIN "join_group" ON join_group.id = join_user.group_id WHERE join_group.active = ?`,
+		core.SQLITE:   "UPDATE `join_user` SET `name` = ? WHERE `id` IN (SELECT `join_user`.`id` FROM `join_user` INNER JOIN `join_group` ON join_group.id = join_user.group_id WHERE join_group.active = ?)",
+	}
+	deletes := map[core.DbType]string{
+		core.MYSQL:    "DELETE `join_user` FROM `join_user` INNER JOIN `join_group` ON join_group.id = join_user.group_id WHERE join_group.active = ?",
+		core.POSTGRES: `DELETE FROM "join_user" USING "join_group" WHERE (join_group.id = join_user.group_id) AND (join_group.active = ?)`,
+		core.MSSQL:    `DELETE "join_user" FROM "join_user" INNER JOIN "join_group" ON join_group.id = join_user.group_id WHERE join_group.active = ?`,
+		core.SQLITE:   "DELETE FROM `join_user` WHERE `id` IN (SELECT `join_user`.`id` FROM `join_user` INNER JOIN `join_group` ON join_group.id = join_user.group_id WHERE join_group.active = ?)",
+	}
+	for dbType, expected := range updates {
+		engine := testEngine(t, dbType)
+		session := engine.NewSession()
+		session.Table(&JoinUser{}).Join("INNER", "join_group", "join_group.id = join_user.group_id")
+		sqlStr, err := session.Statement.genJoinUpdateSql(engine.Quote("name")+" = ?", "join_group.active = ?")
+		assertNoErr(t, err)
+		assertSQL(t, expected, sqlStr)
+
+		sqlStr, err = session.Statement.genJoinDeleteSql("join_group.active = ?")
+		assertNoErr(t, err)
+		assertSQL(t, deletes[dbType], sqlStr)
+		session.Close()
+	}
+}
+
+func TestJoinFirstLeftJoinOnPostgres(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	session := engine.NewSession()
+	defer session.Close()
+	session.Table(&JoinUser{}).Join("LEFT", "join_group", "join_group.id = join_user.group_id")
+	if _, err := session.Statement.genJoinDeleteSql(""); err == nil {
+		t.Error("a first LEFT JOIN of DELETE should be an error on postgres")
+	}
+}
+
+func TestJoinUpdateDelete(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(JoinUser), new(JoinGroup)))
+
+	_, err := engine.Insert(&JoinGroup{Id: 1, Active: true}, &JoinGroup{Id: 2})
+	assertNoErr(t, err)
+	_, err = engine.Insert(&JoinUser{Name: "a", GroupId: 1}, &JoinUser{Name: "b", GroupId: 2})
+	assertNoErr(t, err)
+
+	cnt, err := engine.Join("INNER", "join_group", "join_group.id = join_user.group_id").
+		Where("join_group.active = ?", true).Update(&JoinUser{Name: "active"})
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Fatalf("updated %d records, expected 1", cnt)
+	}
+	var user JoinUser
+	has, err := engine.Where("group_id = ?", 2).Get(&user)
+	assertNoErr(t, err)
+	if !has || user.Name != "b" {
+		t.Errorf("the user of the inactive group is updated: %+v", user)
+	}
+
+	cnt, err = engine.Join("INNER", "join_group", "join_group.id = join_user.group_id").
+		Where("join_group.active = ?", false).Delete(new(JoinUser))
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Fatalf("deleted %d records, expected 1", cnt)
+	}
+	total, err := engine.Count(new(JoinUser))
+	assertNoErr(t, err)
+	if total != 1 {
+		t.Errorf("%d users are left, expected 1", total)
+	}
+}
+
+func TestInsertSelect(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(JoinUser), new(JoinUserCopy)))
+	_, err := engine.Insert(&JoinUser{Name: "a", GroupId: 1}, &JoinUser{Name: "b", GroupId: 2})
+	assertNoErr(t, err)
+
+	log.reset()
+	cnt, err := engine.Where("group_id = ?", 2).InsertSelect(new(JoinUserCopy), []string{"id", "name"}, new(JoinUser))
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Fatalf("inserted %d records, expected 1", cnt)
+	}
+	sqls := log.sqls
+	if len(sqls) == 0 {
+		t.Fatal("no sql is logged")
+	}
+	assertSQL(t, "INSERT INTO `join_user_copy` (`id`, `name`) SELECT `id`, `name` FROM `join_user` WHERE group_id = ? [args] [2]", sqls[0])
+
+	// the name is qualified by the schema
+	engine.SetSchema("main")
+	engine.MapCacher(new(JoinUserCopy), NewLRUCacher(NewMemoryStore(), 100))
+	var copies []JoinUserCopy
+	assertNoErr(t, engine.Find(&copies))
+	if len(copies) != 1 || copies[0].Name != "b" {
+		t.Errorf("copied %+v", copies)
+	}
+
+	// the cached ids of the target named by a string are cleared too
+	log.reset()
+	_, err = engine.Where("group_id = ?", 1).InsertSelect("join_user_copy", []string{"id", "name"}, new(JoinUser))
+	assertNoErr(t, err)
+	assertSQL(t, "INSERT INTO `main`.`join_user_copy` (`id`, `name`) SELECT `id`, `name` FROM `main`.`join_user` WHERE group_id = ? [args] [1]",
+		log.sqls[0])
+	copies = nil
+	assertNoErr(t, engine.Find(&copies))
+	if len(copies) != 2 {
+		t.Errorf("the cached ids are kept, found %+v", copies)
+	}
+}
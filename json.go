@@ -0,0 +1,266 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// jsonPathPart is a key or an array index of a JSON path
+type jsonPathPart struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses a path like $.a.b[0] or a.b[0], $ is optional
+func parseJSONPath(path string) ([]jsonPathPart, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	parts := make([]jsonPathPart, 0)
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '.':
+			i++
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %v", path)
+			}
+			index, err := strconv.Atoi(strings.TrimSpace(path[i+1 : i+end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON path %v", path)
+			}
+			parts = append(parts, jsonPathPart{index: index, isIndex: true})
+			i += end + 1
+		case c == '"':
+			end := strings.IndexByte(path[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %v", path)
+			}
+			parts = append(parts, jsonPathPart{key: path[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			parts = append(parts, jsonPathPart{key: path[i : i+end]})
+			i += end
+		}
+	}
+	return parts, nil
+}
+
+// formatJSONPath returns the standard path like $.a."b c"[0]
+func formatJSONPath(parts []jsonPathPart) string {
+	path := "$"
+	for _, part := range parts {
+		switch {
+		case part.isIndex:
+			path += "[" + strconv.Itoa(part.index) + "]"
+		case isIdent(part.key):
+			path += "." + part.key
+		default:
+			path += `."` + part.key + `"`
+		}
+	}
+	return path
+}
+
+// sqlString returns the string literal of s
+func sqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// jsonExtract returns the expression of the value at the path of col, the
+// path is parsed already
+func (engine *Engine) jsonExtract(col string, parts []jsonPathPart) string {
+	quoted := engine.quoteName(col)
+	path := sqlString(formatJSONPath(parts))
+	switch engine.dialect.DBType() {
+	case core.POSTGRES:
+		// the cast makes it work on the text columns too, it's nothing for
+		// the jsonb ones
+		expr := "CAST(" + quoted + " AS jsonb)"
+		if len(parts) == 0 {
+			return expr + " #>> '{}'"
+		}
+		for i, part := range parts {
+			op := "->"
+			if i == len(parts)-1 {
+				op = "->>"
+			}
+			if part.isIndex {
+				expr += op + strconv.Itoa(part.index)
+			} else {
+				expr += op + sqlString(part.key)
+			}
+		}
+		return expr
+	case core.MYSQL:
+		return "JSON_UNQUOTE(JSON_EXTRACT(" + quoted + ", " + path + "))"
+	case core.MSSQL, core.ORACLE:
+		return "JSON_VALUE(" + quoted + ", " + path + ")"
+	}
+	return "json_extract(" + quoted + ", " + path + ")"
+}
+
+// JSONExtract returns the expression of the value at path of the JSON
+// column col as text, the path is like $.a.b[0]. It could be used by Where,
+// Cols, OrderBy or GroupBy. It returns an error if the path is invalid.
+func (engine *Engine) JSONExtract(col, path string) (string, error) {
+	parts, err := parseJSONPath(path)
+	if err != nil {
+		return "", err
+	}
+	return engine.jsonExtract(col, parts), nil
+}
+
+// jsonIndexRe matches json_extract(col, '$.path') of the index expressions
+var jsonIndexRe = regexp.MustCompile(`(?i)json_extract\(\s*([^,()\s]+)\s*,\s*'([^']*)'\s*\)`)
+
+// jsonIndexExpr renders json_extract(col, '$.path') of an index expression
+// for the dialect, so tag index(name, expr(json_extract(doc, '$.city')))
+// works on all of them
+func (engine *Engine) jsonIndexExpr(expr string) string {
+	return jsonIndexRe.ReplaceAllStringFunc(expr, func(s string) string {
+		m := jsonIndexRe.FindStringSubmatch(s)
+		parts, err := parseJSONPath(m[2])
+		if err != nil {
+			return s
+		}
+		extract := engine.jsonExtract(strings.Trim(m[1], "`[]\""), parts)
+		if engine.dialect.DBType() == core.MYSQL {
+			// the texts are not indexed
+			extract = "CAST(" + extract + " AS CHAR(255))"
+		}
+		return extract
+	})
+}
+
+// jsonScalar returns the argument compared with an extracted value
+func (engine *Engine) jsonScalar(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		return x
+	case bool:
+		if engine.dialect.DBType() == core.SQLITE {
+			if x {
+				return 1
+			}
+			return 0
+		}
+		return strconv.FormatBool(x)
+	}
+	if engine.dialect.DBType() == core.SQLITE {
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonContainsConds returns the conditions that the value at the path of
+// col contains value, it's used by the dialects without a containment
+// operator. The objects are compared key by key, the arrays are compared
+// element by element.
+func (engine *Engine) jsonContainsConds(col string, parts []jsonPathPart, value interface{}) ([]string, []interface{}) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub := append(append([]jsonPathPart{}, parts...), jsonPathPart{key: key})
+			subConds, subArgs := engine.jsonContainsConds(col, sub, v[key])
+			conds = append(conds, subConds...)
+			args = append(args, subArgs...)
+		}
+	case []interface{}:
+		path := sqlString(formatJSONPath(parts))
+		quoted := engine.quoteName(col)
+		for _, elem := range v {
+			switch elem.(type) {
+			case map[string]interface{}, []interface{}:
+				engine.LogWarnf("the nested %v in an array is not compared by JSONContains", elem)
+				continue
+			}
+			switch engine.dialect.DBType() {
+			case core.MSSQL:
+				conds = append(conds, "EXISTS (SELECT 1 FROM OPENJSON("+quoted+", "+path+") WHERE value = ?)")
+			case core.ORACLE:
+				conds = append(conds, "JSON_EXISTS("+quoted+", "+sqlString(formatJSONPath(parts)+"[*]?(@ == $v)")+` PASSING ? AS "v")`)
+			default:
+				conds = append(conds, "EXISTS (SELECT 1 FROM json_each("+quoted+", "+path+") WHERE json_each.value = ?)")
+			}
+			args = append(args, engine.jsonScalar(elem))
+		}
+	case nil:
+		conds = append(conds, engine.jsonExtract(col, parts)+" IS NULL")
+	default:
+		conds = append(conds, engine.jsonExtract(col, parts)+" = ?")
+		args = append(args, engine.jsonScalar(v))
+	}
+	return conds, args
+}
+
+// JSONContains adds the condition that the JSON column col contains value,
+// like the operator @> of postgres. The value is encoded to JSON, so a
+// string is a JSON string, the JSON documents are passed as
+// json.RawMessage. The error of encoding it is returned by the operation.
+func (statement *Statement) JSONContains(col string, value interface{}) *Statement {
+	engine := statement.Engine
+	data, ok := value.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			statement.lastError = err
+			return statement
+		}
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		statement.lastError = fmt.Errorf("invalid JSON of JSONContains: %v", err)
+		return statement
+	}
+	doc := string(data)
+
+	quoted := engine.quoteName(col)
+	switch engine.dialect.DBType() {
+	case core.POSTGRES:
+		return statement.And("CAST("+quoted+" AS jsonb) @> CAST(? AS jsonb)", doc)
+	case core.MYSQL:
+		return statement.And("JSON_CONTAINS("+quoted+", ?)", doc)
+	}
+
+	conds, args := engine.jsonContainsConds(col, nil, decoded)
+	if len(conds) == 0 {
+		return statement
+	}
+	return statement.And(strings.Join(conds, " "+engine.dialect.AndStr()+" "), args...)
+}
+
+// JSONContains adds the condition that the JSON column col contains value
+func (session *Session) JSONContains(col string, value interface{}) *Session {
+	session.Statement.JSONContains(col, value)
+	return session
+}
+
+// JSONContains adds the condition that the JSON column col contains value
+func (engine *Engine) JSONContains(col string, value interface{}) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.JSONContains(col, value)
+}
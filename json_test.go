@@ -0,0 +1,81 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type JsonDoc struct {
+	Id  int64
+	Doc map[string]interface{}
+}
+
+func TestJSONExtract(t *testing.T) {
+	for dbType, expected := range map[core.DbType]string{
+		core.POSTGRES: `CAST("doc" AS jsonb)->'a'->0->>'b c'`,
+		core.MYSQL:    "JSON_UNQUOTE(JSON_EXTRACT(`doc`, '$.a[0].\"b c\"'))",
+		core.SQLITE:   "json_extract(`doc`, '$.a[0].\"b c\"')",
+		core.MSSQL:    `JSON_VALUE("doc", '$.a[0]."b c"')`,
+	} {
+		engine := testEngine(t, dbType)
+		expr, err := engine.JSONExtract("doc", `$.a[0]."b c"`)
+		assertNoErr(t, err)
+		assertSQL(t, expected, expr)
+		if _, err = engine.JSONExtract("doc", "$.a[x]"); err == nil {
+			t.Errorf("expected the error of an invalid path on %v", dbType)
+		}
+	}
+}
+
+func TestJSONContains(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(JsonDoc)))
+	for _, doc := range []map[string]interface{}{
+		{"city": "Paris", "tags": []interface{}{"a", "b"}},
+		{"city": "Lyon", "tags": []interface{}{"b"}},
+	} {
+		_, err := engine.Insert(&JsonDoc{Doc: doc})
+		assertNoErr(t, err)
+	}
+
+	city, err := engine.JSONExtract("doc", "$.city")
+	assertNoErr(t, err)
+	var docs []JsonDoc
+	assertNoErr(t, engine.Where(city+" = ?", "Lyon").Find(&docs))
+	if len(docs) != 1 || docs[0].Id != 2 {
+		t.Errorf("expected the doc in Lyon, got %v", docs)
+	}
+
+	docs = nil
+	assertNoErr(t, engine.JSONContains("doc", map[string]interface{}{"tags": []string{"a"}}).Find(&docs))
+	if len(docs) != 1 || docs[0].Id != 1 {
+		t.Errorf("expected the doc tagged a, got %v", docs)
+	}
+	docs = nil
+	assertNoErr(t, engine.JSONContains("doc", json.RawMessage(`{"city": "Lyon"}`)).Find(&docs))
+	if len(docs) != 1 || docs[0].Id != 2 {
+		t.Errorf("expected the doc in Lyon, got %v", docs)
+	}
+
+	// the invalid values are errors instead of dropping the condition
+	docs = nil
+	if err = engine.JSONContains("doc", math.Inf(1)).Find(&docs); err == nil || len(docs) != 0 {
+		t.Errorf("expected the error of encoding, got %v %v", err, docs)
+	}
+	if _, err = engine.JSONContains("doc", json.RawMessage(`{"city"`)).Delete(new(JsonDoc)); err == nil {
+		t.Error("expected the error of the invalid JSON")
+	}
+	count, err := engine.Count(new(JsonDoc))
+	assertNoErr(t, err)
+	if count != 2 {
+		t.Errorf("expected nothing deleted, got %v docs", count)
+	}
+}
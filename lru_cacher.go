@@ -7,6 +7,7 @@ package xorm
 import (
 	"container/list"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -67,7 +68,7 @@ func (m *LRUCacher) GC() {
 			next := e.Next()
 			//fmt.Println("removing ...", e.Value)
 			node := e.Value.(*idNode)
-			m.delBean(node.tbName, node.id)
+			m.delSegmentBean(node.tbName, node.id)
 			e = next
 		} else {
 			//fmt.Printf("removing %d cache nodes ..., left %d\n", removedNum, m.idList.Len())
@@ -134,7 +135,7 @@ func (m *LRUCacher) GetBean(tableName string, id string) interface{} {
 			lastTime := el.Value.(*idNode).lastVisit
 			// if expired, remove the node and return nil
 			if time.Now().Sub(lastTime) > m.Expired {
-				m.delBean(tableName, id)
+				m.delSegmentBean(tableName, id)
 				//m.clearIds(tableName)
 				return nil
 			}
@@ -147,14 +148,47 @@ func (m *LRUCacher) GetBean(tableName string, id string) interface{} {
 		return v
 	} else {
 		// store bean is not exist, then remove memory's index
-		m.delBean(tableName, id)
+		m.delSegmentBean(tableName, id)
 		//m.clearIds(tableName)
 		return nil
 	}
 }
 
+// tenantSep separates the table name and the tenant id, the records of a
+// tenant are cached in the table's segment "table@tenant".
+const tenantSep = "@"
+
+// relatedSegments returns the segments which should be cleared with
+// tableName. Clearing a table clears all its tenants' segments, clearing a
+// tenant's segment clears the table's since it has the tenant's records too.
+func (m *LRUCacher) relatedSegments(tableName string) []string {
+	if idx := strings.Index(tableName, tenantSep); idx >= 0 {
+		return []string{tableName, tableName[:idx]}
+	}
+
+	names := []string{tableName}
+	prefix := tableName + tenantSep
+	for name := range m.sqlIndex {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	for name := range m.idIndex {
+		if _, ok := m.sqlIndex[name]; !ok && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // Clear all sql-ids mapping on table tableName from cache
 func (m *LRUCacher) clearIds(tableName string) {
+	for _, name := range m.relatedSegments(tableName) {
+		m.clearSegmentIds(name)
+	}
+}
+
+func (m *LRUCacher) clearSegmentIds(tableName string) {
 	if tis, ok := m.sqlIndex[tableName]; ok {
 		for sql, v := range tis {
 			m.sqlList.Remove(v)
@@ -171,6 +205,12 @@ func (m *LRUCacher) ClearIds(tableName string) {
 }
 
 func (m *LRUCacher) clearBeans(tableName string) {
+	for _, name := range m.relatedSegments(tableName) {
+		m.clearSegmentBeans(name)
+	}
+}
+
+func (m *LRUCacher) clearSegmentBeans(tableName string) {
 	if tis, ok := m.idIndex[tableName]; ok {
 		for id, v := range tis {
 			m.idList.Remove(v)
@@ -224,7 +264,7 @@ func (m *LRUCacher) PutBean(tableName string, id string, obj interface{}) {
 	if m.idList.Len() > m.MaxElementSize {
 		e := m.idList.Front()
 		node := e.Value.(*idNode)
-		m.delBean(node.tbName, node.id)
+		m.delSegmentBean(node.tbName, node.id)
 	}
 }
 
@@ -245,6 +285,12 @@ func (m *LRUCacher) DelIds(tableName, sql string) {
 }
 
 func (m *LRUCacher) delBean(tableName string, id string) {
+	for _, name := range m.relatedSegments(tableName) {
+		m.delSegmentBean(name, id)
+	}
+}
+
+func (m *LRUCacher) delSegmentBean(tableName string, id string) {
 	tid := genId(tableName, id)
 	if el, ok := m.idIndex[tableName][id]; ok {
 		delete(m.idIndex[tableName], id)
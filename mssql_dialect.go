@@ -209,12 +209,33 @@ var (
 
 type mssql struct {
 	core.Base
+	schema string
 }
 
 func (db *mssql) Init(d *core.DB, uri *core.Uri, drivername, dataSourceName string) error {
 	return db.Base.Init(d, db, uri, drivername, dataSourceName)
 }
 
+func (db *mssql) SetSchema(schema string) {
+	db.schema = schema
+}
+
+// qualify qualifies tableName with the schema for OBJECT_ID, the default
+// schema of the user is used if it's not set
+func (db *mssql) qualify(tableName string) string {
+	if db.schema == "" || strings.Contains(tableName, ".") {
+		return tableName
+	}
+	return db.schema + "." + tableName
+}
+
+// xTableName returns the table name in the names of the indexes created by
+// xorm, the indexes are in the schema of their table so it's unqualified
+func (db *mssql) xTableName(tableName string) string {
+	_, tableName = splitSchema(tableName, "")
+	return tableName
+}
+
 func (db *mssql) SqlType(c *core.Column) string {
 	var res string
 	switch t := c.SQLType.Name; t {
@@ -242,10 +263,19 @@ func (db *mssql) SqlType(c *core.Column) string {
 		c.Length = 7
 	case core.MediumInt:
 		res = core.Int
-	case core.MediumText, core.TinyText, core.LongText, core.Json:
+	case core.MediumText, core.TinyText, core.LongText:
 		res = core.Text
+	case core.Json:
+		// mssql keeps JSON in nvarchar
+		return "NVARCHAR(MAX)"
 	case core.Double:
 		res = core.Real
+	case core.Uuid:
+		res = core.Varchar
+		c.Length = 36
+	case core.Enum, core.Set:
+		res = core.Varchar
+		c.Length = optionsLength(c)
 	default:
 		res = t
 	}
@@ -274,7 +304,7 @@ func (db *mssql) IsReserved(name string) bool {
 }
 
 func (db *mssql) Quote(name string) string {
-	return "\"" + name + "\""
+	return quoteParts(name, "\"", "\"")
 }
 
 func (db *mssql) QuoteStr() string {
@@ -290,9 +320,10 @@ func (db *mssql) AutoIncrStr() string {
 }
 
 func (db *mssql) DropTableSql(tableName string) string {
+	tableName = db.qualify(tableName)
 	return fmt.Sprintf("IF EXISTS (SELECT * FROM sysobjects WHERE id = "+
 		"object_id(N'%s') and OBJECTPROPERTY(id, N'IsUserTable') = 1) "+
-		"DROP TABLE \"%s\"", tableName, tableName)
+		"DROP TABLE %s", tableName, db.Quote(tableName))
 }
 
 func (db *mssql) SupportCharset() bool {
@@ -305,7 +336,7 @@ func (db *mssql) IndexOnTable() bool {
 
 func (db *mssql) IndexCheckSql(tableName, idxName string) (string, []interface{}) {
 	args := []interface{}{idxName}
-	sql := "select name from sysindexes where id=object_id('" + tableName + "') and name=?"
+	sql := "select name from sysindexes where id=object_id('" + db.qualify(tableName) + "') and name=?"
 	return sql, args
 }
 
@@ -317,13 +348,17 @@ func (db *mssql) IndexCheckSql(tableName, idxName string) (string, []interface{}
 
 func (db *mssql) IsColumnExist(tableName, colName string) (bool, error) {
 	query := `SELECT "COLUMN_NAME" FROM "INFORMATION_SCHEMA"."COLUMNS" WHERE "TABLE_NAME" = ? AND "COLUMN_NAME" = ?`
+	if schema, name := splitSchema(tableName, db.schema); schema != "" {
+		query += ` AND "TABLE_SCHEMA" = ?`
+		return db.HasRecords(query, name, colName, schema)
+	}
 
 	return db.HasRecords(query, tableName, colName)
 }
 
 func (db *mssql) TableCheckSql(tableName string) (string, []interface{}) {
 	args := []interface{}{}
-	sql := "select * from sysobjects where id = object_id(N'" + tableName + "') and OBJECTPROPERTY(id, N'IsUserTable') = 1"
+	sql := "select * from sysobjects where id = object_id(N'" + db.qualify(tableName) + "') and OBJECTPROPERTY(id, N'IsUserTable') = 1"
 	return sql, args
 }
 
@@ -331,7 +366,7 @@ func (db *mssql) GetColumns(tableName string) ([]string, map[string]*core.Column
 	args := []interface{}{}
 	s := `select a.name as name, b.name as ctype,a.max_length,a.precision,a.scale
 from sys.columns a left join sys.types b on a.user_type_id=b.user_type_id
-where a.object_id=object_id('` + tableName + `')`
+where a.object_id=object_id('` + db.qualify(tableName) + `')`
 
 	rows, err := db.DB().Query(s, args...)
 	if err != nil {
@@ -389,6 +424,10 @@ where a.object_id=object_id('` + tableName + `')`
 func (db *mssql) GetTables() ([]*core.Table, error) {
 	args := []interface{}{}
 	s := `select name from sysobjects where xtype ='U'`
+	if db.schema != "" {
+		args = append(args, db.schema)
+		s = `select name from sys.tables where schema_id = SCHEMA_ID(?)`
+	}
 
 	rows, err := db.DB().Query(s, args...)
 	if err != nil {
@@ -411,7 +450,8 @@ func (db *mssql) GetTables() ([]*core.Table, error) {
 }
 
 func (db *mssql) GetIndexes(tableName string) (map[string]*core.Index, error) {
-	args := []interface{}{tableName}
+	xTableName := db.xTableName(tableName)
+	args := []interface{}{db.qualify(tableName)}
 	s := `SELECT
 IXS.NAME                    AS  [INDEX_NAME],
 C.NAME                      AS  [COLUMN_NAME],
@@ -421,7 +461,8 @@ INNER JOIN SYS.INDEX_COLUMNS   IXCS
 ON IXS.OBJECT_ID=IXCS.OBJECT_ID  AND IXS.INDEX_ID = IXCS.INDEX_ID
 INNER   JOIN SYS.COLUMNS C  ON IXS.OBJECT_ID=C.OBJECT_ID
 AND IXCS.COLUMN_ID=C.COLUMN_ID
-WHERE IXS.TYPE_DESC='NONCLUSTERED' and OBJECT_NAME(IXS.OBJECT_ID) =?
+WHERE IXS.TYPE_DESC='NONCLUSTERED' and IXS.OBJECT_ID = OBJECT_ID(?)
+and IXCS.is_included_column = 0
 `
 
 	rows, err := db.DB().Query(s, args...)
@@ -453,8 +494,8 @@ WHERE IXS.TYPE_DESC='NONCLUSTERED' and OBJECT_NAME(IXS.OBJECT_ID) =?
 
 		colName = strings.Trim(colName, "` ")
 
-		if strings.HasPrefix(indexName, "IDX_"+tableName) || strings.HasPrefix(indexName, "UQE_"+tableName) {
-			indexName = indexName[5+len(tableName) : len(indexName)]
+		if strings.HasPrefix(indexName, "IDX_"+xTableName) || strings.HasPrefix(indexName, "UQE_"+xTableName) {
+			indexName = indexName[5+len(xTableName) : len(indexName)]
 		}
 
 		var index *core.Index
@@ -470,38 +511,137 @@ WHERE IXS.TYPE_DESC='NONCLUSTERED' and OBJECT_NAME(IXS.OBJECT_ID) =?
 	return indexes, nil
 }
 
-func (db *mssql) CreateTableSql(table *core.Table, tableName, storeEngine, charset string) string {
-	var sql string
-	if tableName == "" {
-		tableName = table.Name
+func (db *mssql) GetIndexOptions(tableName string) (map[string]*Index, error) {
+	xTableName := db.xTableName(tableName)
+	args := []interface{}{db.qualify(tableName)}
+	s := `SELECT
+IXS.NAME                                AS  [INDEX_NAME],
+C.NAME                                  AS  [COLUMN_NAME],
+CAST(IXS.is_unique AS INT)              AS  [IS_UNIQUE],
+CAST(IXCS.is_descending_key AS INT)     AS  [IS_DESC],
+CAST(IXCS.is_included_column AS INT)    AS  [IS_INCLUDED],
+ISNULL(IXS.filter_definition, '')       AS  [FILTER]
+FROM SYS.INDEXES IXS
+INNER JOIN SYS.INDEX_COLUMNS   IXCS
+ON IXS.OBJECT_ID=IXCS.OBJECT_ID  AND IXS.INDEX_ID = IXCS.INDEX_ID
+INNER   JOIN SYS.COLUMNS C  ON IXS.OBJECT_ID=C.OBJECT_ID
+AND IXCS.COLUMN_ID=C.COLUMN_ID
+WHERE IXS.TYPE_DESC='NONCLUSTERED' and IXS.OBJECT_ID = OBJECT_ID(?)
+ORDER BY IXS.NAME, IXCS.KEY_ORDINAL
+`
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
 	}
 
-	sql = "IF NOT EXISTS (SELECT [name] FROM sys.tables WHERE [name] = '" + tableName + "' ) CREATE TABLE "
+	indexes := make(map[string]*Index)
+	for _, result := range results {
+		indexName := result[0]
+		if strings.HasPrefix(indexName, "IDX_"+xTableName) || strings.HasPrefix(indexName, "UQE_"+xTableName) {
+			indexName = indexName[5+len(xTableName) : len(indexName)]
+		}
+		index, ok := indexes[indexName]
+		if !ok {
+			index = &Index{Name: indexName, Unique: result[2] == "1", Where: result[5]}
+			indexes[indexName] = index
+		}
+		col := strings.Trim(result[1], "` ")
+		if result[4] == "1" {
+			index.Include = append(index.Include, col)
+			continue
+		}
+		if result[3] == "1" {
+			col += " DESC"
+		}
+		index.Cols = append(index.Cols, col)
+	}
+	return indexes, nil
+}
+
+func (db *mssql) getForeignKeys(tableName string) ([]*ForeignKey, error) {
+	schema, _ := splitSchema(db.qualify(tableName), "dbo")
+	args := []interface{}{db.qualify(tableName)}
+	s := `SELECT
+FK.NAME                                 AS  [FK_NAME],
+C.NAME                                  AS  [COLUMN_NAME],
+SCHEMA_NAME(RT.SCHEMA_ID)               AS  [REF_SCHEMA],
+RT.NAME                                 AS  [REF_TABLE],
+RC.NAME                                 AS  [REF_COLUMN],
+FK.DELETE_REFERENTIAL_ACTION_DESC       AS  [ON_DELETE],
+FK.UPDATE_REFERENTIAL_ACTION_DESC       AS  [ON_UPDATE]
+FROM SYS.FOREIGN_KEYS FK
+INNER JOIN SYS.FOREIGN_KEY_COLUMNS FKC ON FK.OBJECT_ID = FKC.CONSTRAINT_OBJECT_ID
+INNER JOIN SYS.COLUMNS C ON FKC.PARENT_OBJECT_ID = C.OBJECT_ID AND FKC.PARENT_COLUMN_ID = C.COLUMN_ID
+INNER JOIN SYS.TABLES RT ON FKC.REFERENCED_OBJECT_ID = RT.OBJECT_ID
+INNER JOIN SYS.COLUMNS RC ON FKC.REFERENCED_OBJECT_ID = RC.OBJECT_ID AND FKC.REFERENCED_COLUMN_ID = RC.COLUMN_ID
+WHERE FK.PARENT_OBJECT_ID = OBJECT_ID(?)
+ORDER BY FK.NAME, FKC.CONSTRAINT_COLUMN_ID
+`
 
-	sql += db.QuoteStr() + tableName + db.QuoteStr() + " ("
+	rows, err := db.DB().Query(s, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	pkList := table.PrimaryKeys
+	var fks []*ForeignKey
+	for rows.Next() {
+		var name, colName, refSchema, refTable, refCol, onDelete, onUpdate string
+		err = rows.Scan(&name, &colName, &refSchema, &refTable, &refCol, &onDelete, &onUpdate)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(refSchema, schema) {
+			refTable = refSchema + "." + refTable
+		}
+		fks = addForeignKeyColumn(fks, name, colName, refTable, refCol, onDelete, onUpdate)
+	}
+	return fks, nil
+}
 
-	for _, colName := range table.ColumnsSeq() {
-		col := table.GetColumn(colName)
-		if col.IsPrimaryKey && len(pkList) == 1 {
-			sql += col.String(db)
+func (db *mssql) GetColumnOptions(tableName string) (*tableExt, error) {
+	ext := newTableExt()
+	fks, err := db.getForeignKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ext.foreignKeys = fks
+	args := []interface{}{db.qualify(tableName)}
+	s := "SELECT ISNULL(c.name, ''), CAST(ep.value AS NVARCHAR(4000)) FROM sys.extended_properties ep " +
+		"LEFT JOIN sys.columns c ON c.object_id = ep.major_id AND c.column_id = ep.minor_id " +
+		"WHERE ep.class = 1 AND ep.name = 'MS_Description' AND ep.major_id = OBJECT_ID(?)"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result[0] == "" {
+			ext.comment = result[1]
 		} else {
-			sql += col.StringNoPk(db)
+			ext.newColumn(result[0]).comment = result[1]
 		}
-		sql = strings.TrimSpace(sql)
-		sql += ", "
 	}
 
-	if len(pkList) > 1 {
-		sql += "PRIMARY KEY ( "
-		sql += strings.Join(pkList, ",")
-		sql += " ), "
+	s = "SELECT name, definition, CAST(is_persisted AS INT) FROM sys.computed_columns WHERE object_id = OBJECT_ID(?)"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		colExt := ext.newColumn(result[0])
+		colExt.generated = result[1]
+		colExt.stored = result[2] == "1"
 	}
 
-	sql = sql[:len(sql)-2] + ")"
-	sql += ";"
-	return sql
+	s = "SELECT name, definition FROM sys.check_constraints WHERE parent_object_id = OBJECT_ID(?) ORDER BY name"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.checks = append(ext.checks, &Check{Name: result[0], Expr: result[1]})
+	}
+	return ext, nil
 }
 
 func (db *mssql) Filters() []core.Filter {
@@ -175,6 +175,21 @@ type mysql struct {
 	allowAllFiles     bool
 	allowOldPasswords bool
 	clientFoundRows   bool
+	schema            string
+}
+
+func (db *mysql) SetSchema(schema string) {
+	db.schema = schema
+}
+
+// splitName splits db.table, mysql's schemas are the databases. The
+// database is the connected one if it's not set.
+func (db *mysql) splitName(tableName string) (string, string) {
+	schema := db.schema
+	if schema == "" {
+		schema = db.DbName
+	}
+	return splitSchema(tableName, schema)
 }
 
 func (db *mysql) Init(d *core.DB, uri *core.Uri, drivername, dataSourceName string) error {
@@ -202,31 +217,15 @@ func (db *mysql) SqlType(c *core.Column) string {
 	case core.TimeStampz:
 		res = core.Char
 		c.Length = 64
-	case core.Enum: //mysql enum
-		res = core.Enum
-		res += "("
-		opts := ""
-		for v, _ := range c.EnumOptions {
-			opts += fmt.Sprintf(",'%v'", v)
-		}
-		res += strings.TrimLeft(opts, ",")
-		res += ")"
-	case core.Set: //mysql set
-		res = core.Set
-		res += "("
-		opts := ""
-		for v, _ := range c.SetOptions {
-			opts += fmt.Sprintf(",'%v'", v)
-		}
-		res += strings.TrimLeft(opts, ",")
-		res += ")"
+	case core.Enum, core.Set: //mysql enum and set, the options are in order
+		res = c.SQLType.Name + "(" + optionsSQL(c) + ")"
 	case core.NVarchar:
 		res = core.Varchar
 	case core.Uuid:
 		res = core.Varchar
 		c.Length = 40
 	case core.Json:
-		res = core.Text
+		return "JSON"
 	default:
 		res = t
 	}
@@ -257,7 +256,7 @@ func (db *mysql) IsReserved(name string) bool {
 }
 
 func (db *mysql) Quote(name string) string {
-	return "`" + name + "`"
+	return quoteParts(name, "`", "`")
 }
 
 func (db *mysql) QuoteStr() string {
@@ -281,7 +280,8 @@ func (db *mysql) IndexOnTable() bool {
 }
 
 func (db *mysql) IndexCheckSql(tableName, idxName string) (string, []interface{}) {
-	args := []interface{}{db.DbName, tableName, idxName}
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName, idxName}
 	sql := "SELECT `INDEX_NAME` FROM `INFORMATION_SCHEMA`.`STATISTICS`"
 	sql += " WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `INDEX_NAME`=?"
 	return sql, args
@@ -294,13 +294,15 @@ func (db *mysql) IndexCheckSql(tableName, idxName string) (string, []interface{}
 }*/
 
 func (db *mysql) TableCheckSql(tableName string) (string, []interface{}) {
-	args := []interface{}{db.DbName, tableName}
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
 	sql := "SELECT `TABLE_NAME` from `INFORMATION_SCHEMA`.`TABLES` WHERE `TABLE_SCHEMA`=? and `TABLE_NAME`=?"
 	return sql, args
 }
 
 func (db *mysql) GetColumns(tableName string) ([]string, map[string]*core.Column, error) {
-	args := []interface{}{db.DbName, tableName}
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
 	s := "SELECT `COLUMN_NAME`, `IS_NULLABLE`, `COLUMN_DEFAULT`, `COLUMN_TYPE`," +
 		" `COLUMN_KEY`, `EXTRA` FROM `INFORMATION_SCHEMA`.`COLUMNS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?"
 
@@ -412,7 +414,8 @@ func (db *mysql) GetColumns(tableName string) ([]string, map[string]*core.Column
 }
 
 func (db *mysql) GetTables() ([]*core.Table, error) {
-	args := []interface{}{db.DbName}
+	schema, _ := db.splitName("")
+	args := []interface{}{schema}
 	s := "SELECT `TABLE_NAME`, `ENGINE`, `TABLE_ROWS`, `AUTO_INCREMENT` from " +
 		"`INFORMATION_SCHEMA`.`TABLES` WHERE `TABLE_SCHEMA`=? AND (`ENGINE`='MyISAM' OR `ENGINE` = 'InnoDB')"
 
@@ -443,8 +446,9 @@ func (db *mysql) GetTables() ([]*core.Table, error) {
 }
 
 func (db *mysql) GetIndexes(tableName string) (map[string]*core.Index, error) {
-	args := []interface{}{db.DbName, tableName}
-	s := "SELECT `INDEX_NAME`, `NON_UNIQUE`, `COLUMN_NAME` FROM `INFORMATION_SCHEMA`.`STATISTICS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?"
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT `INDEX_NAME`, `NON_UNIQUE`, IFNULL(`COLUMN_NAME`, '') FROM `INFORMATION_SCHEMA`.`STATISTICS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?"
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -490,11 +494,138 @@ func (db *mysql) GetIndexes(tableName string) (map[string]*core.Index, error) {
 			index.Name = indexName
 			indexes[indexName] = index
 		}
-		index.AddColumn(colName)
+		// an expression has no column
+		if colName != "" {
+			index.AddColumn(colName)
+		}
+	}
+	return indexes, nil
+}
+
+func (db *mysql) GetIndexOptions(tableName string) (map[string]*Index, error) {
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT `INDEX_NAME`, `NON_UNIQUE`, IFNULL(`COLUMN_NAME`, ''), IFNULL(`COLLATION`, ''), `INDEX_TYPE`, " +
+		"IFNULL(`EXPRESSION`, '') FROM `INFORMATION_SCHEMA`.`STATISTICS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? " +
+		"ORDER BY `INDEX_NAME`, `SEQ_IN_INDEX`"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		// the expressions are since mysql 8.0.13
+		s = strings.Replace(s, "IFNULL(`EXPRESSION`, '')", "''", 1)
+		if results, err = queryStrings(db.DB(), db.Logger, s, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	indexes := make(map[string]*Index)
+	for _, result := range results {
+		indexName := result[0]
+		if indexName == "PRIMARY" {
+			continue
+		}
+		if strings.HasPrefix(indexName, "IDX_"+tableName) || strings.HasPrefix(indexName, "UQE_"+tableName) {
+			indexName = indexName[5+len(tableName) : len(indexName)]
+		}
+		index, ok := indexes[indexName]
+		if !ok {
+			index = &Index{Name: indexName, Unique: result[1] == "0" || result[1] == "NO", Method: result[4]}
+			indexes[indexName] = index
+		}
+		col := result[2]
+		if col == "" {
+			col = result[5]
+		}
+		if result[3] == "D" {
+			col += " DESC"
+		}
+		index.Cols = append(index.Cols, col)
 	}
 	return indexes, nil
 }
 
+func (db *mysql) getForeignKeys(tableName string) ([]*ForeignKey, error) {
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT k.`CONSTRAINT_NAME`, k.`COLUMN_NAME`, k.`REFERENCED_TABLE_NAME`, k.`REFERENCED_COLUMN_NAME`, " +
+		"r.`DELETE_RULE`, r.`UPDATE_RULE` FROM `INFORMATION_SCHEMA`.`KEY_COLUMN_USAGE` k " +
+		"JOIN `INFORMATION_SCHEMA`.`REFERENTIAL_CONSTRAINTS` r ON k.`CONSTRAINT_SCHEMA` = r.`CONSTRAINT_SCHEMA` " +
+		"AND k.`CONSTRAINT_NAME` = r.`CONSTRAINT_NAME` WHERE k.`TABLE_SCHEMA` = ? AND k.`TABLE_NAME` = ? " +
+		"AND k.`REFERENCED_TABLE_NAME` IS NOT NULL ORDER BY k.`CONSTRAINT_NAME`, k.`ORDINAL_POSITION`"
+
+	rows, err := db.DB().Query(s, args...)
+	if db.Logger != nil {
+		db.Logger.Info("[sql]", s, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []*ForeignKey
+	for rows.Next() {
+		var name, colName, refTable, refCol, onDelete, onUpdate string
+		err = rows.Scan(&name, &colName, &refTable, &refCol, &onDelete, &onUpdate)
+		if err != nil {
+			return nil, err
+		}
+		fks = addForeignKeyColumn(fks, name, colName, refTable, refCol, onDelete, onUpdate)
+	}
+	return fks, nil
+}
+
+func (db *mysql) GetColumnOptions(tableName string) (*tableExt, error) {
+	ext := newTableExt()
+	fks, err := db.getForeignKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ext.foreignKeys = fks
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT `TABLE_COMMENT` FROM `INFORMATION_SCHEMA`.`TABLES` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		ext.comment = results[0][0]
+	}
+
+	s = "SELECT `COLUMN_NAME`, `COLUMN_COMMENT` FROM `INFORMATION_SCHEMA`.`COLUMNS` " +
+		"WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `COLUMN_COMMENT` <> ''"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.newColumn(result[0]).comment = result[1]
+	}
+
+	// the generated columns are since mysql 5.7, the older ones have none
+	s = "SELECT `COLUMN_NAME`, `GENERATION_EXPRESSION`, `EXTRA` FROM `INFORMATION_SCHEMA`.`COLUMNS` " +
+		"WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `GENERATION_EXPRESSION` <> ''"
+	if results, err = queryStrings(db.DB(), db.Logger, s, args...); err == nil {
+		for _, result := range results {
+			colExt := ext.newColumn(result[0])
+			colExt.generated = result[1]
+			extra := strings.ToUpper(result[2])
+			colExt.stored = strings.Contains(extra, "STORED") || strings.Contains(extra, "PERSISTENT")
+		}
+	}
+
+	// the checks are since mysql 8.0.16
+	s = "SELECT t.`CONSTRAINT_NAME`, c.`CHECK_CLAUSE` FROM `INFORMATION_SCHEMA`.`TABLE_CONSTRAINTS` t " +
+		"JOIN `INFORMATION_SCHEMA`.`CHECK_CONSTRAINTS` c ON c.`CONSTRAINT_SCHEMA` = t.`CONSTRAINT_SCHEMA` " +
+		"AND c.`CONSTRAINT_NAME` = t.`CONSTRAINT_NAME` WHERE t.`TABLE_SCHEMA` = ? AND t.`TABLE_NAME` = ? " +
+		"AND t.`CONSTRAINT_TYPE` = 'CHECK' ORDER BY t.`CONSTRAINT_NAME`"
+	if results, err = queryStrings(db.DB(), db.Logger, s, args...); err == nil {
+		for _, result := range results {
+			ext.checks = append(ext.checks, &Check{Name: result[0], Expr: result[1]})
+		}
+	}
+	return ext, nil
+}
+
 func (db *mysql) Filters() []core.Filter {
 	return []core.Filter{&core.IdFilter{}}
 }
@@ -503,12 +503,28 @@ var (
 
 type oracle struct {
 	core.Base
+	schema string
 }
 
+// oracleOwner is the owner :1 of the all_ views, the current schema if it's
+// empty
+const oracleOwner = "NVL(:1, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA'))"
+
 func (db *oracle) Init(d *core.DB, uri *core.Uri, drivername, dataSourceName string) error {
 	return db.Base.Init(d, db, uri, drivername, dataSourceName)
 }
 
+func (db *oracle) SetSchema(schema string) {
+	db.schema = schema
+}
+
+// ownerArgs returns the owner and the name of the table as the args of the
+// queries on the all_ views
+func (db *oracle) ownerArgs(tableName string) []interface{} {
+	owner, name := splitSchema(tableName, db.schema)
+	return []interface{}{owner, name}
+}
+
 func (db *oracle) SqlType(c *core.Column) string {
 	var res string
 	switch t := c.SQLType.Name; t {
@@ -526,6 +542,12 @@ func (db *oracle) SqlType(c *core.Column) string {
 		res = "CLOB"
 	case core.Char, core.Varchar, core.TinyText:
 		res = "VARCHAR2"
+	case core.Uuid:
+		res = "VARCHAR2"
+		c.Length = 36
+	case core.Enum, core.Set:
+		res = "VARCHAR2"
+		c.Length = optionsLength(c)
 	default:
 		res = t
 	}
@@ -583,58 +605,15 @@ func (db *oracle) DropTableSql(tableName string) string {
 	return fmt.Sprintf("DROP TABLE `%s`", tableName)
 }
 
-func (b *oracle) CreateTableSql(table *core.Table, tableName, storeEngine, charset string) string {
-	var sql string
-	sql = "CREATE TABLE "
-	if tableName == "" {
-		tableName = table.Name
-	}
-
-	sql += b.Quote(tableName) + " ("
-
-	pkList := table.PrimaryKeys
-
-	for _, colName := range table.ColumnsSeq() {
-		col := table.GetColumn(colName)
-		/*if col.IsPrimaryKey && len(pkList) == 1 {
-			sql += col.String(b.dialect)
-		} else {*/
-		sql += col.StringNoPk(b)
-		//}
-		sql = strings.TrimSpace(sql)
-		sql += ", "
-	}
-
-	if len(pkList) > 0 {
-		sql += "PRIMARY KEY ( "
-		sql += b.Quote(strings.Join(pkList, b.Quote(",")))
-		sql += " ), "
-	}
-
-	sql = sql[:len(sql)-2] + ")"
-	if b.SupportEngine() && storeEngine != "" {
-		sql += " ENGINE=" + storeEngine
-	}
-	if b.SupportCharset() {
-		if len(charset) == 0 {
-			charset = b.URI().Charset
-		}
-		if len(charset) > 0 {
-			sql += " DEFAULT CHARSET " + charset
-		}
-	}
-	return sql
-}
-
 func (db *oracle) IndexCheckSql(tableName, idxName string) (string, []interface{}) {
-	args := []interface{}{tableName, idxName}
-	return `SELECT INDEX_NAME FROM USER_INDEXES ` +
-	`WHERE TABLE_NAME = :1 AND INDEX_NAME = :2`, args
+	args := append(db.ownerArgs(tableName), idxName)
+	return `SELECT INDEX_NAME FROM ALL_INDEXES ` +
+	`WHERE TABLE_OWNER = ` + oracleOwner + ` AND TABLE_NAME = :2 AND INDEX_NAME = :3`, args
 }
 
 func (db *oracle) TableCheckSql(tableName string) (string, []interface{}) {
-	args := []interface{}{tableName}
-	return `SELECT table_name FROM user_tables WHERE table_name = :1`, args
+	args := db.ownerArgs(tableName)
+	return `SELECT table_name FROM all_tables WHERE owner = ` + oracleOwner + ` AND table_name = :2`, args
 }
 
 func (db *oracle) MustDropTable(tableName string) error {
@@ -653,7 +632,7 @@ func (db *oracle) MustDropTable(tableName string) error {
 		return nil
 	}
 
-	sql = "Drop Table \"" + tableName + "\""
+	sql = "Drop Table " + quoteParts(tableName, "\"", "\"")
 	if db.Logger != nil {
 		db.Logger.Info("[sql]", sql)
 	}
@@ -668,9 +647,9 @@ func (db *oracle) MustDropTable(tableName string) error {
 }*/
 
 func (db *oracle) IsColumnExist(tableName, colName string) (bool, error) {
-	args := []interface{}{tableName, colName}
-	query := "SELECT column_name FROM USER_TAB_COLUMNS WHERE table_name = :1" +
-	" AND column_name = :2"
+	args := append(db.ownerArgs(tableName), colName)
+	query := "SELECT column_name FROM ALL_TAB_COLUMNS WHERE owner = " + oracleOwner + " AND table_name = :2" +
+	" AND column_name = :3"
 	rows, err := db.DB().Query(query, args...)
 	if db.Logger != nil {
 		db.Logger.Info("[sql]", query, args)
@@ -687,9 +666,9 @@ func (db *oracle) IsColumnExist(tableName, colName string) (bool, error) {
 }
 
 func (db *oracle) GetColumns(tableName string) ([]string, map[string]*core.Column, error) {
-	args := []interface{}{tableName}
+	args := db.ownerArgs(tableName)
 	s := "SELECT column_name,data_default,data_type,data_length,data_precision,data_scale," +
-	"nullable FROM USER_TAB_COLUMNS WHERE table_name = :1"
+	"nullable FROM ALL_TAB_COLUMNS WHERE owner = " + oracleOwner + " AND table_name = :2"
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -787,8 +766,8 @@ func (db *oracle) GetColumns(tableName string) ([]string, map[string]*core.Colum
 }
 
 func (db *oracle) GetTables() ([]*core.Table, error) {
-	args := []interface{}{}
-	s := "SELECT table_name FROM user_tables"
+	args := []interface{}{db.schema}
+	s := "SELECT table_name FROM all_tables WHERE owner = " + oracleOwner
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -813,9 +792,10 @@ func (db *oracle) GetTables() ([]*core.Table, error) {
 }
 
 func (db *oracle) GetIndexes(tableName string) (map[string]*core.Index, error) {
-	args := []interface{}{tableName}
-	s := "SELECT t.column_name,i.uniqueness,i.index_name FROM user_ind_columns t,user_indexes i " +
-	"WHERE t.index_name = i.index_name and t.table_name = i.table_name and t.table_name =:1"
+	args := db.ownerArgs(tableName)
+	s := "SELECT t.column_name,i.uniqueness,i.index_name FROM all_ind_columns t,all_indexes i " +
+	"WHERE t.index_owner = i.owner and t.index_name = i.index_name and t.table_name = i.table_name " +
+	"and i.table_owner = " + oracleOwner + " and t.table_name =:2"
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -852,11 +832,137 @@ func (db *oracle) GetIndexes(tableName string) (map[string]*core.Index, error) {
 			index.Name = indexName
 			indexes[indexName] = index
 		}
-		index.AddColumn(colName)
+		// the expressions and descending columns are hidden columns
+		if !strings.HasPrefix(colName, "SYS_NC") {
+			index.AddColumn(colName)
+		}
 	}
 	return indexes, nil
 }
 
+func (db *oracle) GetIndexOptions(tableName string) (map[string]*Index, error) {
+	args := db.ownerArgs(tableName)
+	s := "SELECT i.index_name, i.uniqueness, i.index_type, c.column_name, c.descend, e.column_expression " +
+		"FROM all_indexes i JOIN all_ind_columns c ON c.index_owner = i.owner AND c.index_name = i.index_name " +
+		"LEFT JOIN all_ind_expressions e ON e.index_owner = c.index_owner AND e.index_name = c.index_name " +
+		"AND e.column_position = c.column_position " +
+		"WHERE i.table_owner = " + oracleOwner + " AND i.table_name = :2 ORDER BY i.index_name, c.column_position"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]*Index)
+	for _, result := range results {
+		indexName := strings.Trim(result[0], `" `)
+		index, ok := indexes[indexName]
+		if !ok {
+			index = &Index{Name: indexName, Unique: result[1] == "UNIQUE"}
+			if strings.Contains(result[2], "BITMAP") {
+				index.Method = "BITMAP"
+			}
+			indexes[indexName] = index
+		}
+		col := result[3]
+		if expr := strings.TrimSpace(result[5]); expr != "" {
+			// a descending column is an expression of the quoted column
+			col = strings.Trim(expr, `"`)
+			if strings.ContainsAny(col, `"()`) {
+				col = expr
+			}
+		}
+		if result[4] == "DESC" {
+			col += " DESC"
+		}
+		index.Cols = append(index.Cols, col)
+	}
+	return indexes, nil
+}
+
+func (db *oracle) getForeignKeys(tableName string) ([]*ForeignKey, error) {
+	args := db.ownerArgs(tableName)
+	s := "SELECT c.constraint_name, cc.column_name, CASE WHEN r.owner = c.owner THEN r.table_name " +
+		"ELSE r.owner || '.' || r.table_name END, rc.column_name, c.delete_rule " +
+		"FROM all_constraints c, all_cons_columns cc, all_constraints r, all_cons_columns rc " +
+		"WHERE c.constraint_type = 'R' AND c.owner = " + oracleOwner + " AND c.table_name = :2 " +
+		"AND cc.owner = c.owner AND cc.constraint_name = c.constraint_name " +
+		"AND r.owner = c.r_owner AND r.constraint_name = c.r_constraint_name " +
+		"AND rc.owner = r.owner AND rc.constraint_name = r.constraint_name " +
+		"AND rc.position = cc.position ORDER BY c.constraint_name, cc.position"
+
+	rows, err := db.DB().Query(s, args...)
+	if db.Logger != nil {
+		db.Logger.Info("[sql]", s, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []*ForeignKey
+	for rows.Next() {
+		var name, colName, refTable, refCol, onDelete string
+		err = rows.Scan(&name, &colName, &refTable, &refCol, &onDelete)
+		if err != nil {
+			return nil, err
+		}
+		// oracle has no ON UPDATE
+		fks = addForeignKeyColumn(fks, name, colName, refTable, refCol, onDelete, "")
+	}
+	return fks, nil
+}
+
+func (db *oracle) GetColumnOptions(tableName string) (*tableExt, error) {
+	ext := newTableExt()
+	fks, err := db.getForeignKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ext.foreignKeys = fks
+	args := db.ownerArgs(tableName)
+	s := "SELECT comments FROM all_tab_comments WHERE owner = " + oracleOwner + " AND table_name = :2"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		ext.comment = results[0][0]
+	}
+
+	s = "SELECT column_name, comments FROM all_col_comments WHERE owner = " + oracleOwner +
+		" AND table_name = :2 AND comments IS NOT NULL"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.newColumn(result[0]).comment = result[1]
+	}
+
+	// oracle has virtual columns only
+	s = "SELECT column_name, data_default FROM all_tab_cols " +
+		"WHERE owner = " + oracleOwner + " AND table_name = :2 AND virtual_column = 'YES' AND hidden_column = 'NO'"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.newColumn(result[0]).generated = strings.TrimSpace(result[1])
+	}
+
+	// the NOT NULL constraints are checks too, but they are named by oracle
+	s = "SELECT constraint_name, search_condition FROM all_constraints " +
+		"WHERE owner = " + oracleOwner + " AND table_name = :2 AND constraint_type = 'C' AND generated = 'USER NAME' ORDER BY constraint_name"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.checks = append(ext.checks, &Check{Name: result[0], Expr: result[1]})
+	}
+	return ext, nil
+}
+
 func (db *oracle) Filters() []core.Filter {
 	return []core.Filter{&core.QuoteFilter{}, &core.SeqFilter{":", 1}, &core.IdFilter{}}
 }
@@ -0,0 +1,256 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// PKGenerator generates the primary key of a record before it's inserted.
+// The builtin generators are used by tag `pk uuid`, `pk ulid` and
+// `pk snowflake(nodeId)`, a generator registered by Engine.RegisterPKGenerator
+// is used by tag `pk gen(name)`.
+type PKGenerator interface {
+	GenPK(table *core.Table, col *core.Column) (interface{}, error)
+}
+
+// RegisterPKGenerator registers a generator which is used by tag `gen(name)`
+func (engine *Engine) RegisterPKGenerator(name string, generator PKGenerator) {
+	engine.mutex.Lock()
+	engine.pkGenerators[strings.ToLower(name)] = generator
+	engine.mutex.Unlock()
+}
+
+// pkGenerator returns the generator named by tag, the builtin generators are
+// created the first time they're used.
+func (engine *Engine) pkGenerator(name string) (PKGenerator, error) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if generator, ok := engine.pkGenerators[name]; ok {
+		return generator, nil
+	}
+
+	var generator PKGenerator
+	switch {
+	case name == "uuid":
+		generator = uuidGenerator{}
+	case name == "ulid":
+		generator = ulidGenerator{}
+	case strings.HasPrefix(name, "snowflake(") && strings.HasSuffix(name, ")"):
+		node, err := strconv.ParseInt(name[len("snowflake("):len(name)-1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		generator, err = NewSnowflakeGenerator(node)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("pk generator %v is not registered", name)
+	}
+	engine.pkGenerators[name] = generator
+	return generator, nil
+}
+
+// genPKs fills the zero columns of beans which have a generator or are
+// backed by a sequence, the values of a sequence are read by one query
+func (session *Session) genPKs(table *core.Table, beans ...interface{}) error {
+	ext := session.Engine.tableExt(table)
+	for _, col := range table.Columns() {
+		colExt := ext.column(col.Name)
+		seqName := session.Engine.sequenceName(table, col)
+		if (colExt == nil || colExt.generator == "") && seqName == "" {
+			continue
+		}
+
+		fieldValues := make([]*reflect.Value, 0, len(beans))
+		for _, bean := range beans {
+			fieldValue, err := colValueOf(col, bean)
+			if err != nil {
+				return err
+			}
+			if isZeroValue(*fieldValue) {
+				fieldValues = append(fieldValues, fieldValue)
+			}
+		}
+		if len(fieldValues) == 0 {
+			continue
+		}
+
+		if seqName != "" {
+			ids, err := session.nextSequenceValues(seqName, len(fieldValues))
+			if err != nil {
+				return err
+			}
+			for i, fieldValue := range fieldValues {
+				if err = setGeneratedValue(fieldValue, ids[i]); err != nil {
+					return fmt.Errorf("column %v: %v", col.Name, err)
+				}
+			}
+			continue
+		}
+
+		generator, err := session.Engine.pkGenerator(colExt.generator)
+		if err != nil {
+			return err
+		}
+		for _, fieldValue := range fieldValues {
+			v, err := generator.GenPK(table, col)
+			if err != nil {
+				return err
+			}
+			if err = setGeneratedValue(fieldValue, v); err != nil {
+				return fmt.Errorf("column %v: %v", col.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setGeneratedValue sets the generated value to field, numbers could be set
+// to string fields and numeric strings could be set to number fields.
+func setGeneratedValue(fieldValue *reflect.Value, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(fmt.Sprintf("%v", v))
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fieldValue.SetInt(rv.Int())
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fieldValue.SetInt(int64(rv.Uint()))
+			return nil
+		case reflect.String:
+			n, err := strconv.ParseInt(rv.String(), 10, 64)
+			if err != nil {
+				return err
+			}
+			fieldValue.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fieldValue.SetUint(uint64(rv.Int()))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fieldValue.SetUint(rv.Uint())
+			return nil
+		case reflect.String:
+			n, err := strconv.ParseUint(rv.String(), 10, 64)
+			if err != nil {
+				return err
+			}
+			fieldValue.SetUint(n)
+			return nil
+		}
+	}
+	if rv.Type().ConvertibleTo(fieldValue.Type()) {
+		fieldValue.Set(rv.Convert(fieldValue.Type()))
+		return nil
+	}
+	return fmt.Errorf("could not set %T to %v", v, fieldValue.Type())
+}
+
+type uuidGenerator struct{}
+
+// GenPK generates a random (version 4) UUID string
+func (uuidGenerator) GenPK(table *core.Table, col *core.Column) (interface{}, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type ulidGenerator struct{}
+
+// GenPK generates an ULID string, 48 bits milliseconds and 80 bits randomness
+func (ulidGenerator) GenPK(table *core.Table, col *core.Column) (interface{}, error) {
+	var b [16]byte
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	if _, err := rand.Read(b[6:]); err != nil {
+		return nil, err
+	}
+
+	// 128 bits are encoded to 26 characters, 5 bits each, from the highest
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:]), nil
+}
+
+const (
+	snowflakeEpoch    = int64(1288834974657) // milliseconds of 2010-11-04 01:42:54 UTC
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+)
+
+type snowflakeGenerator struct {
+	mutex    sync.Mutex
+	node     int64
+	lastTime int64
+	seq      int64
+}
+
+// NewSnowflakeGenerator returns a generator of 63 bits snowflake ids, node
+// should be unique among the processes inserting to the same table.
+func NewSnowflakeGenerator(node int64) (PKGenerator, error) {
+	if node < 0 || node >= 1<<snowflakeNodeBits {
+		return nil, fmt.Errorf("snowflake node should be between 0 and %d", 1<<snowflakeNodeBits-1)
+	}
+	return &snowflakeGenerator{node: node}, nil
+}
+
+// GenPK generates an int64 snowflake id
+func (g *snowflakeGenerator) GenPK(table *core.Table, col *core.Column) (interface{}, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := time.Now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+	if now < g.lastTime {
+		// clock moved backwards, keep using the last time
+		now = g.lastTime
+	}
+	if now == g.lastTime {
+		g.seq = (g.seq + 1) & (1<<snowflakeSeqBits - 1)
+		if g.seq == 0 {
+			// sequence exhausted, wait for the next millisecond
+			for now <= g.lastTime {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+			}
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastTime = now
+	return now<<(snowflakeNodeBits+snowflakeSeqBits) | g.node<<snowflakeSeqBits | g.seq, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type UuidRecord struct {
+	Id       string `xorm:"pk uuid"`
+	Name     string
+	BeforeId string `xorm:"-"`
+}
+
+func (r *UuidRecord) BeforeInsert() {
+	r.BeforeId = r.Id
+}
+
+type UlidRecord struct {
+	Id   string `xorm:"pk varchar(26) ulid"`
+	Name string
+}
+
+type SnowflakeRecord struct {
+	Id   int64 `xorm:"pk snowflake(3)"`
+	Name string
+}
+
+type CodeRecord struct {
+	Code string `xorm:"pk varchar(20) gen(code)"`
+	Name string
+}
+
+type codeGenerator struct {
+	n int
+}
+
+func (g *codeGenerator) GenPK(table *core.Table, col *core.Column) (interface{}, error) {
+	g.n++
+	return g.n, nil
+}
+
+var (
+	uuidRe = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidRe = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+)
+
+func TestPKGenerators(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	engine.RegisterPKGenerator("code", &codeGenerator{})
+	assertNoErr(t, engine.Sync2(new(UuidRecord), new(UlidRecord), new(SnowflakeRecord), new(CodeRecord)))
+
+	uuid := UuidRecord{Name: "a"}
+	_, err := engine.Insert(&uuid)
+	assertNoErr(t, err)
+	if !uuidRe.MatchString(uuid.Id) {
+		t.Errorf("%q is not an uuid", uuid.Id)
+	}
+	if uuid.BeforeId != uuid.Id {
+		t.Errorf("the id is not generated before BeforeInsert: %q", uuid.BeforeId)
+	}
+
+	ulids := []UlidRecord{{Name: "a"}, {Name: "b"}}
+	_, err = engine.Insert(&ulids)
+	assertNoErr(t, err)
+	for _, ulid := range ulids {
+		if !ulidRe.MatchString(ulid.Id) {
+			t.Errorf("%q is not an ulid", ulid.Id)
+		}
+	}
+	if ulids[0].Id == ulids[1].Id {
+		t.Error("the ulids of InsertMulti are the same")
+	}
+
+	snowflakes := []SnowflakeRecord{{Name: "a"}, {Name: "b"}}
+	_, err = engine.Insert(&snowflakes)
+	assertNoErr(t, err)
+	if snowflakes[0].Id == 0 || snowflakes[0].Id >= snowflakes[1].Id {
+		t.Errorf("the snowflake ids are not increasing: %v, %v", snowflakes[0].Id, snowflakes[1].Id)
+	}
+	if node := snowflakes[0].Id >> snowflakeSeqBits & (1<<snowflakeNodeBits - 1); node != 3 {
+		t.Errorf("the snowflake node is %v, expected 3", node)
+	}
+
+	code := CodeRecord{Name: "a"}
+	_, err = engine.Insert(&code)
+	assertNoErr(t, err)
+	if code.Code != "1" {
+		t.Errorf("the registered generator is not used: %q", code.Code)
+	}
+
+	// a given key isn't replaced
+	code = CodeRecord{Code: "x", Name: "b"}
+	_, err = engine.Insert(&code)
+	assertNoErr(t, err)
+	var got CodeRecord
+	has, err := engine.Id("x").Get(&got)
+	assertNoErr(t, err)
+	if !has || got.Name != "b" {
+		t.Errorf("the record of key x is %+v", got)
+	}
+}
+
+func TestSnowflakeNode(t *testing.T) {
+	if _, err := NewSnowflakeGenerator(1 << snowflakeNodeBits); err == nil {
+		t.Error("a too large snowflake node should be an error")
+	}
+	engine := testEngine(t, core.SQLITE)
+	if _, err := engine.pkGenerator("nothing"); err == nil {
+		t.Error("an unregistered generator should be an error")
+	}
+}
@@ -770,13 +770,41 @@ var (
 
 type postgres struct {
 	core.Base
+	schema string
 }
 
 func (db *postgres) Init(d *core.DB, uri *core.Uri, drivername, dataSourceName string) error {
 	return db.Base.Init(d, db, uri, drivername, dataSourceName)
 }
 
+func (db *postgres) SetSchema(schema string) {
+	db.schema = schema
+}
+
+// splitName splits schema.table, the schema is public if it's not set
+func (db *postgres) splitName(tableName string) (string, string) {
+	schema := db.schema
+	if schema == "" {
+		schema = "public"
+	}
+	return splitSchema(tableName, schema)
+}
+
+// xTableName returns the table name in the names of the indexes created by
+// xorm, the indexes are in the schema of their table so it's unqualified
+func (db *postgres) xTableName(tableName string) string {
+	_, tableName = splitSchema(tableName, "")
+	return tableName
+}
+
 func (db *postgres) SqlType(c *core.Column) string {
+	if strings.HasSuffix(c.SQLType.Name, "[]") {
+		elem := *c
+		elem.SQLType.Name = strings.TrimSuffix(c.SQLType.Name, "[]")
+		elem.IsAutoIncrement = false
+		return db.SqlType(&elem) + "[]"
+	}
+
 	var res string
 	switch t := c.SQLType.Name; t {
 	case core.TinyInt:
@@ -803,6 +831,10 @@ func (db *postgres) SqlType(c *core.Column) string {
 		res = core.Text
 	case core.NVarchar:
 		res = core.Varchar
+	case core.Enum, core.Set:
+		// the enums are checked by constraints
+		res = core.Varchar
+		c.Length = optionsLength(c)
 	case core.Uuid:
 		res = core.Uuid
 	case core.Blob, core.TinyBlob, core.MediumBlob, core.LongBlob:
@@ -836,7 +868,7 @@ func (db *postgres) IsReserved(name string) bool {
 }
 
 func (db *postgres) Quote(name string) string {
-	return "\"" + name + "\""
+	return quoteParts(name, "\"", "\"")
 }
 
 func (db *postgres) QuoteStr() string {
@@ -860,14 +892,16 @@ func (db *postgres) IndexOnTable() bool {
 }
 
 func (db *postgres) IndexCheckSql(tableName, idxName string) (string, []interface{}) {
-	args := []interface{}{tableName, idxName}
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName, idxName}
 	return `SELECT indexname FROM pg_indexes ` +
-		`WHERE tablename = ? AND indexname = ?`, args
+		`WHERE schemaname = ? AND tablename = ? AND indexname = ?`, args
 }
 
 func (db *postgres) TableCheckSql(tableName string) (string, []interface{}) {
-	args := []interface{}{tableName}
-	return `SELECT tablename FROM pg_tables WHERE tablename = ?`, args
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	return `SELECT tablename FROM pg_tables WHERE schemaname = ? AND tablename = ?`, args
 }
 
 /*func (db *postgres) ColumnCheckSql(tableName, colName string) (string, []interface{}) {
@@ -888,18 +922,23 @@ func (db *postgres) DropIndexSql(tableName string, index *core.Index) string {
 	if !strings.HasPrefix(idxName, "UQE_") &&
 		!strings.HasPrefix(idxName, "IDX_") {
 		if index.Type == core.UniqueType {
-			idxName = fmt.Sprintf("UQE_%v_%v", tableName, index.Name)
+			idxName = fmt.Sprintf("UQE_%v_%v", db.xTableName(tableName), index.Name)
 		} else {
-			idxName = fmt.Sprintf("IDX_%v_%v", tableName, index.Name)
+			idxName = fmt.Sprintf("IDX_%v_%v", db.xTableName(tableName), index.Name)
 		}
 	}
+	// the index is in the schema of its table
+	if schema, _ := splitSchema(tableName, db.schema); schema != "" {
+		idxName = schema + "." + idxName
+	}
 	return fmt.Sprintf("DROP INDEX %v", quote(idxName))
 }
 
 func (db *postgres) IsColumnExist(tableName, colName string) (bool, error) {
-	args := []interface{}{tableName, colName}
-	query := "SELECT column_name FROM INFORMATION_SCHEMA.COLUMNS WHERE table_name = $1" +
-		" AND column_name = $2"
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName, colName}
+	query := "SELECT column_name FROM INFORMATION_SCHEMA.COLUMNS WHERE table_schema = $1 AND table_name = $2" +
+		" AND column_name = $3"
 	rows, err := db.DB().Query(query, args...)
 	if db.Logger != nil {
 		db.Logger.Info("[sql]", query, args)
@@ -913,18 +952,19 @@ func (db *postgres) IsColumnExist(tableName, colName string) (bool, error) {
 }
 
 func (db *postgres) GetColumns(tableName string) ([]string, map[string]*core.Column, error) {
-	args := []interface{}{tableName}
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
 	s := `SELECT column_name, column_default, is_nullable, data_type, character_maximum_length, numeric_precision, numeric_precision_radix ,
     CASE WHEN p.contype = 'p' THEN true ELSE false END AS primarykey,
-    CASE WHEN p.contype = 'u' THEN true ELSE false END AS uniquekey
+    CASE WHEN p.contype = 'u' THEN true ELSE false END AS uniquekey, t.typname
 FROM pg_attribute f
     JOIN pg_class c ON c.oid = f.attrelid JOIN pg_type t ON t.oid = f.atttypid
     LEFT JOIN pg_attrdef d ON d.adrelid = c.oid AND d.adnum = f.attnum
     LEFT JOIN pg_namespace n ON n.oid = c.relnamespace
     LEFT JOIN pg_constraint p ON p.conrelid = c.oid AND f.attnum = ANY (p.conkey)
     LEFT JOIN pg_class AS g ON p.confrelid = g.oid
-    LEFT JOIN INFORMATION_SCHEMA.COLUMNS s ON s.column_name=f.attname AND c.relname=s.table_name
-WHERE c.relkind = 'r'::char AND c.relname = $1 AND f.attnum > 0 ORDER BY f.attnum;`
+    LEFT JOIN INFORMATION_SCHEMA.COLUMNS s ON s.column_name=f.attname AND c.relname=s.table_name AND s.table_schema=n.nspname
+WHERE c.relkind = 'r'::char AND n.nspname = $1 AND c.relname = $2 AND f.attnum > 0 ORDER BY f.attnum;`
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -942,10 +982,10 @@ WHERE c.relkind = 'r'::char AND c.relname = $1 AND f.attnum > 0 ORDER BY f.attnu
 		col := new(core.Column)
 		col.Indexes = make(map[string]bool)
 
-		var colName, isNullable, dataType string
+		var colName, isNullable, dataType, udtName string
 		var maxLenStr, colDefault, numPrecision, numRadix *string
 		var isPK, isUnique bool
-		err = rows.Scan(&colName, &colDefault, &isNullable, &dataType, &maxLenStr, &numPrecision, &numRadix, &isPK, &isUnique)
+		err = rows.Scan(&colName, &colDefault, &isNullable, &dataType, &maxLenStr, &numPrecision, &numRadix, &isPK, &isUnique, &udtName)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -988,10 +1028,15 @@ WHERE c.relkind = 'r'::char AND c.relname = $1 AND f.attnum > 0 ORDER BY f.attnu
 			col.SQLType = core.SQLType{core.Bool, 0, 0}
 		case "time without time zone":
 			col.SQLType = core.SQLType{core.Time, 0, 0}
+		case "ARRAY":
+			// the element type's name begins with _, like _int8
+			col.SQLType = core.SQLType{pgUdtType(strings.TrimPrefix(udtName, "_")) + "[]", 0, 0}
+		case "USER-DEFINED":
+			col.SQLType = core.SQLType{strings.ToUpper(udtName), 0, 0}
 		default:
 			col.SQLType = core.SQLType{strings.ToUpper(dataType), 0, 0}
 		}
-		if _, ok := core.SqlTypes[col.SQLType.Name]; !ok {
+		if _, ok := core.SqlTypes[col.SQLType.Name]; !ok && !isPgNative(col.SQLType) {
 			return nil, nil, errors.New(fmt.Sprintf("unkonw colType %v", dataType))
 		}
 
@@ -1014,8 +1059,9 @@ WHERE c.relkind = 'r'::char AND c.relname = $1 AND f.attnum > 0 ORDER BY f.attnu
 }
 
 func (db *postgres) GetTables() ([]*core.Table, error) {
-	args := []interface{}{}
-	s := "SELECT tablename FROM pg_tables where schemaname = 'public'"
+	schema, _ := db.splitName("")
+	args := []interface{}{schema}
+	s := "SELECT tablename FROM pg_tables where schemaname = $1"
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -1041,8 +1087,10 @@ func (db *postgres) GetTables() ([]*core.Table, error) {
 }
 
 func (db *postgres) GetIndexes(tableName string) (map[string]*core.Index, error) {
-	args := []interface{}{tableName}
-	s := "SELECT indexname, indexdef FROM pg_indexes WHERE schemaname='public' AND tablename=$1"
+	xTableName := db.xTableName(tableName)
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT indexname, indexdef FROM pg_indexes WHERE schemaname=$1 AND tablename=$2"
 
 	rows, err := db.DB().Query(s, args...)
 	if db.Logger != nil {
@@ -1071,11 +1119,12 @@ func (db *postgres) GetIndexes(tableName string) (map[string]*core.Index, error)
 		} else {
 			indexType = core.IndexType
 		}
-		cs := strings.Split(indexdef, "(")
-		colNames = strings.Split(cs[1][0:len(cs[1])-1], ",")
+		def := &Index{}
+		parseIndexDef(def, indexdef)
+		colNames = indexColumns(def.Cols)
 
-		if strings.HasPrefix(indexName, "IDX_"+tableName) || strings.HasPrefix(indexName, "UQE_"+tableName) {
-			newIdxName := indexName[5+len(tableName) : len(indexName)]
+		if strings.HasPrefix(indexName, "IDX_"+xTableName) || strings.HasPrefix(indexName, "UQE_"+xTableName) {
+			newIdxName := indexName[5+len(xTableName) : len(indexName)]
 			if newIdxName != "" {
 				indexName = newIdxName
 			}
@@ -1090,6 +1139,127 @@ func (db *postgres) GetIndexes(tableName string) (map[string]*core.Index, error)
 	return indexes, nil
 }
 
+func (db *postgres) GetIndexOptions(tableName string) (map[string]*Index, error) {
+	xTableName := db.xTableName(tableName)
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT indexname, indexdef FROM pg_indexes WHERE schemaname=$1 AND tablename=$2"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]*Index)
+	for _, result := range results {
+		indexName := strings.Trim(result[0], `" `)
+		if strings.HasSuffix(indexName, "_pkey") {
+			continue
+		}
+		if strings.HasPrefix(indexName, "IDX_"+xTableName) || strings.HasPrefix(indexName, "UQE_"+xTableName) {
+			newIdxName := indexName[5+len(xTableName) : len(indexName)]
+			if newIdxName != "" {
+				indexName = newIdxName
+			}
+		}
+		index := &Index{Name: indexName, Unique: strings.HasPrefix(result[1], "CREATE UNIQUE INDEX")}
+		parseIndexDef(index, result[1])
+		indexes[indexName] = index
+	}
+	return indexes, nil
+}
+
+func (db *postgres) getForeignKeys(tableName string) ([]*ForeignKey, error) {
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT kcu.constraint_name, kcu.column_name, ccu.table_schema, ccu.table_name, ccu.column_name, rc.delete_rule, rc.update_rule " +
+		"FROM information_schema.referential_constraints rc " +
+		"JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema " +
+		"AND kcu.constraint_name = rc.constraint_name " +
+		"JOIN information_schema.key_column_usage ccu ON ccu.constraint_schema = rc.unique_constraint_schema " +
+		"AND ccu.constraint_name = rc.unique_constraint_name AND ccu.ordinal_position = kcu.position_in_unique_constraint " +
+		"WHERE kcu.table_schema = $1 AND kcu.table_name = $2 ORDER BY kcu.constraint_name, kcu.ordinal_position"
+
+	rows, err := db.DB().Query(s, args...)
+	if db.Logger != nil {
+		db.Logger.Info("[sql]", s, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []*ForeignKey
+	for rows.Next() {
+		var name, colName, refSchema, refTable, refCol, onDelete, onUpdate string
+		err = rows.Scan(&name, &colName, &refSchema, &refTable, &refCol, &onDelete, &onUpdate)
+		if err != nil {
+			return nil, err
+		}
+		if refSchema != schema {
+			refTable = refSchema + "." + refTable
+		}
+		fks = addForeignKeyColumn(fks, name, colName, refTable, refCol, onDelete, onUpdate)
+	}
+	return fks, nil
+}
+
+func (db *postgres) GetColumnOptions(tableName string) (*tableExt, error) {
+	ext := newTableExt()
+	fks, err := db.getForeignKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ext.foreignKeys = fks
+	schema, tableName := db.splitName(tableName)
+	args := []interface{}{schema, tableName}
+	s := "SELECT COALESCE(obj_description(c.oid, 'pg_class'), '') FROM pg_class c " +
+		"JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		ext.comment = results[0][0]
+	}
+
+	s = "SELECT a.attname, col_description(c.oid, a.attnum) FROM pg_attribute a " +
+		"JOIN pg_class c ON c.oid = a.attrelid JOIN pg_namespace n ON n.oid = c.relnamespace " +
+		"WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped " +
+		"AND col_description(c.oid, a.attnum) IS NOT NULL"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.newColumn(result[0]).comment = result[1]
+	}
+
+	// the generated columns are since postgres 12 and they are stored
+	s = "SELECT column_name, generation_expression FROM information_schema.columns " +
+		"WHERE table_schema = $1 AND table_name = $2 AND is_generated = 'ALWAYS'"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		colExt := ext.newColumn(result[0])
+		colExt.generated = result[1]
+		colExt.stored = true
+	}
+
+	s = "SELECT con.conname, pg_get_constraintdef(con.oid) FROM pg_constraint con " +
+		"JOIN pg_class c ON c.oid = con.conrelid JOIN pg_namespace n ON n.oid = c.relnamespace " +
+		"WHERE con.contype = 'c' AND n.nspname = $1 AND c.relname = $2 ORDER BY con.conname"
+	results, err = queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		ext.checks = append(ext.checks, &Check{Name: result[0], Expr: parenBody(result[1])})
+	}
+	return ext, nil
+}
+
 func (db *postgres) Filters() []core.Filter {
 	return []core.Filter{&core.IdFilter{}, &core.QuoteFilter{}, &core.SeqFilter{"$", 1}}
 }
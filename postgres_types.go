@@ -0,0 +1,669 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// the postgres types which core doesn't know, a type ending with [] like
+// BIGINT[] is an array of the type
+const (
+	pgJsonb     = "JSONB"
+	pgHstore    = "HSTORE"
+	pgInet      = "INET"
+	pgCidr      = "CIDR"
+	pgInt4Range = "INT4RANGE"
+	pgInt8Range = "INT8RANGE"
+	pgNumRange  = "NUMRANGE"
+	pgTsRange   = "TSRANGE"
+	pgTsTzRange = "TSTZRANGE"
+	pgDateRange = "DATERANGE"
+)
+
+// pgTypes are the postgres types with the types used by the other dialects
+var pgTypes = map[string]string{
+	pgJsonb:     core.Json,
+	pgHstore:    core.Text,
+	pgInet:      core.Varchar,
+	pgCidr:      core.Varchar,
+	pgInt4Range: core.Varchar,
+	pgInt8Range: core.Varchar,
+	pgNumRange:  core.Varchar,
+	pgTsRange:   core.Varchar,
+	pgTsTzRange: core.Varchar,
+	pgDateRange: core.Varchar,
+}
+
+var (
+	ipType    = reflect.TypeOf(net.IP{})
+	ipNetType = reflect.TypeOf(net.IPNet{})
+)
+
+// isPgType reports whether name is a postgres type of tags, which are in
+// upper case
+func isPgType(name string) bool {
+	if strings.HasSuffix(name, "[]") {
+		elem := strings.TrimSuffix(name, "[]")
+		_, ok := core.SqlTypes[elem]
+		return ok || isPgType(elem)
+	}
+	_, ok := pgTypes[name]
+	return ok
+}
+
+// isPgNative reports whether the values of sqlType are encoded by xorm
+func isPgNative(sqlType core.SQLType) bool {
+	if strings.HasSuffix(sqlType.Name, "[]") || sqlType.Name == core.Uuid {
+		return true
+	}
+	_, ok := pgTypes[sqlType.Name]
+	return ok
+}
+
+// pgArrayType returns the array type of a slice like []int64 for tag array
+func pgArrayType(t reflect.Type) core.SQLType {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return core.Type2SQLType(t)
+	}
+	// the slices of slices are multidimensional arrays
+	elem := t.Elem()
+	for elem.Kind() == reflect.Ptr || (elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array) &&
+		elem.Elem().Kind() != reflect.Uint8 {
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.String {
+		return core.SQLType{core.Text + "[]", 0, 0}
+	}
+	sqlType := core.Type2SQLType(elem)
+	return core.SQLType{sqlType.Name + "[]", sqlType.DefaultLength, sqlType.DefaultLength2}
+}
+
+// pgNativeType returns the type used instead of a postgres type by the
+// other dialects, the arrays and hstores are stored as JSON text
+func (engine *Engine) pgNativeType(sqlType core.SQLType) core.SQLType {
+	if engine.dialect.DBType() == core.POSTGRES {
+		return sqlType
+	}
+	if strings.HasSuffix(sqlType.Name, "[]") {
+		return core.SQLType{core.Text, 0, 0}
+	}
+	if name, ok := pgTypes[sqlType.Name]; ok {
+		if name == core.Varchar {
+			return core.SQLType{name, 255, 0}
+		}
+		return core.SQLType{name, 0, 0}
+	}
+	return sqlType
+}
+
+// pgUdtType returns the type of a postgres type name of pg_type
+func pgUdtType(udtName string) string {
+	switch udtName {
+	case "int2":
+		return core.SmallInt
+	case "int4":
+		return core.Integer
+	case "int8":
+		return core.BigInt
+	case "float4":
+		return core.Real
+	case "float8":
+		return core.Double
+	case "bool":
+		return core.Bool
+	case "varchar":
+		return core.Varchar
+	case "bpchar":
+		return core.Char
+	case "timestamp":
+		return core.DateTime
+	case "timestamptz":
+		return core.TimeStampz
+	}
+	return strings.ToUpper(udtName)
+}
+
+// pgEncode encodes the field to the text format of col's postgres type, ok
+// is false if the field is encoded as usual
+func pgEncode(col *core.Column, v reflect.Value) (arg interface{}, ok bool, err error) {
+	if !isPgNative(col.SQLType) {
+		return nil, false, nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return v.String(), true, nil
+	}
+
+	name := col.SQLType.Name
+	switch {
+	case strings.HasSuffix(name, "[]"):
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, false, nil
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, true, nil
+		}
+		s, err := pgArrayLiteral(v)
+		return s, true, err
+	case name == pgJsonb:
+		data, err := json.Marshal(v.Interface())
+		return string(data), true, err
+	case name == pgHstore:
+		if v.Kind() != reflect.Map {
+			return nil, false, nil
+		}
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		s, err := pgHstoreLiteral(v)
+		return s, true, err
+	case name == core.Uuid:
+		if v.Kind() != reflect.Array || v.Len() != 16 || v.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, false, nil
+		}
+		b := make([]byte, 16)
+		reflect.Copy(reflect.ValueOf(b), v)
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), true, nil
+	}
+
+	// inet, cidr and the ranges
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return nil, true, nil
+	}
+	if s, ok := pgText(v); ok {
+		return s, true, nil
+	}
+	return nil, false, nil
+}
+
+// pgText returns the text of a value like net.IP or a time
+func pgText(v reflect.Value) (string, bool) {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), true
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	// the methods like net.IPNet.String are declared on the pointer
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if s, ok := ptr.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
+
+// pgQuote quotes an element of arrays or hstores
+func pgQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// pgArrayLiteral returns the array literal of a slice like {1,2,3}, the
+// slices of slices are multidimensional arrays
+func pgArrayLiteral(v reflect.Value) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			if elem.IsNil() {
+				buf.WriteString("NULL")
+				continue
+			}
+			elem = elem.Elem()
+		}
+
+		switch elem.Kind() {
+		case reflect.Bool:
+			if elem.Bool() {
+				buf.WriteString("t")
+			} else {
+				buf.WriteString("f")
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			buf.WriteString(strconv.FormatInt(elem.Int(), 10))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			buf.WriteString(strconv.FormatUint(elem.Uint(), 10))
+		case reflect.Float32, reflect.Float64:
+			buf.WriteString(strconv.FormatFloat(elem.Float(), 'g', -1, 64))
+		case reflect.String:
+			buf.WriteString(pgQuote(elem.String()))
+		case reflect.Slice, reflect.Array:
+			if elem.Type().Elem().Kind() == reflect.Uint8 && elem.Type() != ipType {
+				// bytea
+				b := make([]byte, elem.Len())
+				reflect.Copy(reflect.ValueOf(b), elem)
+				buf.WriteString(pgQuote(`\x` + hex.EncodeToString(b)))
+				break
+			}
+			if elem.Type() == ipType {
+				buf.WriteString(pgQuote(elem.Interface().(net.IP).String()))
+				break
+			}
+			s, err := pgArrayLiteral(elem)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(s)
+		default:
+			if s, ok := pgText(elem); ok {
+				buf.WriteString(pgQuote(s))
+				break
+			}
+			// i.e. the structs of jsonb[]
+			data, err := json.Marshal(elem.Interface())
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(pgQuote(string(data)))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+// pgHstoreLiteral returns the hstore of a map like "a"=>"1", "b"=>NULL
+func pgHstoreLiteral(v reflect.Value) (string, error) {
+	pairs := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		if key.Kind() != reflect.String {
+			return "", fmt.Errorf("the keys of hstore should be strings but %v", key.Type())
+		}
+		value := v.MapIndex(key)
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				break
+			}
+			value = value.Elem()
+		}
+		if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil() {
+			pairs = append(pairs, pgQuote(key.String())+"=>NULL")
+		} else {
+			pairs = append(pairs, pgQuote(key.String())+"=>"+pgQuote(fmt.Sprint(value.Interface())))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", "), nil
+}
+
+// pgDecode decodes data of col's postgres type to the field, ok is false if
+// the field is decoded as usual
+func pgDecode(col *core.Column, fieldValue *reflect.Value, data []byte) (ok bool, err error) {
+	if !isPgNative(col.SQLType) {
+		return false, nil
+	}
+	v := *fieldValue
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	name := col.SQLType.Name
+	switch {
+	case t.Kind() == reflect.String:
+	case strings.HasSuffix(name, "[]"):
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return false, nil
+		}
+	case name == pgJsonb:
+	case name == pgHstore:
+		if t.Kind() != reflect.Map {
+			return false, nil
+		}
+	case name == core.Uuid:
+		if t.Kind() != reflect.Array || t.Len() != 16 || t.Elem().Kind() != reflect.Uint8 {
+			return false, nil
+		}
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	s := string(data)
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+		return true, nil
+	case strings.HasSuffix(name, "[]"):
+		return true, pgParseArray(s, v)
+	case name == pgJsonb:
+		x := reflect.New(v.Type())
+		if err := json.Unmarshal(data, x.Interface()); err != nil {
+			return true, err
+		}
+		v.Set(x.Elem())
+		return true, nil
+	case name == pgHstore:
+		return true, pgParseHstore(s, v)
+	case name == core.Uuid:
+		b, err := hex.DecodeString(strings.Replace(strings.Trim(s, "{}"), "-", "", -1))
+		if err != nil {
+			return true, err
+		}
+		if len(b) != 16 {
+			return true, fmt.Errorf("invalid uuid %v", s)
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+		return true, nil
+	}
+	return true, pgSetText(v, s)
+}
+
+// pgSetText sets v by the text of a postgres value
+func pgSetText(v reflect.Value, s string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case ipType:
+		// inet has the mask unless it's a host
+		if pos := strings.Index(s, "/"); pos > 0 {
+			s = s[:pos]
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("invalid ip %v", s)
+		}
+		v.Set(reflect.ValueOf(ip))
+		return nil
+	case ipNetType:
+		if !strings.Contains(s, "/") {
+			if strings.Contains(s, ":") {
+				s += "/128"
+			} else {
+				s += "/32"
+			}
+		}
+		ip, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return err
+		}
+		ipNet.IP = ip
+		v.Set(reflect.ValueOf(*ipNet))
+		return nil
+	case core.TimeType:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07",
+			"2006-01-02 15:04:05.999999999", time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid time %v", s)
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		v.SetBool(s == "t" || strings.EqualFold(s, "true"))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %v", v.Type())
+		}
+		if strings.HasPrefix(s, `\x`) {
+			b, err := hex.DecodeString(s[2:])
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+		} else {
+			v.SetBytes([]byte(s))
+		}
+	default:
+		// i.e. the structs of jsonb[]
+		x := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(s), x.Interface()); err != nil {
+			return err
+		}
+		v.Set(x.Elem())
+	}
+	return nil
+}
+
+// pgArrayElem is an element of an array literal, text is the literal of a
+// nested array
+type pgArrayElem struct {
+	text   string
+	null   bool
+	nested bool
+}
+
+var errPgArray = errors.New("invalid array literal")
+
+// pgSplitArray splits an array literal like {1,"a b",NULL,{2,3}} to the
+// elements, the quoted ones are unescaped
+func pgSplitArray(s string) ([]pgArrayElem, error) {
+	// the bounds like [0:2]={1,2,3}
+	if strings.HasPrefix(s, "[") {
+		if pos := strings.Index(s, "="); pos > 0 {
+			s = s[pos+1:]
+		}
+	}
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, errPgArray
+	}
+	s = s[1 : len(s)-1]
+	elems := make([]pgArrayElem, 0)
+	if strings.TrimSpace(s) == "" {
+		return elems, nil
+	}
+
+	for i := 0; ; {
+		switch {
+		case i < len(s) && s[i] == '"':
+			var buf bytes.Buffer
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				buf.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errPgArray
+			}
+			i++
+			elems = append(elems, pgArrayElem{text: buf.String()})
+		case i < len(s) && s[i] == '{':
+			start, depth, quoted := i, 0, false
+			for ; i < len(s); i++ {
+				switch c := s[i]; {
+				case quoted && c == '\\':
+					i++
+				case c == '"':
+					quoted = !quoted
+				case !quoted && c == '{':
+					depth++
+				case !quoted && c == '}':
+					depth--
+				}
+				if depth == 0 {
+					break
+				}
+			}
+			if i >= len(s) {
+				return nil, errPgArray
+			}
+			i++
+			elems = append(elems, pgArrayElem{text: s[start:i], nested: true})
+		default:
+			end := strings.IndexByte(s[i:], ',')
+			if end < 0 {
+				end = len(s) - i
+			}
+			text := strings.TrimSpace(s[i : i+end])
+			elems = append(elems, pgArrayElem{text: text, null: strings.EqualFold(text, "NULL")})
+			i += end
+		}
+
+		if i >= len(s) {
+			return elems, nil
+		}
+		if s[i] != ',' {
+			return nil, errPgArray
+		}
+		i++
+	}
+}
+
+// pgParseArray parses an array literal to a slice or an array
+func pgParseArray(s string, v reflect.Value) error {
+	elems, err := pgSplitArray(s)
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), len(elems), len(elems)))
+	} else if len(elems) > v.Len() {
+		return fmt.Errorf("%v elements can't be set to %v", len(elems), v.Type())
+	}
+
+	for i, elem := range elems {
+		ev := v.Index(i)
+		switch {
+		case elem.null:
+			ev.Set(reflect.Zero(ev.Type()))
+		case elem.nested:
+			for ev.Kind() == reflect.Ptr {
+				if ev.IsNil() {
+					ev.Set(reflect.New(ev.Type().Elem()))
+				}
+				ev = ev.Elem()
+			}
+			if err := pgParseArray(elem.text, ev); err != nil {
+				return err
+			}
+		default:
+			if err := pgSetText(ev, elem.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pgParseHstore parses an hstore like "a"=>"1", "b"=>NULL to a map
+func pgParseHstore(s string, v reflect.Value) error {
+	i := 0
+	skipSpaces := func() {
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+	}
+	// next returns a quoted string or a word like NULL
+	next := func() (string, bool, error) {
+		skipSpaces()
+		if i < len(s) && s[i] == '"' {
+			var buf bytes.Buffer
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				buf.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return "", false, errors.New("invalid hstore")
+			}
+			i++
+			return buf.String(), false, nil
+		}
+		start := i
+		for i < len(s) && s[i] != ',' && s[i] != '=' && s[i] != ' ' {
+			i++
+		}
+		word := s[start:i]
+		return word, strings.EqualFold(word, "NULL"), nil
+	}
+
+	m := reflect.MakeMap(v.Type())
+	keyType, elemType := v.Type().Key(), v.Type().Elem()
+	for skipSpaces(); i < len(s); skipSpaces() {
+		key, _, err := next()
+		if err != nil {
+			return err
+		}
+		skipSpaces()
+		if !strings.HasPrefix(s[i:], "=>") {
+			return errors.New("invalid hstore")
+		}
+		i += 2
+		value, null, err := next()
+		if err != nil {
+			return err
+		}
+
+		ev := reflect.New(elemType).Elem()
+		if !null {
+			if err := pgSetText(ev, value); err != nil {
+				return err
+			}
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(keyType), ev)
+
+		skipSpaces()
+		if i < len(s) && s[i] == ',' {
+			i++
+		}
+	}
+	v.Set(m)
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type PgDoc struct {
+	Id   int64
+	Tags []string               `xorm:"array"`
+	Meta map[string]interface{} `xorm:"jsonb"`
+}
+
+func TestPgEncode(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	table := engine.TableInfo(new(PgDoc))
+	if col := table.GetColumn("meta"); col == nil || col.SQLType.Name != pgJsonb {
+		t.Fatalf("expected meta a jsonb, got %v", col)
+	}
+
+	doc := &PgDoc{Tags: []string{"a", "b c"}, Meta: map[string]interface{}{"x": 1}}
+	_, args, err := buildUpdates(engine, table, doc, false, false, false, false, false, false,
+		map[string]bool{}, map[string]bool{}, map[string]bool{}, true)
+	assertNoErr(t, err)
+	if expected := []interface{}{`{"a","b c"}`, `{"x":1}`}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	// the values which can't be encoded are errors instead of being skipped
+	doc.Meta["x"] = math.Inf(1)
+	_, _, err = buildUpdates(engine, table, doc, false, false, false, false, false, false,
+		map[string]bool{}, map[string]bool{}, map[string]bool{}, true)
+	if err == nil {
+		t.Error("expected the error of encoding meta by buildUpdates")
+	}
+
+	session := engine.NewSession()
+	defer session.Close()
+	if _, _, err = session.Statement.genGetSql(doc); err == nil {
+		t.Error("expected the error of encoding meta by buildConditions")
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+)
+
+// splitOutside splits s by sep which is neither in parentheses nor in
+// quotes, quoted by ', ", ` or []. The parts are kept as they are.
+func splitOutside(s string, sep byte) []string {
+	parts := make([]string, 0)
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth <= 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquoteIdent removes the quotes of an identifier quoted by ", ` or [],
+// the escaped quotes in it are unescaped
+func unquoteIdent(ident string) (string, bool) {
+	if len(ident) < 2 {
+		return ident, false
+	}
+	switch first, last := ident[0], ident[len(ident)-1]; {
+	case (first == '"' || first == '`') && last == first:
+		q := string(first)
+		return strings.Replace(ident[1:len(ident)-1], q+q, q, -1), true
+	case first == '[' && last == ']':
+		return strings.Replace(ident[1:len(ident)-1], "]]", "]", -1), true
+	}
+	return ident, false
+}
+
+// isIdent reports whether s is a plain identifier, which needs no quotes to
+// be recognized
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c > 0x7f:
+		case i > 0 && (c >= '0' && c <= '9' || c == '$' || c == '#'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quoteParts quotes each part of a qualified name like schema.table by left
+// and right, the quotes in the names are escaped. The parts quoted already
+// are quoted again by left and right.
+func quoteParts(name, left, right string) string {
+	// oracle's quote is blank
+	if strings.TrimSpace(left) == "" {
+		return left + name + right
+	}
+	parts := splitOutside(name, '.')
+	for i, part := range parts {
+		if part == "" || part == "*" {
+			continue
+		}
+		part, _ = unquoteIdent(part)
+		parts[i] = left + strings.Replace(part, right, right+right, -1) + right
+	}
+	return strings.Join(parts, ".")
+}
+
+// isReserved reports whether word is reserved by the dialect
+func (engine *Engine) isReserved(word string) bool {
+	return engine.dialect.IsReserved(strings.ToUpper(word))
+}
+
+// quoteName quotes a name which may be qualified like schema.table.col
+func (engine *Engine) quoteName(name string) string {
+	quote := engine.dialect.QuoteStr()
+	if strings.TrimSpace(quote) != "" {
+		return quoteParts(name, quote, quote)
+	}
+
+	// oracle doesn't quote the names since the quoted ones are case
+	// sensitive, except the reserved words
+	parts := splitOutside(name, '.')
+	for i, part := range parts {
+		if ident, quoted := unquoteIdent(part); quoted {
+			parts[i] = `"` + strings.Replace(ident, `"`, `""`, -1) + `"`
+		} else if isIdent(part) && engine.isReserved(part) {
+			parts[i] = `"` + strings.ToUpper(part) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteExpr quotes the names in a column expression of Cols, Desc or Asc.
+// It knows the names like t.col, the aliases like a.b AS c, the function
+// calls like count(*) and the stars, the others like a + 1 are kept as they
+// are.
+func (engine *Engine) quoteExpr(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if expr == "" || expr == "*" {
+		return expr
+	}
+
+	words := make([]string, 0)
+	for _, word := range splitOutside(expr, ' ') {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	n := len(words)
+	switch {
+	case n >= 3 && strings.EqualFold(words[n-2], "AS"):
+		return engine.quoteExpr(strings.Join(words[:n-2], " ")) + " AS " + engine.quoteName(words[n-1])
+	case n == 2 && strings.EqualFold(words[0], "DISTINCT"):
+		return words[0] + " " + engine.quoteExpr(words[1])
+	case n == 2 && isQuotable(words[1]) && !engine.isReserved(words[1]):
+		// an alias without AS
+		return engine.quoteExpr(words[0]) + " " + engine.quoteName(words[1])
+	case n > 1:
+		return expr
+	}
+
+	if pos := strings.Index(expr, "("); pos > 0 && strings.HasSuffix(expr, ")") && isIdent(expr[:pos]) {
+		args := splitOutside(expr[pos+1:len(expr)-1], ',')
+		for i, arg := range args {
+			args[i] = engine.quoteExpr(arg)
+		}
+		return expr[:pos] + "(" + strings.Join(args, ", ") + ")"
+	}
+	if isQuotable(expr) {
+		return engine.quoteName(expr)
+	}
+	return expr
+}
+
+// isQuotable reports whether s is a name, the parts of a qualified name are
+// identifiers, quoted ones or a star at last
+func isQuotable(s string) bool {
+	parts := splitOutside(s, '.')
+	for i, part := range parts {
+		if _, quoted := unquoteIdent(part); quoted {
+			continue
+		}
+		if !isIdent(part) && !(part == "*" && i > 0 && i == len(parts)-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteExprs quotes the column expressions, each one could be a list
+// separated by commas
+func (engine *Engine) quoteExprs(columns ...string) []string {
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		for _, expr := range splitOutside(col, ',') {
+			if expr = engine.quoteExpr(expr); expr != "" {
+				quoted = append(quoted, expr)
+			}
+		}
+	}
+	return quoted
+}
+
+// quoteColumns quotes the column expressions and joins them
+func (engine *Engine) quoteColumns(columns ...string) string {
+	return strings.Join(engine.quoteExprs(columns...), ", ")
+}
+
+// quoteOrder quotes the names in the terms of ORDER BY or GROUP BY, each one
+// could end with ASC or DESC and NULLS FIRST or NULLS LAST. The functions
+// and the expressions are kept as they are.
+func (engine *Engine) quoteOrder(order string) string {
+	terms := make([]string, 0)
+	for _, term := range splitOutside(order, ',') {
+		words := make([]string, 0)
+		for _, word := range splitOutside(term, ' ') {
+			if word != "" {
+				words = append(words, word)
+			}
+		}
+		suffix := ""
+		for len(words) > 1 {
+			last := strings.ToUpper(words[len(words)-1])
+			if last != "ASC" && last != "DESC" && last != "FIRST" && last != "LAST" && last != "NULLS" {
+				break
+			}
+			suffix = " " + words[len(words)-1] + suffix
+			words = words[:len(words)-1]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		term = strings.Join(words, " ")
+		if isQuotable(term) {
+			term = engine.quoteName(term)
+		}
+		terms = append(terms, term+suffix)
+	}
+	return strings.Join(terms, ", ")
+}
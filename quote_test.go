@@ -0,0 +1,70 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type QuoteUser struct {
+	Id   int64
+	Name string
+}
+
+func TestQuote(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	assertSQL(t, `"app"."user"`, engine.Quote("app.user"))
+	assertSQL(t, `"a""b"`, engine.Quote(`a"b`))
+	assertSQL(t, `"name"`, engine.Quote(`"name"`))
+	for expr, expected := range map[string]string{
+		"t.col":          `"t"."col"`,
+		"t.*":            `"t".*`,
+		"a.b AS c":       `"a"."b" AS "c"`,
+		"a.b alias":      `"a"."b" "alias"`,
+		"count(*)":       `count(*)`,
+		"max(t.id) AS m": `max("t"."id") AS "m"`,
+		"DISTINCT name":  `DISTINCT "name"`,
+		"id + 1":         `id + 1`,
+	} {
+		assertSQL(t, expected, engine.quoteExpr(expr))
+	}
+
+	engine = testEngine(t, core.MYSQL)
+	assertSQL(t, "`a``b`", engine.Quote("a`b"))
+	assertSQL(t, "`t`.`col`, count(*)", engine.quoteColumns("t.col, count(*)"))
+
+	// oracle quotes the reserved words only
+	engine = testEngine(t, core.ORACLE)
+	assertSQL(t, `t."LEVEL"`, engine.quoteName("t.level"))
+	assertSQL(t, `t.name`, engine.quoteName("t.name"))
+}
+
+func TestQuoteOrder(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	for order, expected := range map[string]string{
+		"name":                      `"name"`,
+		"t.name DESC, id":           `"t"."name" DESC, "id"`,
+		"name asc nulls last":       `"name" asc nulls last`,
+		"\"Name\" DESC NULLS FIRST": `"Name" DESC NULLS FIRST`,
+		"lower(name), id + 1 DESC":  `lower(name), id + 1 DESC`,
+		"count(*) DESC, user.id":    `count(*) DESC, "user"."id"`,
+	} {
+		assertSQL(t, expected, engine.quoteOrder(order))
+	}
+
+	engine = testEngine(t, core.MYSQL)
+	assertSQL(t, "`order` DESC, `t`.`group`", engine.quoteOrder("order DESC, t.group"))
+}
+
+func TestOrderByGroupBy(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	session := engine.NewSession()
+	defer session.Close()
+	session.Table(new(QuoteUser)).OrderBy("Name DESC, lower(name)").Asc("id").GroupBy("Name, id")
+	assertSQL(t, `SELECT "id", "name" FROM "quote_user" GROUP BY "Name", "id" ORDER BY "Name" DESC, lower(name), "id" ASC`,
+		session.Statement.genSelectSql(session.Statement.genColumnStr()))
+}
@@ -0,0 +1,187 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// returningColumns returns the columns which should be read back after
+// Insert or Update, nil if Returning is not used.
+func (statement *Statement) returningColumns() ([]*core.Column, error) {
+	if !statement.returning {
+		return nil, nil
+	}
+
+	table := statement.RefTable
+	cols := make([]*core.Column, 0)
+	if len(statement.returningCols) == 0 {
+		for _, col := range table.Columns() {
+			if col.MapType != core.ONLYTODB {
+				cols = append(cols, col)
+			}
+		}
+		return cols, nil
+	}
+
+	var hasAutoIncr bool
+	for _, name := range statement.returningCols {
+		col := table.GetColumn(name)
+		if col == nil {
+			return nil, fmt.Errorf("column %v is not found on table %v", name, table.Name)
+		}
+		if col.Name == table.AutoIncrement {
+			hasAutoIncr = true
+		}
+		cols = append(cols, col)
+	}
+	// the autoincrement id is always read back since the RETURNING path has
+	// no LastInsertId
+	if table.AutoIncrement != "" && !hasAutoIncr {
+		cols = append(cols, table.AutoIncrColumn())
+	}
+	return cols, nil
+}
+
+// hasReturning reports whether the database has RETURNING or OUTPUT. sqlite
+// has RETURNING since 3.35, its version is read once by the engine.
+func (engine *Engine) hasReturning() bool {
+	switch engine.dialect.DBType() {
+	case core.POSTGRES, core.MSSQL, core.ORACLE:
+		return true
+	case core.SQLITE:
+	default:
+		return false
+	}
+
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.sqliteVersion == "" {
+		if err := engine.db.QueryRow("SELECT sqlite_version()").Scan(&engine.sqliteVersion); err != nil {
+			engine.LogWarnf("could not read the version of sqlite3: %v", err)
+			engine.sqliteVersion = "0"
+		}
+	}
+	var major, minor int
+	fmt.Sscanf(engine.sqliteVersion, "%d.%d", &major, &minor)
+	return major > 3 || (major == 3 && minor >= 35)
+}
+
+// genReturning generates the clause which reads back the columns. For mssql
+// the OUTPUT clause should be put before VALUES or WHERE, for the others the
+// RETURNING clause goes to the end. Both are empty if the database has none,
+// i.e. mysql and sqlite before 3.35, which read them back by primary keys.
+func (statement *Statement) genReturning(cols []*core.Column) (outputStr string, returningStr string) {
+	if len(cols) == 0 || !statement.Engine.hasReturning() {
+		return "", ""
+	}
+
+	names := make([]string, 0, len(cols))
+	switch statement.Engine.dialect.DBType() {
+	case core.POSTGRES, core.SQLITE:
+		for _, col := range cols {
+			names = append(names, statement.Engine.Quote(col.Name))
+		}
+		returningStr = " RETURNING " + strings.Join(names, ", ")
+	case core.MSSQL:
+		for _, col := range cols {
+			names = append(names, "INSERTED."+statement.Engine.Quote(col.Name))
+		}
+		outputStr = " OUTPUT " + strings.Join(names, ", ")
+	case core.ORACLE:
+		for _, col := range cols {
+			names = append(names, statement.Engine.Quote(col.Name))
+		}
+		returningStr = fmt.Sprintf(" RETURNING %v INTO %v", strings.Join(names, ", "),
+			strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+	}
+	return
+}
+
+// execReturning executes sqlStr which has a RETURNING or OUTPUT clause and
+// fills the returned values into bean. It returns the affected rows and
+// ErrReturningRows if more than one row is changed, the changes are kept.
+func (session *Session) execReturning(bean interface{}, cols []*core.Column, sqlStr string, args ...interface{}) (int64, error) {
+	if session.Engine.dialect.DBType() == core.ORACLE {
+		// oracle's RETURNING INTO writes the values to the out parameters
+		for _, col := range cols {
+			fieldValue, err := colValueOf(col, bean)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, sql.Out{Dest: fieldValue.Addr().Interface()})
+		}
+		res, err := session.exec(sqlStr, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	resultsSlice, err := session.query(sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected := int64(len(resultsSlice))
+	if affected > 1 {
+		return affected, ErrReturningRows
+	}
+	if affected == 1 {
+		if err = session.scanMapIntoStruct(bean, resultsSlice[0]); err != nil {
+			return affected, err
+		}
+	}
+	return affected, nil
+}
+
+// refreshBean reads the columns back by the primary keys of Id or else the
+// bean's, it's used for the databases which have no RETURNING, i.e. mysql
+// and sqlite before 3.35. It returns ErrReturningRows if the row couldn't be
+// identified.
+func (session *Session) refreshBean(bean interface{}, cols []*core.Column) error {
+	table := session.Statement.RefTable
+	pkCols := table.PKColumns()
+	if len(pkCols) == 0 {
+		return ErrReturningRows
+	}
+
+	var pk core.PK
+	if session.Statement.IdParam != nil {
+		pk = *session.Statement.IdParam
+	} else {
+		pk = session.Engine.IdOf(bean)
+	}
+	if len(pk) != len(pkCols) {
+		return ErrReturningRows
+	}
+	conds := make([]string, 0, len(pkCols))
+	for i, col := range pkCols {
+		if isZero(pk[i]) {
+			return ErrReturningRows
+		}
+		conds = append(conds, session.Engine.Quote(col.Name)+" = ?")
+	}
+
+	names := make([]string, 0, len(cols))
+	for _, col := range cols {
+		names = append(names, session.Engine.Quote(col.Name))
+	}
+
+	sqlStr := fmt.Sprintf("SELECT %v FROM %v WHERE %v", strings.Join(names, ", "),
+		session.Engine.Quote(session.Statement.TableName()),
+		strings.Join(conds, " "+session.Engine.dialect.AndStr()+" "))
+	resultsSlice, err := session.query(sqlStr, pk...)
+	if err != nil {
+		return err
+	}
+	if len(resultsSlice) != 1 {
+		return ErrReturningRows
+	}
+	return session.scanMapIntoStruct(bean, resultsSlice[0])
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type ReturningUser struct {
+	Id     int64
+	Name   string
+	Status int `xorm:"default 3"`
+}
+
+func TestGenReturning(t *testing.T) {
+	expected := map[string][2]string{
+		"postgres": {"", ` RETURNING "status", "id"`},
+		"mssql":    {` OUTPUT INSERTED."status", INSERTED."id"`, ""},
+		"mysql":    {"", ""},
+		"3.34.1":   {"", ""},
+		"3.35.0":   {"", " RETURNING `status`, `id`"},
+	}
+	for name, strs := range expected {
+		dbType := core.DbType(name)
+		if strings.HasPrefix(name, "3.") {
+			dbType = core.SQLITE
+		}
+		engine := testEngine(t, dbType)
+		engine.sqliteVersion = name
+		session := engine.NewSession()
+		session.Table(&ReturningUser{}).Returning("status")
+		cols, err := session.Statement.returningColumns()
+		assertNoErr(t, err)
+		outputStr, returningStr := session.Statement.genReturning(cols)
+		assertSQL(t, strs[0], outputStr)
+		assertSQL(t, strs[1], returningStr)
+		session.Close()
+	}
+}
+
+func TestInsertReturningSetsId(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(ReturningUser)))
+
+	user := ReturningUser{Name: "a"}
+	_, err := engine.Returning("name").Omit("status").Insert(&user)
+	assertNoErr(t, err)
+	if user.Id == 0 {
+		t.Error("the autoincrement id is not set")
+	}
+
+	user = ReturningUser{Name: "b"}
+	_, err = engine.Returning("status").Omit("status").Insert(&user)
+	assertNoErr(t, err)
+	if user.Id != 2 || user.Status != 3 {
+		t.Errorf("the inserted record is not read back: %+v", user)
+	}
+}
+
+func TestUpdateReturning(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(ReturningUser)))
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := engine.Insert(&ReturningUser{Name: name})
+		assertNoErr(t, err)
+	}
+
+	// sqlite3 has RETURNING since 3.35, the older ones read the row back by
+	// the primary key of Id
+	for _, version := range []string{"", "3.34.1"} {
+		engine.sqliteVersion = version
+		log.reset()
+		user := ReturningUser{Status: 5}
+		_, err := engine.Id(2).Returning().Update(&user)
+		assertNoErr(t, err)
+		if user.Id != 2 || user.Name != "b" || user.Status != 5 {
+			t.Errorf("the updated record is not read back: %+v", user)
+		}
+		hasReturning := strings.Contains(strings.Join(log.sqls, "\n"), "RETURNING")
+		if hasReturning != (version == "") {
+			t.Errorf("unexpected sqls of sqlite %v: %v", version, log.sqls)
+		}
+
+		// the bean couldn't be refreshed by one of several rows
+		user = ReturningUser{Status: 6}
+		_, err = engine.Where("id > ?", 1).Returning("name").Update(&user)
+		if err != ErrReturningRows || user.Name != "" {
+			t.Errorf("expected ErrReturningRows, got %v %+v", err, user)
+		}
+	}
+}
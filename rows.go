@@ -35,7 +35,10 @@ func newRows(session *Session, bean interface{}) (*Rows, error) {
 	var args []interface{}
 	rows.session.Statement.RefTable = rows.session.Engine.TableInfo(bean)
 	if rows.session.Statement.RawSQL == "" {
-		sqlStr, args = rows.session.Statement.genGetSql(bean)
+		var err error
+		if sqlStr, args, err = rows.session.Statement.genGetSql(bean); err != nil {
+			return nil, err
+		}
 	} else {
 		sqlStr = rows.session.Statement.RawSQL
 		args = rows.session.Statement.RawParams
@@ -0,0 +1,67 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// schemaDialect is implemented by the dialects whose metadata queries are
+// limited to a schema
+type schemaDialect interface {
+	SetSchema(schema string)
+}
+
+// SetSchema sets the schema of the tables whose names aren't qualified, a
+// bean could have its own one by returning schema.table from TableName.
+// Sync2 and DumpAll work on the tables of the schema. The indexes and the
+// constraints are in the schema of their table and named by the unqualified
+// table name.
+func (engine *Engine) SetSchema(schema string) {
+	engine.schema = schema
+	if dialect, ok := engine.dialect.(schemaDialect); ok {
+		dialect.SetSchema(schema)
+	}
+}
+
+// Schema returns the schema set by SetSchema
+func (engine *Engine) Schema() string {
+	return engine.schema
+}
+
+// tbNameWithSchema qualifies tableName with the schema of engine, unless
+// it's qualified already
+func (engine *Engine) tbNameWithSchema(tableName string) string {
+	if engine.schema == "" || tableName == "" || strings.Contains(tableName, ".") {
+		return tableName
+	}
+	return engine.schema + "." + tableName
+}
+
+// splitSchema splits schema.table, schema is def if tableName isn't
+// qualified
+func splitSchema(tableName, def string) (schema, name string) {
+	if pos := strings.LastIndex(tableName, "."); pos >= 0 {
+		return tableName[:pos], tableName[pos+1:]
+	}
+	return def, tableName
+}
+
+// tableMeta reads the metas of a table which may be in another schema, it's
+// nil if the table doesn't exist
+func (session *Session) tableMeta(tableName string) (*core.Table, error) {
+	exist, err := session.isTableExist(tableName)
+	if err != nil || !exist {
+		return nil, err
+	}
+	table := core.NewEmptyTable()
+	table.Name = tableName
+	if err = session.Engine.loadMetas([]*core.Table{table}, false); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/go-xorm/core"
+)
+
+type SchemaOwner struct {
+	Id int64
+}
+
+type SchemaDoc struct {
+	Id      int64
+	OwnerId int64  `xorm:"index fk(schema_owner.id)"`
+	Code    string `xorm:"unique check(code <> '')"`
+}
+
+// TestSchemaDDL creates the tables in schema app, the indexes and the
+// constraints are named by the unqualified table names
+func TestSchemaDDL(t *testing.T) {
+	expected := map[core.DbType][]string{
+		core.MYSQL: {
+			"CREATE TABLE IF NOT EXISTS `app`.`schema_doc` (`id` BIGINT(20) PRIMARY KEY AUTO_INCREMENT NOT NULL, " +
+				"`owner_id` BIGINT(20) NULL, `code` VARCHAR(255) NULL, " +
+				"CONSTRAINT `CK_schema_doc_code` CHECK (code <> ''), " +
+				"CONSTRAINT `FK_schema_doc_owner_id` FOREIGN KEY (`owner_id`) REFERENCES `app`.`schema_owner` (`id`))",
+			"CREATE INDEX `IDX_schema_doc_owner_id` ON `app`.`schema_doc` (`owner_id`)",
+			"CREATE UNIQUE INDEX `UQE_schema_doc_code` ON `app`.`schema_doc` (`code`)",
+			"DROP INDEX `IDX_schema_doc_owner_id` ON `app`.`schema_doc`",
+		},
+		core.POSTGRES: {
+			`CREATE TABLE IF NOT EXISTS "app"."schema_doc" ("id" SERIAL PRIMARY KEY NOT NULL, ` +
+				`"owner_id" BIGINT NULL, "code" VARCHAR(255) NULL, ` +
+				`CONSTRAINT "CK_schema_doc_code" CHECK (code <> ''), ` +
+				`CONSTRAINT "FK_schema_doc_owner_id" FOREIGN KEY ("owner_id") REFERENCES "app"."schema_owner" ("id"))`,
+			`CREATE INDEX "IDX_schema_doc_owner_id" ON "app"."schema_doc" ("owner_id")`,
+			`CREATE UNIQUE INDEX "UQE_schema_doc_code" ON "app"."schema_doc" ("code")`,
+			`DROP INDEX "app"."IDX_schema_doc_owner_id"`,
+		},
+	}
+	for dbType, sqls := range expected {
+		engine := testEngine(t, dbType)
+		engine.SetSchema("app")
+		session := engine.NewSession()
+		session.Statement.RefTable = engine.TableInfo(new(SchemaDoc))
+		assertSQL(t, sqls[0], session.Statement.genCreateTableSQL())
+		indexSQLs := session.Statement.genIndexSQL()
+		uniqueSQLs := session.Statement.genUniqueSQL()
+		if len(indexSQLs) != 1 || len(uniqueSQLs) != 1 {
+			t.Fatalf("expected an index and a unique, got %v %v", indexSQLs, uniqueSQLs)
+		}
+		assertSQL(t, sqls[1], indexSQLs[0])
+		assertSQL(t, sqls[2], uniqueSQLs[0])
+
+		index := core.NewIndex("owner_id", core.IndexType)
+		index.IsRegular = true
+		assertSQL(t, sqls[3], engine.dropIndexSQL(session.Statement.TableName(), index))
+
+		delSQLs := session.Statement.genDelIndexSQL()
+		sort.Strings(delSQLs)
+		assertSQL(t, sqls[3], delSQLs[0])
+		session.Close()
+	}
+}
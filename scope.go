@@ -0,0 +1,100 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+)
+
+// tableScope is a condition which is applied to all queries of a table
+type tableScope struct {
+	name string
+	cond string
+	args []interface{}
+}
+
+// AddScope adds a named condition which is applied to all the queries,
+// updates and deletes of bean's table, i.e. AddScope(new(User), "active",
+// "status <> ?", "archived"). Session.WithoutScope disables it. A scope with
+// the same name is replaced and moved to the last.
+func (engine *Engine) AddScope(bean interface{}, name string, cond string, args ...interface{}) {
+	table := engine.TableInfo(bean)
+
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	ext, ok := engine.tableExts[table]
+	if !ok {
+		ext = newTableExt()
+		engine.tableExts[table] = ext
+	}
+	// the scopes are copied on write since they're read without lock
+	scopes := make([]tableScope, 0, len(ext.scopes)+1)
+	for _, s := range ext.scopes {
+		if s.name != name {
+			scopes = append(scopes, s)
+		}
+	}
+	ext.scopes = append(scopes, tableScope{name, cond, args})
+}
+
+// scopeConds returns the conditions of the table's scopes which are not
+// disabled by WithoutScope
+func (statement *Statement) scopeConds() ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	// the tenant could not be disabled
+	if cond, tenantArgs := statement.tenantCond(); cond != "" {
+		conds = append(conds, cond)
+		args = append(args, tenantArgs...)
+	}
+	if statement.RefTable == nil || statement.withoutAllScopes {
+		return conds, args
+	}
+
+	for _, scope := range statement.Engine.tableExt(statement.RefTable).scopes {
+		if statement.withoutScopes[scope.name] {
+			continue
+		}
+		conds = append(conds, "("+scope.cond+")")
+		args = append(args, scope.args...)
+	}
+	return conds, args
+}
+
+// attachScopes appends the scopes to ConditionStr and BeanArgs
+func (statement *Statement) attachScopes() {
+	conds, args := statement.scopeConds()
+	if len(conds) == 0 {
+		return
+	}
+	andStr := " " + statement.Engine.dialect.AndStr() + " "
+	if statement.ConditionStr != "" {
+		conds = append([]string{statement.ConditionStr}, conds...)
+	}
+	statement.ConditionStr = strings.Join(conds, andStr)
+	statement.BeanArgs = append(statement.BeanArgs, args...)
+}
+
+// WithoutScope disables the named scopes, or all of them if no name given
+func (statement *Statement) WithoutScope(names ...string) *Statement {
+	if len(names) == 0 {
+		statement.withoutAllScopes = true
+		return statement
+	}
+	if statement.withoutScopes == nil {
+		statement.withoutScopes = make(map[string]bool)
+	}
+	for _, name := range names {
+		statement.withoutScopes[name] = true
+	}
+	return statement
+}
+
+// WithoutScope disables the named scopes, or all of them if no name given
+func (session *Session) WithoutScope(names ...string) *Session {
+	session.Statement.WithoutScope(names...)
+	return session
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+)
+
+type ScopeDoc struct {
+	Id     int64
+	Name   string
+	Status string
+}
+
+func TestScopes(t *testing.T) {
+	engine, log, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(ScopeDoc)))
+	_, err := engine.Insert(&ScopeDoc{Name: "a", Status: "active"}, &ScopeDoc{Name: "b", Status: "archived"},
+		&ScopeDoc{Name: "c", Status: "active"})
+	assertNoErr(t, err)
+	engine.AddScope(new(ScopeDoc), "active", "status <> ?", "archived")
+
+	log.reset()
+	var docs []ScopeDoc
+	assertNoErr(t, engine.Find(&docs))
+	if len(docs) != 2 {
+		t.Errorf("found %d records, expected 2", len(docs))
+	}
+	if len(log.sqls) == 0 || !strings.Contains(log.sqls[0], "(status <> ?)") {
+		t.Errorf("the scope is not in the sql: %v", log.sqls)
+	}
+
+	var doc ScopeDoc
+	has, err := engine.Where("name = ?", "b").Get(&doc)
+	assertNoErr(t, err)
+	if has {
+		t.Error("Get finds the record out of the scope")
+	}
+
+	cnt, err := engine.Count(new(ScopeDoc))
+	assertNoErr(t, err)
+	if cnt != 2 {
+		t.Errorf("counted %d records, expected 2", cnt)
+	}
+	cnt, err = engine.WithoutScope("active").Count(new(ScopeDoc))
+	assertNoErr(t, err)
+	if cnt != 3 {
+		t.Errorf("counted %d records without the scope, expected 3", cnt)
+	}
+
+	iterated := 0
+	assertNoErr(t, engine.Iterate(new(ScopeDoc), func(i int, bean interface{}) error {
+		iterated++
+		return nil
+	}))
+	if iterated != 2 {
+		t.Errorf("iterated %d records, expected 2", iterated)
+	}
+
+	cnt, err = engine.Where("name <> ?", "").Update(&ScopeDoc{Name: "x"})
+	assertNoErr(t, err)
+	if cnt != 2 {
+		t.Errorf("updated %d records, expected 2", cnt)
+	}
+	cnt, err = engine.Where("name = ?", "x").Delete(new(ScopeDoc))
+	assertNoErr(t, err)
+	if cnt != 2 {
+		t.Errorf("deleted %d records, expected 2", cnt)
+	}
+
+	has, err = engine.WithoutScope().Where("name = ?", "b").Get(&doc)
+	assertNoErr(t, err)
+	if !has {
+		t.Error("the record out of the scope is changed")
+	}
+}
@@ -45,6 +45,18 @@ type Session struct {
 
 	stmtCache   map[uint32]*core.Stmt //key: hash.Hash32 of (queryStr, len(queryStr))
 	cascadeDeep int
+	cascades    *cascadeBatch // the cascade fields waiting for batch loading
+	cascading   map[interface{}]bool // the beans being saved or deleted with their associations
+
+	tenantId interface{}            // the tenant bound by Tenant
+	values   map[string]interface{} // the values bound by SetValue
+
+	// snapshots of the beans loaded when TrackChanges
+	trackChanges bool
+	snapshots    map[interface{}]map[string]interface{}
+
+	// the loaded records when UseIdentityMap, by table name and primary key
+	identities map[string]map[string]interface{}
 }
 
 // Method Init reset the session as the init status.
@@ -78,6 +90,12 @@ func (session *Session) Close() {
 		}
 		session.Tx = nil
 		session.stmtCache = nil
+		session.tenantId = nil
+		session.values = nil
+		session.trackChanges = false
+		session.snapshots = nil
+		session.identities = nil
+		session.cascading = nil
 		session.Init()
 		session.db = nil
 	}
@@ -86,6 +104,7 @@ func (session *Session) Close() {
 func (session *Session) resetStatement() {
 	if session.AutoResetStatement {
 		session.Statement.Init()
+		session.Statement.tenantId = session.tenantId
 	}
 }
 
@@ -223,6 +242,13 @@ func (session *Session) Omit(columns ...string) *Session {
 	return session
 }
 
+// Returning reads the columns back into the bean after Insert and Update,
+// all the columns will be read back when no column is given.
+func (session *Session) Returning(columns ...string) *Session {
+	session.Statement.Returning(columns...)
+	return session
+}
+
 // Set null when column is zero-value and nullable for update
 func (session *Session) Nullable(columns ...string) *Session {
 	session.Statement.Nullable(columns...)
@@ -415,19 +441,10 @@ func (session *Session) scanMapIntoStruct(obj interface{}, objMap map[string][]b
 			continue
 		}
 
-		fieldName := col.FieldName
-		fieldPath := strings.Split(fieldName, ".")
-		var fieldValue reflect.Value
-		if len(fieldPath) > 2 {
-			session.Engine.LogError("Unsupported mutliderive", fieldName)
+		fieldValue, err := colValueOfV(col, &dataStruct)
+		if err != nil {
+			session.Engine.LogError(err)
 			continue
-		} else if len(fieldPath) == 2 {
-			parentField := dataStruct.FieldByName(fieldPath[0])
-			if parentField.IsValid() {
-				fieldValue = parentField.FieldByName(fieldPath[1])
-			}
-		} else {
-			fieldValue = dataStruct.FieldByName(fieldName)
 		}
 		if !fieldValue.IsValid() || !fieldValue.CanSet() {
 			session.Engine.LogWarn("table %v's column %v is not valid or cannot set",
@@ -435,7 +452,7 @@ func (session *Session) scanMapIntoStruct(obj interface{}, objMap map[string][]b
 			continue
 		}
 
-		err := session.bytes2Value(col, &fieldValue, data)
+		err = session.bytes2Value(col, fieldValue, data)
 		if err != nil {
 			return err
 		}
@@ -488,6 +505,7 @@ func (session *Session) Exec(sqlStr string, args ...interface{}) (sql.Result, er
 		defer session.Close()
 	}
 
+	session.clearIdentities("")
 	return session.exec(sqlStr, args...)
 }
 
@@ -514,8 +532,7 @@ func (session *Session) CreateIndexes(bean interface{}) error {
 
 	sqls := session.Statement.genIndexSQL()
 	for _, sqlStr := range sqls {
-		_, err := session.exec(sqlStr)
-		if err != nil {
+		if err := session.execIndexSQL(sqlStr); err != nil {
 			return err
 		}
 	}
@@ -533,8 +550,7 @@ func (session *Session) CreateUniques(bean interface{}) error {
 
 	sqls := session.Statement.genUniqueSQL()
 	for _, sqlStr := range sqls {
-		_, err := session.exec(sqlStr)
-		if err != nil {
+		if err := session.execIndexSQL(sqlStr); err != nil {
 			return err
 		}
 	}
@@ -542,10 +558,27 @@ func (session *Session) CreateUniques(bean interface{}) error {
 }
 
 func (session *Session) createOneTable() error {
+	// the defaults of the columns may use the sequences
+	if err := session.createSequences(); err != nil {
+		return err
+	}
 	sqlStr := session.Statement.genCreateTableSQL()
 	session.Engine.LogDebug("create table sql: [", sqlStr, "]")
 	_, err := session.exec(sqlStr)
-	return err
+	if err != nil {
+		return err
+	}
+	for _, sqlStr := range session.Statement.genIdentitySQLs() {
+		if _, err = session.exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	for _, sqlStr := range session.Statement.genCommentSQLs() {
+		if _, err = session.exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // to be deleted
@@ -659,7 +692,7 @@ func (session *Session) cacheGet(bean interface{}, sqlStr string, args ...interf
 	}
 
 	cacher := session.Engine.getCacher2(session.Statement.RefTable)
-	tableName := session.Statement.TableName()
+	tableName := session.Statement.cacheTableName()
 	session.Engine.LogDebug("[cacheGet] find sql:", newsql, args)
 	ids, err := core.GetCacheSql(cacher, tableName, newsql, args)
 	table := session.Statement.RefTable
@@ -682,16 +715,9 @@ func (session *Session) cacheGet(bean interface{}, sqlStr string, args ...interf
 
 		var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
 		for i, col := range table.PKColumns() {
-			if col.SQLType.IsText() {
-				pk[i] = res[i]
-			} else if col.SQLType.IsNumeric() {
-				n, err := strconv.ParseInt(res[i], 10, 64)
-				if err != nil {
-					return false, err
-				}
-				pk[i] = n
-			} else {
-				return false, errors.New("unsupported")
+			pk[i], err = session.Engine.pkOfStr(table, col, res[i])
+			if err != nil {
+				return false, err
 			}
 		}
 
@@ -761,7 +787,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 
 	table := session.Statement.RefTable
 	cacher := session.Engine.getCacher2(table)
-	ids, err := core.GetCacheSql(cacher, session.Statement.TableName(), newsql, args)
+	ids, err := core.GetCacheSql(cacher, session.Statement.cacheTableName(), newsql, args)
 	if err != nil {
 		rows, err := session.DB().Query(newsql, args...)
 		if err != nil {
@@ -785,23 +811,16 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 
 			var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
 			for i, col := range table.PKColumns() {
-				if col.SQLType.IsNumeric() {
-					n, err := strconv.ParseInt(res[i], 10, 64)
-					if err != nil {
-						return err
-					}
-					pk[i] = n
-				} else if col.SQLType.IsText() {
-					pk[i] = res[i]
-				} else {
-					return errors.New("not supported")
+				pk[i], err = session.Engine.pkOfStr(table, col, res[i])
+				if err != nil {
+					return err
 				}
 			}
 
 			ids = append(ids, pk)
 		}
 
-		tableName := session.Statement.TableName()
+		tableName := session.Statement.cacheTableName()
 
 		session.Engine.LogDebug("[cacheFind] cache sql:", ids, tableName, newsql, args)
 		err = core.PutCacheSql(cacher, ids, tableName, newsql, args)
@@ -817,7 +836,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 	ididxes := make(map[string]int)
 	var ides []core.PK = make([]core.PK, 0)
 	var temps []interface{} = make([]interface{}, len(ids))
-	tableName := session.Statement.TableName()
+	tableName := session.Statement.cacheTableName()
 	for idx, id := range ids {
 		sid, err := id.ToString()
 		if err != nil {
@@ -985,11 +1004,25 @@ func (session *Session) doPrepare(sqlStr string) (stmt *core.Stmt, err error) {
 
 // get retrieve one record from database, bean's non-empty fields
 // will be as conditions
-func (session *Session) Get(bean interface{}) (bool, error) {
+func (session *Session) Get(bean interface{}) (has bool, err error) {
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
 	}
+	if session.trackChanges {
+		defer func() {
+			if has && err == nil {
+				session.snapshot(bean)
+			}
+		}()
+	}
+	if preloads := session.Statement.preloads; len(preloads) > 0 {
+		defer func() {
+			if has && err == nil {
+				err = session.preload([]reflect.Value{rValue(bean)}, preloads)
+			}
+		}()
+	}
 
 	session.Statement.Limit(1)
 	var sqlStr string
@@ -998,9 +1031,24 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 	if session.Statement.RefTable == nil {
 		session.Statement.RefTable = session.Engine.TableInfo(bean)
 	}
+	session.useBeanTable(bean)
+
+	if session.useIdentities() {
+		if session.getIdentity(bean) {
+			return true, nil
+		}
+		tableName := session.Statement.TableName()
+		defer func() {
+			if has && err == nil {
+				session.putIdentity(tableName, bean)
+			}
+		}()
+	}
 
 	if session.Statement.RawSQL == "" {
-		sqlStr, args = session.Statement.genGetSql(bean)
+		if sqlStr, args, err = session.Statement.genGetSql(bean); err != nil {
+			return false, err
+		}
 	} else {
 		sqlStr = session.Statement.RawSQL
 		args = session.Statement.RawParams
@@ -1009,7 +1057,7 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 	if session.Statement.JoinStr == "" {
 		if cacher := session.Engine.getCacher2(session.Statement.RefTable); cacher != nil &&
 			session.Statement.UseCache &&
-			!session.Statement.unscoped {
+			!session.Statement.unscoped && !session.Statement.onlyDeleted {
 			has, err := session.cacheGet(bean, sqlStr, args...)
 			if err != ErrCacheFailed {
 				return has, err
@@ -1018,7 +1066,6 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 	}
 
 	var rawRows *core.Rows
-	var err error
 	session.queryPreprocess(&sqlStr, args...)
 	if session.IsAutoCommit {
 		stmt, errPrepare := session.doPrepare(sqlStr)
@@ -1056,7 +1103,10 @@ func (session *Session) Count(bean interface{}) (int64, error) {
 	var sqlStr string
 	var args []interface{}
 	if session.Statement.RawSQL == "" {
-		sqlStr, args = session.Statement.genCountSql(bean)
+		var err error
+		if sqlStr, args, err = session.Statement.genCountSql(bean); err != nil {
+			return 0, err
+		}
 	} else {
 		sqlStr = session.Statement.RawSQL
 		args = session.Statement.RawParams
@@ -1151,11 +1201,36 @@ func Atot(s string, tp reflect.Type) (interface{}, error) {
 // Find retrieve records from table, condiBeans's non-empty fields
 // are conditions. beans could be []Struct, []*Struct, map[int64]Struct
 // map[int64]*Struct
-func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{}) error {
+func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{}) (err error) {
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
 	}
+	if session.Statement.lastError != nil {
+		return session.Statement.lastError
+	}
+	if session.trackChanges {
+		defer func() {
+			if err == nil {
+				session.snapshotSlice(rowsSlicePtr)
+			}
+		}()
+	}
+	if preloads := session.Statement.preloads; len(preloads) > 0 {
+		defer func() {
+			if err == nil {
+				err = session.preloadSlice(rowsSlicePtr, preloads)
+			}
+		}()
+	}
+	if session.useIdentities() {
+		// runs before the statement is reset
+		defer func() {
+			if err == nil {
+				session.putIdentities(session.Statement.TableName(), rowsSlicePtr)
+			}
+		}()
+	}
 
 	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
 	if sliceValue.Kind() != reflect.Slice && sliceValue.Kind() != reflect.Map {
@@ -1185,20 +1260,21 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 
 	if len(condiBean) > 0 {
 		var addedTableName = (len(session.Statement.JoinStr) > 0)
-		colNames, args := buildConditions(session.Engine, table, condiBean[0], true, true,
+		colNames, args, err := buildConditions(session.Engine, table, condiBean[0], true, true,
 			false, true, session.Statement.allUseBool, session.Statement.useAllCols,
-			session.Statement.unscoped, session.Statement.mustColumnMap, 
-			session.Statement.TableName(), addedTableName)
+			session.Statement.unscoped, session.Statement.onlyDeleted, session.Statement.mustColumnMap, 
+			session.Statement.refName(), addedTableName)
+		if err != nil {
+			return err
+		}
 		session.Statement.ConditionStr = strings.Join(colNames, " AND ")
 		session.Statement.BeanArgs = args
 	} else {
 		// !oinume! Add "<col> IS NULL" to WHERE whatever condiBean is given.
 		// See https://github.com/go-xorm/xorm/issues/179
-		if col := table.DeletedColumn(); col != nil && !session.Statement.unscoped { // tag "deleted" is enabled
-			session.Statement.ConditionStr = fmt.Sprintf("(%v IS NULL or %v = '0001-01-01 00:00:00') ",
-				session.Engine.Quote(col.Name), session.Engine.Quote(col.Name))
-		}
+		session.Statement.ConditionStr = session.Statement.softDeleteFilter()
 	}
+	session.Statement.attachScopes()
 
 	var sqlStr string
 	var args []interface{}
@@ -1210,7 +1286,7 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 			if session.Statement.JoinStr == "" {
 				if columnStr == "" {
 					if session.Statement.GroupByStr != "" {
-						columnStr = session.Statement.Engine.Quote(strings.Replace(session.Statement.GroupByStr, ",", session.Engine.Quote(","), -1))
+						columnStr = session.Statement.GroupByStr
 					} else {
 						columnStr = session.Statement.genColumnStr()
 					}
@@ -1218,7 +1294,7 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 			} else {
 				if columnStr == "" {
 					if session.Statement.GroupByStr != "" {
-						columnStr = session.Statement.Engine.Quote(strings.Replace(session.Statement.GroupByStr, ",", session.Engine.Quote(","), -1))
+						columnStr = session.Statement.GroupByStr
 					} else {
 						columnStr = "*"
 					}
@@ -1240,12 +1316,11 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 		args = session.Statement.RawParams
 	}
 
-	var err error
 	if session.Statement.JoinStr == "" {
 		if cacher := session.Engine.getCacher2(table); cacher != nil &&
 			session.Statement.UseCache &&
 			!session.Statement.IsDistinct &&
-			!session.Statement.unscoped {
+			!session.Statement.unscoped && !session.Statement.onlyDeleted {
 			err = session.cacheFind(sliceElementType, sqlStr, rowsSlicePtr, args...)
 			if err != ErrCacheFailed {
 				return err
@@ -1523,10 +1598,8 @@ func (session *Session) addIndex(tableName, idxName string) error {
 		defer session.Close()
 	}
 	index := session.Statement.RefTable.Indexes[idxName]
-	sqlStr := session.Engine.dialect.CreateIndexSql(tableName, index)
-
-	_, err := session.exec(sqlStr)
-	return err
+	sqlStr := session.Engine.indexSQL(tableName, session.Statement.RefTable, index)
+	return session.execIndexSQL(sqlStr)
 }
 
 func (session *Session) addUnique(tableName, uqeName string) error {
@@ -1535,9 +1608,8 @@ func (session *Session) addUnique(tableName, uqeName string) error {
 		defer session.Close()
 	}
 	index := session.Statement.RefTable.Indexes[uqeName]
-	sqlStr := session.Engine.dialect.CreateIndexSql(tableName, index)
-	_, err := session.exec(sqlStr)
-	return err
+	sqlStr := session.Engine.indexSQL(tableName, session.Statement.RefTable, index)
+	return session.execIndexSQL(sqlStr)
 }
 
 // To be deleted
@@ -1566,7 +1638,7 @@ func (session *Session) getField(dataStruct *reflect.Value, key string, table *c
 		return nil
 	}
 
-	fieldValue, err := col.ValueOfV(dataStruct)
+	fieldValue, err := colValueOfV(col, dataStruct)
 	if err != nil {
 		session.Engine.LogError(err)
 		return nil
@@ -1586,6 +1658,18 @@ func (session *Session) rows2Beans(rows *core.Rows, fields []string, fieldsCount
 	table *core.Table, newElemFunc func() reflect.Value,
 	sliceValueSetFunc func(*reflect.Value)) error {
 
+	// the cascade fields are loaded by batch after all rows are read, the
+	// beans are kept until then since the slice may hold copies
+	var cascades *cascadeBatch
+	var newValues []reflect.Value
+	if session.Statement.UseCascade && session.cascades == nil {
+		cascades = newCascadeBatch()
+		session.cascades = cascades
+		defer func() {
+			session.cascades = nil
+		}()
+	}
+
 	for rows.Next() {
 		var newValue reflect.Value = newElemFunc()
 		bean := newValue.Interface()
@@ -1594,8 +1678,22 @@ func (session *Session) rows2Beans(rows *core.Rows, fields []string, fieldsCount
 		if err != nil {
 			return err
 		}
-		sliceValueSetFunc(&newValue)
+		if cascades == nil {
+			sliceValueSetFunc(&newValue)
+		} else {
+			newValues = append(newValues, newValue)
+		}
+	}
+	if cascades == nil {
+		return nil
+	}
 
+	session.cascades = nil
+	if err := session.loadCascades(cascades); err != nil {
+		return err
+	}
+	for i := range newValues {
+		sliceValueSetFunc(&newValues[i])
 	}
 	return nil
 }
@@ -1645,6 +1743,17 @@ func (session *Session) _row2Bean(rows *core.Rows, fields []string, fieldsCount
 				continue
 			}
 
+			if conv := session.Engine.converterOf(table, table.GetColumnIdx(key, idx), fieldValue.Type()); conv != nil {
+				if data, err := value2Bytes(&rawValue); err == nil {
+					if err = conv.fromDB(fieldValue, data); err != nil {
+						return err
+					}
+				} else {
+					session.Engine.LogError(err)
+				}
+				continue
+			}
+
 			if fieldValue.CanAddr() {
 				if structConvert, ok := fieldValue.Addr().Interface().(core.Conversion); ok {
 					if data, err := value2Bytes(&rawValue); err == nil {
@@ -1668,6 +1777,23 @@ func (session *Session) _row2Bean(rows *core.Rows, fields []string, fieldsCount
 				continue
 			}
 
+			if col := table.GetColumnIdx(key, idx); col != nil && col.SQLType.Name == core.Set {
+				if data, err := value2Bytes(&rawValue); err == nil && setDecode(col, fieldValue, data) {
+					continue
+				}
+			}
+
+			if col := table.GetColumnIdx(key, idx); col != nil && isPgNative(col.SQLType) {
+				if data, err := value2Bytes(&rawValue); err == nil {
+					if ok, err := pgDecode(col, fieldValue, data); ok {
+						if err != nil {
+							return err
+						}
+						continue
+					}
+				}
+			}
+
 			rawValueType := reflect.TypeOf(rawValue.Interface())
 			vv := reflect.ValueOf(rawValue.Interface())
 
@@ -1793,19 +1919,9 @@ func (session *Session) _row2Bean(rows *core.Rows, fields []string, fieldsCount
 							// !nashtsai! TODO for hasOne relationship, it's preferred to use join query for eager fetch
 							// however, also need to consider adding a 'lazy' attribute to xorm tag which allow hasOne
 							// property to be fetched lazily
-							structInter := reflect.New(fieldValue.Type())
-							newsession := session.Engine.NewSession()
-							defer newsession.Close()
-							has, err := newsession.Id(pk).NoCascade().Get(structInter.Interface())
-							if err != nil {
+							if err := session.cascade(*fieldValue, pk); err != nil {
 								return err
 							}
-							if has {
-								v := structInter.Elem().Interface()
-								fieldValue.Set(reflect.ValueOf(v))
-							} else {
-								return errors.New("cascade obj is not exist!")
-							}
 						}
 					} else {
 						session.Engine.LogError("unsupported struct type in Scan: ", fieldValue.Type().String())
@@ -2029,7 +2145,19 @@ func (session *Session) Insert(beans ...interface{}) (int64, error) {
 		sliceValue := reflect.Indirect(reflect.ValueOf(bean))
 		if sliceValue.Kind() == reflect.Slice {
 			size := sliceValue.Len()
-			if size > 0 {
+			if size > 0 && session.sliceCascades(sliceValue) {
+				for i := 0; i < size; i++ {
+					elem := sliceValue.Index(i)
+					if elem.Kind() != reflect.Ptr {
+						elem = elem.Addr()
+					}
+					cnt, err := session.insertCascade(elem.Interface(), session.cascadeAssocs(elem.Interface(), false))
+					if err != nil {
+						return affected, err
+					}
+					affected += cnt
+				}
+			} else if size > 0 {
 				if session.Engine.SupportInsertMany() {
 					cnt, err := session.innerInsertMulti(bean)
 					if err != nil {
@@ -2046,6 +2174,12 @@ func (session *Session) Insert(beans ...interface{}) (int64, error) {
 					}
 				}
 			}
+		} else if assocs := session.cascadeAssocs(bean, false); len(assocs) > 0 {
+			cnt, err := session.insertCascade(bean, assocs)
+			if err != nil {
+				return affected, err
+			}
+			affected += cnt
 		} else {
 			cnt, err := session.innerInsert(bean)
 			if err != nil {
@@ -2064,12 +2198,28 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 		return 0, errors.New("needs a pointer to a slice")
 	}
 
+	// the rows are inserted into their own tables
+	if groups := session.groupByTable(sliceValue); groups != nil {
+		var affected int64
+		before, after := session.beforeClosures, session.afterClosures
+		for _, group := range groups {
+			session.beforeClosures, session.afterClosures = before, after
+			cnt, err := session.innerInsertMulti(group.Interface())
+			if err != nil {
+				return affected, err
+			}
+			affected += cnt
+		}
+		return affected, nil
+	}
+
 	bean := sliceValue.Index(0).Interface()
 	elementValue := rValue(bean)
 	//sliceElementType := elementValue.Type()
 
 	table := session.Engine.autoMapType(elementValue)
 	session.Statement.RefTable = table
+	session.useBeanTable(bean)
 
 	size := sliceValue.Len()
 
@@ -2078,7 +2228,23 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 	var args = make([]interface{}, 0)
 	cols := make([]*core.Column, 0)
 
+	elemPtrs := make([]interface{}, size)
+	for i := 0; i < size; i++ {
+		elemPtr := sliceValue.Index(i)
+		if elemPtr.Kind() != reflect.Ptr {
+			elemPtr = elemPtr.Addr()
+		}
+		elemPtrs[i] = elemPtr.Interface()
+	}
+	if err := session.genPKs(table, elemPtrs...); err != nil {
+		return 0, err
+	}
+
 	for i := 0; i < size; i++ {
+		if err := session.fillTenant(table, elemPtrs[i]); err != nil {
+			return 0, err
+		}
+
 		elemValue := sliceValue.Index(i).Interface()
 		colPlaces := make([]string, 0)
 
@@ -2095,7 +2261,11 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 
 		if i == 0 {
 			for _, col := range table.Columns() {
-				fieldValue := reflect.Indirect(reflect.ValueOf(elemValue)).FieldByName(col.FieldName)
+				fieldValuePtr, err := colValueOf(col, elemValue)
+				if err != nil {
+					return 0, err
+				}
+				fieldValue := *fieldValuePtr
 				if col.IsAutoIncrement && fieldValue.Int() == 0 {
 					continue
 				}
@@ -2138,7 +2308,11 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 			}
 		} else {
 			for _, col := range cols {
-				fieldValue := reflect.Indirect(reflect.ValueOf(elemValue)).FieldByName(col.FieldName)
+				fieldValuePtr, err := colValueOf(col, elemValue)
+				if err != nil {
+					return 0, err
+				}
+				fieldValue := *fieldValuePtr
 				if col.IsAutoIncrement && fieldValue.Int() == 0 {
 					continue
 				}
@@ -2182,13 +2356,9 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 	}
 	cleanupProcessorsClosures(&session.beforeClosures)
 
-	statement := fmt.Sprintf("INSERT INTO %v%v%v (%v%v%v) VALUES (%v)",
-		session.Engine.QuoteStr(),
-		session.Statement.TableName(),
-		session.Engine.QuoteStr(),
-		session.Engine.QuoteStr(),
-		strings.Join(colNames, session.Engine.QuoteStr()+", "+session.Engine.QuoteStr()),
-		session.Engine.QuoteStr(),
+	statement := fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v)",
+		session.Engine.Quote(session.Statement.TableName()),
+		session.Engine.quoteColumns(colNames...),
 		strings.Join(colMultiPlaces, "),("))
 
 	res, err := session.exec(statement, args...)
@@ -2197,7 +2367,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 	}
 
 	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
-		session.cacheInsert(session.Statement.TableName())
+		session.cacheInsert(session.Statement.cacheTableName())
 	}
 
 	lenAfterClosures := len(session.afterClosures)
@@ -2317,6 +2487,10 @@ func (session *Session) byte2Time(col *core.Column, data []byte) (outTime time.T
 
 // convert a db data([]byte) to a field value
 func (session *Session) bytes2Value(col *core.Column, fieldValue *reflect.Value, data []byte) error {
+	if conv := session.Engine.converterOf(session.Statement.RefTable, col, fieldValue.Type()); conv != nil {
+		return conv.fromDB(fieldValue, data)
+	}
+
 	if structConvert, ok := fieldValue.Addr().Interface().(core.Conversion); ok {
 		return structConvert.FromDB(data)
 	}
@@ -2325,6 +2499,14 @@ func (session *Session) bytes2Value(col *core.Column, fieldValue *reflect.Value,
 		return structConvert.FromDB(data)
 	}
 
+	if ok, err := pgDecode(col, fieldValue, data); ok {
+		return err
+	}
+
+	if setDecode(col, fieldValue, data) {
+		return nil
+	}
+
 	var v interface{}
 	key := col.Name
 	fieldType := fieldValue.Type()
@@ -2502,19 +2684,9 @@ func (session *Session) bytes2Value(col *core.Column, fieldValue *reflect.Value,
 					// !nashtsai! TODO for hasOne relationship, it's preferred to use join query for eager fetch
 					// however, also need to consider adding a 'lazy' attribute to xorm tag which allow hasOne
 					// property to be fetched lazily
-					structInter := reflect.New(fieldValue.Type())
-					newsession := session.Engine.NewSession()
-					defer newsession.Close()
-					has, err := newsession.Id(pk).NoCascade().Get(structInter.Interface())
-					if err != nil {
+					if err := session.cascade(*fieldValue, pk); err != nil {
 						return err
 					}
-					if has {
-						v = structInter.Elem().Interface()
-						fieldValue.Set(reflect.ValueOf(v))
-					} else {
-						return errors.New("cascade obj is not exist!")
-					}
 				}
 			} else {
 				return fmt.Errorf("unsupported struct type in Scan: %s", fieldValue.Type().String())
@@ -2850,23 +3022,15 @@ func (session *Session) bytes2Value(col *core.Column, fieldValue *reflect.Value,
 							// !nashtsai! TODO for hasOne relationship, it's preferred to use join query for eager fetch
 							// however, also need to consider adding a 'lazy' attribute to xorm tag which allow hasOne
 							// property to be fetched lazily
-							newsession := session.Engine.NewSession()
-							defer newsession.Close()
-							has, err := newsession.Id(pk).NoCascade().Get(structInter.Interface())
-							if err != nil {
+							if err := session.cascade(*fieldValue, pk); err != nil {
 								return err
 							}
-							if has {
-								v = structInter.Interface()
-								fieldValue.Set(reflect.ValueOf(v))
-							} else {
-								return errors.New("cascade obj is not exist!")
-							}
 						}
 					}
 				} else {
 					return fmt.Errorf("unsupported struct type in Scan: %s", fieldValue.Type().String())
 				}
+				return nil
 			}
 			return fmt.Errorf("unsupported type in Scan: %s", reflect.TypeOf(v).String())
 		}
@@ -2879,6 +3043,10 @@ func (session *Session) bytes2Value(col *core.Column, fieldValue *reflect.Value,
 
 // convert a field value of a struct to interface for put into db
 func (session *Session) value2Interface(col *core.Column, fieldValue reflect.Value) (interface{}, error) {
+	if conv := session.Engine.converterOf(session.Statement.RefTable, col, fieldValue.Type()); conv != nil {
+		return conv.toDB(fieldValue)
+	}
+
 	if fieldValue.CanAddr() {
 		if fieldConvert, ok := fieldValue.Addr().Interface().(core.Conversion); ok {
 			data, err := fieldConvert.ToDB()
@@ -2899,6 +3067,14 @@ func (session *Session) value2Interface(col *core.Column, fieldValue reflect.Val
 		}
 	}
 
+	if arg, ok, err := pgEncode(col, fieldValue); ok {
+		return arg, err
+	}
+
+	if arg, ok := optionArg(col, fieldValue); ok {
+		return arg, checkOption(col, arg)
+	}
+
 	fieldType := fieldValue.Type()
 	k := fieldType.Kind()
 	if k == reflect.Ptr {
@@ -2987,6 +3163,20 @@ func (session *Session) value2Interface(col *core.Column, fieldValue reflect.Val
 func (session *Session) innerInsert(bean interface{}) (int64, error) {
 	table := session.Engine.TableInfo(bean)
 	session.Statement.RefTable = table
+	session.useBeanTable(bean)
+
+	// generated primary keys are filled before the processors see the bean
+	if err := session.genPKs(table, bean); err != nil {
+		return 0, err
+	}
+	if err := session.fillTenant(table, bean); err != nil {
+		return 0, err
+	}
+
+	// the timestamp version is inserted as the bean's value
+	if table.Version != "" && session.Statement.checkVersion && isTimeVersion(table.VersionColumn()) {
+		session.initVersion(table.VersionColumn(), bean)
+	}
 
 	// handle BeforeInsertProcessor
 	for _, closure := range session.beforeClosures {
@@ -3028,13 +3218,16 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		colPlaces = colPlaces[0 : len(colPlaces)-2]
 	}
 
-	sqlStr := fmt.Sprintf("INSERT INTO %v%v%v (%v%v%v) VALUES (%v)",
-		session.Engine.QuoteStr(),
-		session.Statement.TableName(),
-		session.Engine.QuoteStr(),
-		session.Engine.QuoteStr(),
-		strings.Join(colNames, session.Engine.Quote(", ")),
-		session.Engine.QuoteStr(),
+	returnCols, err := session.Statement.returningColumns()
+	if err != nil {
+		return 0, err
+	}
+	outputStr, returningStr := session.Statement.genReturning(returnCols)
+
+	sqlStr := fmt.Sprintf("INSERT INTO %v (%v)%v VALUES (%v)",
+		session.Engine.Quote(session.Statement.TableName()),
+		session.Engine.quoteColumns(colNames...),
+		outputStr,
 		colPlaces)
 
 	handleAfterInsertProcessorFunc := func(bean interface{}) {
@@ -3066,125 +3259,105 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		cleanupProcessorsClosures(&session.afterClosures) // cleanup after used
 	}
 
-	// for postgres, many of them didn't implement lastInsertId, so we should
-	// implemented it ourself.
-
-	if session.Engine.DriverName() != core.POSTGRES || table.AutoIncrement == "" {
-		res, err := session.exec(sqlStr, args...)
-		if err != nil {
-			return 0, err
-		} else {
-			handleAfterInsertProcessorFunc(bean)
-		}
-
+	handleInsertedFunc := func() {
 		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
-			session.cacheInsert(session.Statement.TableName())
+			session.cacheInsert(session.Statement.cacheTableName())
 		}
 
-		if table.Version != "" && session.Statement.checkVersion {
-			verValue, err := table.VersionColumn().ValueOf(bean)
-			if err != nil {
-				session.Engine.LogError(err)
-			} else if verValue.IsValid() && verValue.CanSet() {
-				verValue.SetInt(1)
-			}
+		if table.Version != "" && session.Statement.checkVersion && !isTimeVersion(table.VersionColumn()) {
+			session.initVersion(table.VersionColumn(), bean)
 		}
 
-		if table.AutoIncrement == "" {
-			return res.RowsAffected()
-		}
+	}
 
-		var id int64 = 0
-		id, err = res.LastInsertId()
-		if err != nil || id <= 0 {
-			return res.RowsAffected()
+	// the generated columns are read back by RETURNING or OUTPUT clause
+	if outputStr != "" || returningStr != "" {
+		affected, err := session.execReturning(bean, returnCols, sqlStr+returningStr, args...)
+		if err != nil {
+			return 0, err
 		}
+		handleInsertedFunc()
+		handleAfterInsertProcessorFunc(bean)
+		return affected, nil
+	}
 
-		aiValue, err := table.AutoIncrColumn().ValueOf(bean)
+	// for postgres, many of them didn't implement lastInsertId, so we should
+	// implemented it ourself.
+
+	if session.Engine.DriverName() != core.POSTGRES || table.AutoIncrement == "" {
+		res, err := session.exec(sqlStr, args...)
 		if err != nil {
-			session.Engine.LogError(err)
+			return 0, err
 		}
 
-		if aiValue == nil || !aiValue.IsValid() /*|| aiValue.Int() != 0*/ || !aiValue.CanSet() {
-			return res.RowsAffected()
+		handleInsertedFunc()
+		if table.AutoIncrement != "" {
+			if id, err := res.LastInsertId(); err == nil && id > 0 {
+				session.setAutoIncr(table, bean, id)
+			}
 		}
 
-		var v interface{} = id
-		switch aiValue.Type().Kind() {
-		case reflect.Int32:
-			v = int32(id)
-		case reflect.Int:
-			v = int(id)
-		case reflect.Uint32:
-			v = uint32(id)
-		case reflect.Uint64:
-			v = uint64(id)
-		case reflect.Uint:
-			v = uint(id)
+		// the database has no RETURNING, read them back by primary keys
+		if returnCols != nil {
+			if err := session.refreshBean(bean, returnCols); err != nil {
+				return 0, err
+			}
 		}
-		aiValue.Set(reflect.ValueOf(v))
+		handleAfterInsertProcessorFunc(bean)
 
 		return res.RowsAffected()
 	} else {
 		//assert table.AutoIncrement != ""
 		sqlStr = sqlStr + " RETURNING " + session.Engine.Quote(table.AutoIncrement)
 		res, err := session.query(sqlStr, args...)
-
 		if err != nil {
 			return 0, err
-		} else {
-			handleAfterInsertProcessorFunc(bean)
-		}
-
-		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
-			session.cacheInsert(session.Statement.TableName())
-		}
-
-		if table.Version != "" && session.Statement.checkVersion {
-			verValue, err := table.VersionColumn().ValueOf(bean)
-			if err != nil {
-				session.Engine.LogError(err)
-			} else if verValue.IsValid() && verValue.CanSet() {
-				verValue.SetInt(1)
-			}
 		}
 
+		handleInsertedFunc()
 		if len(res) < 1 {
+			handleAfterInsertProcessorFunc(bean)
 			return 0, errors.New("insert no error but not returned id")
 		}
 
 		idByte := res[0][table.AutoIncrement]
 		id, err := strconv.ParseInt(string(idByte), 10, 64)
 		if err != nil {
+			handleAfterInsertProcessorFunc(bean)
 			return 1, err
 		}
+		session.setAutoIncr(table, bean, id)
+		handleAfterInsertProcessorFunc(bean)
 
-		aiValue, err := table.AutoIncrColumn().ValueOf(bean)
-		if err != nil {
-			session.Engine.LogError(err)
-		}
+		return 1, nil
+	}
+}
 
-		if aiValue == nil || !aiValue.IsValid() /*|| aiValue. != 0*/ || !aiValue.CanSet() {
-			return 1, nil
-		}
+// setAutoIncr sets the inserted id to the bean's autoincrement field
+func (session *Session) setAutoIncr(table *core.Table, bean interface{}, id int64) {
+	aiValue, err := colValueOf(table.AutoIncrColumn(), bean)
+	if err != nil {
+		session.Engine.LogError(err)
+	}
 
-		var v interface{} = id
-		switch aiValue.Type().Kind() {
-		case reflect.Int32:
-			v = int32(id)
-		case reflect.Int:
-			v = int(id)
-		case reflect.Uint32:
-			v = uint32(id)
-		case reflect.Uint64:
-			v = uint64(id)
-		case reflect.Uint:
-			v = uint(id)
-		}
-		aiValue.Set(reflect.ValueOf(v))
+	if aiValue == nil || !aiValue.IsValid() /*|| aiValue.Int() != 0*/ || !aiValue.CanSet() {
+		return
+	}
 
-		return 1, nil
+	var v interface{} = id
+	switch aiValue.Type().Kind() {
+	case reflect.Int32:
+		v = int32(id)
+	case reflect.Int:
+		v = int(id)
+	case reflect.Uint32:
+		v = uint32(id)
+	case reflect.Uint64:
+		v = uint64(id)
+	case reflect.Uint:
+		v = uint(id)
 	}
+	aiValue.Set(reflect.ValueOf(v))
 }
 
 // Method InsertOne insert only one struct into database as a record.
@@ -3199,6 +3372,68 @@ func (session *Session) InsertOne(bean interface{}) (int64, error) {
 	return session.innerInsert(bean)
 }
 
+// Method InsertSelect copies records into targetTable by "INSERT INTO ... SELECT ...".
+// targetTable could be a table name or a bean. subquery could be a raw SELECT
+// sql with its args, or a bean whose table is selected with the session's
+// conditions, joins, orders and limits. When cols is empty, all the columns
+// of targetTable are filled in order.
+func (session *Session) InsertSelect(targetTable interface{}, cols []string, subquery interface{}, args ...interface{}) (int64, error) {
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	var target *core.Table
+	var tableName string
+	if name, ok := targetTable.(string); ok {
+		tableName = name
+		target = session.Engine.tableByName(name)
+	} else {
+		target = session.Engine.TableInfo(targetTable)
+		tableName = target.Name
+	}
+
+	var selectSql string
+	var selectArgs []interface{}
+	if sqlStr, ok := subquery.(string); ok {
+		selectSql, selectArgs = sqlStr, args
+	} else {
+		if session.Statement.ColumnStr == "" && len(cols) > 0 {
+			session.Statement.Cols(cols...)
+		}
+		var err error
+		if selectSql, selectArgs, err = session.Statement.genGetSql(subquery); err != nil {
+			return 0, err
+		}
+	}
+
+	var colStr string
+	if len(cols) > 0 {
+		quoted := make([]string, 0, len(cols))
+		for _, col := range cols {
+			quoted = append(quoted, session.Engine.Quote(col))
+		}
+		colStr = " (" + strings.Join(quoted, ", ") + ")"
+	}
+
+	sqlStr := fmt.Sprintf("INSERT INTO %v%v %v", session.Engine.Quote(session.Engine.tbNameWithSchema(tableName)),
+		colStr, selectSql)
+	res, err := session.exec(sqlStr, selectArgs...)
+	if err != nil {
+		return 0, err
+	}
+
+	if target != nil {
+		if cacher := session.Engine.getCacher2(target); cacher != nil && session.Statement.UseCache {
+			// the ids are cached by the target table, of the bound tenant too
+			session.Statement.RefTable = target
+			session.Statement.AltTableName = tableName
+			session.cacheInsert(session.Statement.cacheTableName())
+		}
+	}
+	return res.RowsAffected()
+}
+
 func (statement *Statement) convertUpdateSql(sqlStr string) (string, string) {
 	if statement.RefTable == nil || len(statement.RefTable.PrimaryKeys) != 1 {
 		return "", ""
@@ -3282,7 +3517,7 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 	}
 	table := session.Statement.RefTable
 	cacher := session.Engine.getCacher2(table)
-	tableName := session.Statement.TableName()
+	tableName := session.Statement.cacheTableName()
 	session.Engine.LogDebug("[cacheUpdate] get cache sql", newsql, args[nStart:])
 	ids, err := core.GetCacheSql(cacher, tableName, newsql, args[nStart:])
 	if err != nil {
@@ -3301,16 +3536,9 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 			}
 			var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
 			for i, col := range table.PKColumns() {
-				if col.SQLType.IsNumeric() {
-					n, err := strconv.ParseInt(res[i], 10, 64)
-					if err != nil {
-						return err
-					}
-					pk[i] = n
-				} else if col.SQLType.IsText() {
-					pk[i] = res[i]
-				} else {
-					return errors.New("not supported")
+				pk[i], err = session.Engine.pkOfStr(table, col, res[i])
+				if err != nil {
+					return err
 				}
 			}
 
@@ -3352,13 +3580,15 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 				}
 
 				if col := table.GetColumn(colName); col != nil {
-					fieldValue, err := col.ValueOf(bean)
+					fieldValue, err := colValueOf(col, bean)
 					if err != nil {
 						session.Engine.LogError(err)
 					} else {
 						session.Engine.LogDebug("[cacheUpdate] set bean field", bean, colName, fieldValue.Interface())
 						if col.IsVersion && session.Statement.checkVersion {
-							fieldValue.SetInt(fieldValue.Int() + 1)
+							if !isTimeVersion(col) {
+								incrVersion(*fieldValue)
+							}
 						} else {
 							fieldValue.Set(reflect.ValueOf(args[idx]))
 						}
@@ -3389,6 +3619,12 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	if session.IsAutoClose {
 		defer session.Close()
 	}
+	if session.Statement.lastError != nil {
+		return 0, session.Statement.lastError
+	}
+	if assocs := session.cascadeAssocs(bean, false); len(assocs) > 0 {
+		return session.updateCascade(bean, assocs, condiBean...)
+	}
 
 	t := rType(bean)
 
@@ -3410,12 +3646,37 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	if t.Kind() == reflect.Struct {
 		table = session.Engine.TableInfo(bean)
 		session.Statement.RefTable = table
+		session.useBeanTable(bean)
 
-		if session.Statement.ColumnStr == "" {
-			colNames, args = buildUpdates(session.Engine, table, bean, false, false,
+		changes, tracked, err := session.Changes(bean)
+		if err != nil {
+			return 0, err
+		}
+		tracked = tracked && session.Statement.ColumnStr == "" && session.Statement.OmitStr == "" &&
+			!session.Statement.useAllCols
+
+		if tracked {
+			// a tracked bean updates the changed columns only
+			colNames, args, err = session.changedCols(table, bean, changes)
+			if err != nil {
+				return 0, err
+			}
+			if len(colNames) == 0 && len(session.Statement.getInc()) == 0 &&
+				len(session.Statement.getDec()) == 0 && len(session.Statement.getExpr()) == 0 {
+				return 0, nil
+			}
+			if session.Statement.WhereStr == "" && session.Statement.IdParam == nil &&
+				len(session.Statement.inColumns) == 0 && len(condiBean) == 0 {
+				session.Statement.Id(session.Engine.IdOf(bean))
+			}
+		} else if session.Statement.ColumnStr == "" {
+			colNames, args, err = buildUpdates(session.Engine, table, bean, false, false,
 				false, false, session.Statement.allUseBool, session.Statement.useAllCols,
 				session.Statement.mustColumnMap, session.Statement.nullableMap, 
 				session.Statement.columnMap, true)
+			if err != nil {
+				return 0, err
+			}
 		} else {
 			colNames, args, err = genCols(table, session, bean, true, true)
 			if err != nil {
@@ -3439,8 +3700,32 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		return 0, ErrParamsType
 	}
 
+	colNames, args, err = session.dropTenantUpdate(table, colNames, args)
+	if err != nil {
+		return 0, err
+	}
+	if err = session.checkUpdateOptions(table, colNames, args); err != nil {
+		return 0, err
+	}
+
+	// with joins, mysql and mssql need the updated columns be qualified
+	var joined = session.Statement.JoinStr != ""
+	var quoteCol = session.Engine.Quote
+	if joined {
+		dbType := session.Engine.dialect.DBType()
+		if dbType == core.MYSQL || dbType == core.MSSQL {
+			refName := session.Engine.Quote(session.Statement.refName()) + "."
+			for i, colName := range colNames {
+				colNames[i] = refName + colName
+			}
+			quoteCol = func(name string) string {
+				return refName + session.Engine.Quote(name)
+			}
+		}
+	}
+
 	if session.Statement.UseAutoTime && table.Updated != "" {
-		colNames = append(colNames, session.Engine.Quote(table.Updated)+" = ?")
+		colNames = append(colNames, quoteCol(table.Updated)+" = ?")
 		col := table.UpdatedColumn()
 		val, t := session.Engine.NowTime2(col.SQLType.Name)
 		args = append(args, val)
@@ -3455,28 +3740,35 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	//for update action to like "column = column + ?"
 	incColumns := session.Statement.getInc()
 	for _, v := range incColumns {
-		colNames = append(colNames, session.Engine.Quote(v.colName)+" = "+session.Engine.Quote(v.colName)+" + ?")
+		colNames = append(colNames, quoteCol(v.colName)+" = "+quoteCol(v.colName)+" + ?")
 		args = append(args, v.arg)
 	}
 	//for update action to like "column = column - ?"
 	decColumns := session.Statement.getDec()
 	for _, v := range decColumns {
-		colNames = append(colNames, session.Engine.Quote(v.colName)+" = "+session.Engine.Quote(v.colName)+" - ?")
+		colNames = append(colNames, quoteCol(v.colName)+" = "+quoteCol(v.colName)+" - ?")
 		args = append(args, v.arg)
 	}
 	//for update action to like "column = expression"
 	exprColumns := session.Statement.getExpr()
 	for _, v := range exprColumns {
-		colNames = append(colNames, session.Engine.Quote(v.colName)+" = "+v.expr)
+		colNames = append(colNames, quoteCol(v.colName)+" = "+v.expr)
 	}
 
 	var condiColNames []string
 	var condiArgs []interface{}
 
 	if len(condiBean) > 0 {
-		condiColNames, condiArgs = buildConditions(session.Engine, session.Statement.RefTable, condiBean[0], true, true,
+		condiColNames, condiArgs, err = buildConditions(session.Engine, session.Statement.RefTable, condiBean[0], true, true,
 			false, true, session.Statement.allUseBool, session.Statement.useAllCols,
-			session.Statement.unscoped, session.Statement.mustColumnMap, session.Statement.TableName(), false)
+			session.Statement.unscoped, session.Statement.onlyDeleted, session.Statement.mustColumnMap, session.Statement.refName(), joined)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if scopeConds, scopeArgs := session.Statement.scopeConds(); len(scopeConds) > 0 {
+		condiColNames = append(condiColNames, scopeConds...)
+		condiArgs = append(condiArgs, scopeArgs...)
 	}
 
 	var condition = ""
@@ -3501,79 +3793,124 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	var sqlStr, inSql string
 	var inArgs []interface{}
 	doIncVer := false
-	var verValue *reflect.Value
-	if table.Version != "" && session.Statement.checkVersion {
+	var incVer func(interface{})
+	if table.Version != "" && session.Statement.checkVersion && t.Kind() == reflect.Struct {
+		verName := session.Engine.Quote(table.Version)
+		if joined {
+			verName = session.Engine.Quote(session.Statement.refName()) + "." + verName
+		}
 		if condition != "" {
-			condition = fmt.Sprintf("WHERE (%v) %v %v = ?", condition, session.Engine.Dialect().AndStr(),
-				session.Engine.Quote(table.Version))
+			condition = fmt.Sprintf("(%v) %v %v = ?", condition, session.Engine.Dialect().AndStr(),
+				verName)
 		} else {
-			condition = fmt.Sprintf("WHERE %v = ?", session.Engine.Quote(table.Version))
-		}
-		inSql, inArgs = session.Statement.genInSql()
-		if len(inSql) > 0 {
-			if condition != "" {
-				condition += " " + session.Engine.Dialect().AndStr() + " " + inSql
-			} else {
-				condition = "WHERE " + inSql
-			}
+			condition = fmt.Sprintf("%v = ?", verName)
 		}
 
-		if st.LimitN > 0 {
-			condition = condition + fmt.Sprintf(" LIMIT %d", st.LimitN)
+		verCol := table.VersionColumn()
+		setStr, setArgs, after := session.versionSet(verCol, quoteCol)
+		colNames = append(colNames, setStr)
+		args = append(args, setArgs...)
+		incVer = after
+
+		verArg, _, err := session.versionCond(verCol, bean)
+		if err != nil {
+			return 0, err
 		}
+		condiArgs = append(condiArgs, verArg)
+		doIncVer = true
+	}
 
-		sqlStr = fmt.Sprintf("UPDATE %v SET %v, %v %v",
-			session.Engine.Quote(session.Statement.TableName()),
-			strings.Join(colNames, ", "),
-			session.Engine.Quote(table.Version)+" = "+session.Engine.Quote(table.Version)+" + 1",
-			condition)
+	inSql, inArgs = session.Statement.genInSql()
+	if len(inSql) > 0 {
+		if condition != "" {
+			condition += " " + session.Engine.Dialect().AndStr() + " " + inSql
+		} else {
+			condition = inSql
+		}
+	}
 
-		verValue, err = table.VersionColumn().ValueOf(bean)
+	var returnCols []*core.Column
+	if t.Kind() == reflect.Struct {
+		returnCols, err = session.Statement.returningColumns()
 		if err != nil {
 			return 0, err
 		}
+	}
+	outputStr, returningStr := session.Statement.genReturning(returnCols)
 
-		condiArgs = append(condiArgs, verValue.Interface())
-		doIncVer = true
+	if joined {
+		// LIMIT is not allowed by the multi-table UPDATE
+		sqlStr, err = session.Statement.genJoinUpdateSql(strings.Join(colNames, ", ")+outputStr, condition)
+		if err != nil {
+			return 0, err
+		}
 	} else {
 		if condition != "" {
 			condition = "WHERE " + condition
 		}
-		inSql, inArgs = session.Statement.genInSql()
-		if len(inSql) > 0 {
-			if condition != "" {
-				condition += " " + session.Engine.Dialect().AndStr() + " " + inSql
-			} else {
-				condition = "WHERE " + inSql
-			}
-		}
-
 		if st.LimitN > 0 {
 			condition = condition + fmt.Sprintf(" LIMIT %d", st.LimitN)
 		}
 
-		sqlStr = fmt.Sprintf("UPDATE %v SET %v %v",
+		sqlStr = fmt.Sprintf("UPDATE %v SET %v%v %v",
 			session.Engine.Quote(session.Statement.TableName()),
 			strings.Join(colNames, ", "),
+			outputStr,
 			condition)
 	}
 
 	args = append(args, st.Params...)
-	args = append(args, inArgs...)
 	args = append(args, condiArgs...)
+	args = append(args, inArgs...)
 
-	res, err := session.exec(sqlStr, args...)
-	if err != nil {
-		return 0, err
-	} else if doIncVer {
-		if verValue != nil && verValue.IsValid() && verValue.CanSet() {
-			verValue.SetInt(verValue.Int() + 1)
+	var affected int64
+	if outputStr != "" || returningStr != "" {
+		// the version is read back with the other columns
+		for _, col := range returnCols {
+			if col.IsVersion {
+				incVer = func(interface{}) {}
+			}
 		}
+		affected, err = session.execReturning(bean, returnCols, sqlStr+returningStr, args...)
+		if err != nil {
+			return 0, err
+		}
+		returnCols = nil
+	} else {
+		res, err := session.exec(sqlStr, args...)
+		if err != nil {
+			return 0, err
+		}
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if doIncVer {
+		if affected == 0 {
+			return 0, session.versionConflict(bean)
+		}
+		incVer(bean)
 	}
 
+	// the database has no RETURNING, read them back by primary keys
+	if returnCols != nil {
+		if err = session.refreshBean(bean, returnCols); err != nil {
+			return 0, err
+		}
+	}
+
+	if t.Kind() == reflect.Struct {
+		if _, ok := session.snapshots[bean]; ok {
+			session.snapshot(bean)
+		}
+	}
+	session.clearIdentities(session.Statement.TableName())
+
 	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
-		cacher.ClearIds(session.Statement.TableName())
-		cacher.ClearBeans(session.Statement.TableName())
+		cacher.ClearIds(session.Statement.cacheTableName())
+		cacher.ClearBeans(session.Statement.cacheTableName())
 	}
 
 	// handle after update processors
@@ -3605,7 +3942,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	cleanupProcessorsClosures(&session.afterClosures) // cleanup after used
 	// --
 
-	return res.RowsAffected()
+	return affected, nil
 }
 
 func (session *Session) cacheDelete(sqlStr string, args ...interface{}) error {
@@ -3624,7 +3961,7 @@ func (session *Session) cacheDelete(sqlStr string, args ...interface{}) error {
 	}
 
 	cacher := session.Engine.getCacher2(session.Statement.RefTable)
-	tableName := session.Statement.TableName()
+	tableName := session.Statement.cacheTableName()
 	ids, err := core.GetCacheSql(cacher, tableName, newsql, args)
 	if err != nil {
 		resultsSlice, err := session.query(newsql, args...)
@@ -3634,23 +3971,16 @@ func (session *Session) cacheDelete(sqlStr string, args ...interface{}) error {
 		ids = make([]core.PK, 0)
 		if len(resultsSlice) > 0 {
 			for _, data := range resultsSlice {
-				var id int64
 				var pk core.PK = make([]interface{}, 0)
 				for _, col := range session.Statement.RefTable.PKColumns() {
 					if v, ok := data[col.Name]; !ok {
 						return errors.New("no id")
 					} else {
-						if col.SQLType.IsText() {
-							pk = append(pk, string(v))
-						} else if col.SQLType.IsNumeric() {
-							id, err = strconv.ParseInt(string(v), 10, 64)
-							if err != nil {
-								return err
-							}
-							pk = append(pk, id)
-						} else {
-							return errors.New("not supported primary key type")
+						id, err := session.Engine.pkOfStr(session.Statement.RefTable, col, string(v))
+						if err != nil {
+							return err
 						}
+						pk = append(pk, id)
 					}
 				}
 				ids = append(ids, pk)
@@ -3680,6 +4010,12 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 	if session.IsAutoClose {
 		defer session.Close()
 	}
+	if session.Statement.lastError != nil {
+		return 0, session.Statement.lastError
+	}
+	if assocs := session.cascadeAssocs(bean, true); len(assocs) > 0 {
+		return session.deleteCascade(bean, assocs)
+	}
 
 	// handle before delete processors
 	for _, closure := range session.beforeClosures {
@@ -3694,10 +4030,24 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 
 	table := session.Engine.TableInfo(bean)
 	session.Statement.RefTable = table
-	colNames, args := buildConditions(session.Engine, table, bean, true, true,
-		false, true, session.Statement.allUseBool, session.Statement.useAllCols,
-		session.Statement.unscoped, session.Statement.mustColumnMap, 
-		session.Statement.TableName(), false)
+	session.useBeanTable(bean)
+	var joined = session.Statement.JoinStr != ""
+	var checkVersion = table.Version != "" && session.Statement.checkVersion
+	// ForceDelete removes the soft deleted records too unless OnlyDeleted
+	var unscoped = session.Statement.unscoped ||
+		(session.Statement.forceDelete && !session.Statement.onlyDeleted)
+	var colNames []string
+	var args []interface{}
+	if !session.Statement.idCondOnly {
+		var err error
+		colNames, args, err = buildConditions(session.Engine, table, bean, !checkVersion, true,
+			false, true, session.Statement.allUseBool, session.Statement.useAllCols,
+			unscoped, session.Statement.onlyDeleted, session.Statement.mustColumnMap,
+			session.Statement.refName(), joined)
+		if err != nil {
+			return 0, err
+		}
+	}
 
 	var condition = ""
 	var andStr = session.Engine.dialect.AndStr()
@@ -3722,12 +4072,44 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 	if len(condition) == 0 {
 		return 0, ErrNeedDeletedCond
 	}
+	if scopeConds, scopeArgs := session.Statement.scopeConds(); len(scopeConds) > 0 {
+		condition = fmt.Sprintf("(%v) %v %v", condition, andStr, strings.Join(scopeConds, " "+andStr+" "))
+		args = append(args, scopeArgs...)
+	}
+
+	// the version is checked only if the bean has one, i.e. it's read before
+	if checkVersion {
+		verArg, zero, err := session.versionCond(table.VersionColumn(), bean)
+		if err != nil {
+			return 0, err
+		}
+		if zero {
+			checkVersion = false
+		} else {
+			verName := session.Engine.Quote(table.Version)
+			if joined {
+				verName = session.Engine.Quote(session.Statement.refName()) + "." + verName
+			}
+			condition = fmt.Sprintf("(%v) %v %v = ?", condition, andStr, verName)
+			args = append(args, verArg)
+		}
+	}
 
 	sqlStr, sqlStrForCache := "", ""
 	argsForCache := make([]interface{}, 0, len(args)*2)
-	if session.Statement.unscoped || table.DeletedColumn() == nil { // tag "deleted" is disabled
-		sqlStr = fmt.Sprintf("DELETE FROM %v WHERE %v",
-			session.Engine.Quote(session.Statement.TableName()), condition)
+	var err error
+	var incVer func(interface{})
+	if session.Statement.unscoped || session.Statement.forceDelete ||
+		table.DeletedColumn() == nil { // tag "deleted" is disabled
+		if joined {
+			sqlStr, err = session.Statement.genJoinDeleteSql(condition)
+			if err != nil {
+				return 0, err
+			}
+		} else {
+			sqlStr = fmt.Sprintf("DELETE FROM %v WHERE %v",
+				session.Engine.Quote(session.Statement.TableName()), condition)
+		}
 
 		sqlStrForCache = sqlStr
 		copy(argsForCache, args)
@@ -3740,30 +4122,58 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 		argsForCache = append(session.Statement.Params, argsForCache...)
 
 		deletedColumn := table.DeletedColumn()
-		sqlStr = fmt.Sprintf("UPDATE %v SET %v = ? WHERE %v",
-			session.Engine.Quote(session.Statement.TableName()),
-			session.Engine.Quote(deletedColumn.Name),
-			condition)
+		var quoteCol = session.Engine.Quote
+		if joined {
+			dbType := session.Engine.dialect.DBType()
+			if dbType == core.MYSQL || dbType == core.MSSQL {
+				quoteCol = func(name string) string {
+					return session.Engine.Quote(session.Statement.refName()) + "." + session.Engine.Quote(name)
+				}
+			}
+		}
 
-		// !oinume! Insert NowTime to the head of session.Statement.Params
-		session.Statement.Params = append(session.Statement.Params, "")
-		paramsLen := len(session.Statement.Params)
-		copy(session.Statement.Params[1:paramsLen], session.Statement.Params[0:paramsLen-1])
+		val, after := session.Engine.deletedValue(deletedColumn)
+		setStr := quoteCol(deletedColumn.Name) + " = ?"
+		setArgs := []interface{}{val}
+		session.afterClosures = append(session.afterClosures, after)
 
-		val, t := session.Engine.NowTime2(deletedColumn.SQLType.Name)
-		session.Statement.Params[0] = val
+		if col := session.Engine.deletedByColumn(table); col != nil && session.Statement.deletedBy != nil {
+			setStr += ", " + quoteCol(col.Name) + " = ?"
+			setArgs = append(setArgs, session.Statement.deletedBy)
+		}
 
-		var colName = deletedColumn.Name
-		session.afterClosures = append(session.afterClosures, func(bean interface{}) {
-			col := table.GetColumn(colName)
-			setColumnTime(bean, col, t)
-		})
+		// the soft deleted record gets a new version as an update
+		if checkVersion {
+			verStr, verArgs, after := session.versionSet(table.VersionColumn(), quoteCol)
+			setStr += ", " + verStr
+			setArgs = append(setArgs, verArgs...)
+			incVer = after
+		}
+
+		if joined {
+			sqlStr, err = session.Statement.genJoinUpdateSql(setStr, condition)
+			if err != nil {
+				return 0, err
+			}
+		} else {
+			sqlStr = fmt.Sprintf("UPDATE %v SET %v WHERE %v",
+				session.Engine.Quote(session.Statement.TableName()),
+				setStr, condition)
+		}
+
+		// !oinume! Insert NowTime to the head of session.Statement.Params
+		session.Statement.Params = append(setArgs, session.Statement.Params...)
 	}
 
 	args = append(session.Statement.Params, args...)
 
 	if cacher := session.Engine.getCacher2(session.Statement.RefTable); cacher != nil && session.Statement.UseCache {
-		session.cacheDelete(sqlStrForCache, argsForCache...)
+		if joined {
+			cacher.ClearIds(session.Statement.cacheTableName())
+			cacher.ClearBeans(session.Statement.cacheTableName())
+		} else {
+			session.cacheDelete(sqlStrForCache, argsForCache...)
+		}
 	}
 
 	res, err := session.exec(sqlStr, args...)
@@ -3771,6 +4181,21 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 		return 0, err
 	}
 
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if checkVersion {
+		if affected == 0 {
+			return 0, session.versionConflict(bean)
+		}
+		if incVer != nil {
+			incVer(bean)
+		}
+	}
+	delete(session.snapshots, bean)
+	session.clearIdentities(session.Statement.TableName())
+
 	// handle after delete processors
 	if session.IsAutoCommit {
 		for _, closure := range session.afterClosures {
@@ -3798,7 +4223,7 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 	cleanupProcessorsClosures(&session.afterClosures)
 	// --
 
-	return res.RowsAffected()
+	return affected, nil
 }
 
 func (s *Session) Sync2(beans ...interface{}) error {
@@ -3811,8 +4236,9 @@ func (s *Session) Sync2(beans ...interface{}) error {
 
 	structTables := make([]*core.Table, 0)
 
-	for _, bean := range beans {
+	for _, bean := range engine.sortByDependency(beans) {
 		table := engine.TableInfo(bean)
+		tbName := engine.tbNameWithSchema(table.Name)
 		structTables = append(structTables, table)
 
 		var oriTable *core.Table
@@ -3822,6 +4248,12 @@ func (s *Session) Sync2(beans ...interface{}) error {
 				break
 			}
 		}
+		// DBMetas reads the tables of the engine's schema only
+		if oriTable == nil && strings.Contains(table.Name, ".") {
+			if oriTable, err = s.tableMeta(table.Name); err != nil {
+				return err
+			}
+		}
 
 		if oriTable == nil {
 			err = engine.StoreEngine(s.Statement.StoreEngine).CreateTable(bean)
@@ -3839,6 +4271,14 @@ func (s *Session) Sync2(beans ...interface{}) error {
 				return err
 			}
 		} else {
+			session := engine.NewSession()
+			session.Statement.RefTable = table
+			err = session.createSequences()
+			session.Close()
+			if err != nil {
+				return err
+			}
+
 			for _, col := range table.Columns() {
 				var oriCol *core.Column
 				for _, col2 := range oriTable.Columns() {
@@ -3852,14 +4292,20 @@ func (s *Session) Sync2(beans ...interface{}) error {
 					expectedType := engine.dialect.SqlType(col)
 					curType := engine.dialect.SqlType(oriCol)
 					if expectedType != curType {
-						if expectedType == core.Text &&
+						if columnOptions(col) != nil && (engine.dialect.DBType() == core.MYSQL ||
+							engine.dialect.DBType() == core.POSTGRES) {
+							// the options of the enum or set are changed
+							engine.LogInfof("Table %s column %s change type from %s to %s\n",
+								table.Name, col.Name, curType, expectedType)
+							_, err = engine.Exec(engine.dialect.ModifyColumnSql(tbName, col))
+						} else if expectedType == core.Text &&
 							strings.HasPrefix(curType, core.Varchar) {
 							// currently only support mysql & postgres
 							if engine.dialect.DBType() == core.MYSQL ||
 								engine.dialect.DBType() == core.POSTGRES {
 								engine.LogInfof("Table %s column %s change type from %s to %s\n",
 									table.Name, col.Name, curType, expectedType)
-								_, err = engine.Exec(engine.dialect.ModifyColumnSql(table.Name, col))
+								_, err = engine.Exec(engine.dialect.ModifyColumnSql(tbName, col))
 							} else {
 								engine.LogWarnf("Table %s column %s db type is %s, struct type is %s\n",
 									table.Name, col.Name, curType, expectedType)
@@ -3894,7 +4340,7 @@ func (s *Session) Sync2(beans ...interface{}) error {
 			for name, index := range table.Indexes {
 				var oriIndex *core.Index
 				for name2, index2 := range oriTable.Indexes {
-					if index.Equal(index2) {
+					if index.Equal(index2) && engine.sameIndex(table, index, oriTable, index2) {
 						oriIndex = index2
 						foundIndexNames[name2] = true
 						break
@@ -3903,7 +4349,7 @@ func (s *Session) Sync2(beans ...interface{}) error {
 
 				if oriIndex != nil {
 					if oriIndex.Type != index.Type {
-						sql := engine.dialect.DropIndexSql(table.Name, oriIndex)
+						sql := engine.dropIndexSQL(tbName, oriIndex)
 						_, err = engine.Exec(sql)
 						if err != nil {
 							return err
@@ -3919,7 +4365,7 @@ func (s *Session) Sync2(beans ...interface{}) error {
 
 			for name2, index2 := range oriTable.Indexes {
 				if _, ok := foundIndexNames[name2]; !ok {
-					sql := engine.dialect.DropIndexSql(table.Name, index2)
+					sql := engine.dropIndexSQL(tbName, index2)
 					_, err = engine.Exec(sql)
 					if err != nil {
 						return err
@@ -3932,17 +4378,28 @@ func (s *Session) Sync2(beans ...interface{}) error {
 					session := engine.NewSession()
 					session.Statement.RefTable = table
 					defer session.Close()
-					err = session.addUnique(table.Name, name)
+					err = session.addUnique(tbName, name)
 				} else if index.Type == core.IndexType {
 					session := engine.NewSession()
 					session.Statement.RefTable = table
 					defer session.Close()
-					err = session.addIndex(table.Name, name)
+					err = session.addIndex(tbName, name)
 				}
 				if err != nil {
 					return err
 				}
 			}
+
+			session = engine.NewSession()
+			session.Statement.RefTable = table
+			err = session.syncForeignKeys(table, oriTable)
+			if err == nil {
+				err = session.syncColumnOptions(table, oriTable)
+			}
+			session.Close()
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,287 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// the kinds of the column with tag "deleted"
+const (
+	deletedTime = iota // datetime, set to now when deleted
+	deletedUnix        // integer, set to now's unix time when deleted
+	deletedFlag        // bool or tinyint, set to true when deleted
+)
+
+func deletedKind(col *core.Column) int {
+	switch {
+	case col.SQLType.IsTime():
+		return deletedTime
+	case col.SQLType.Name == core.Bool || col.SQLType.Name == core.TinyInt || col.SQLType.Name == core.Bit:
+		return deletedFlag
+	case col.SQLType.IsNumeric():
+		return deletedUnix
+	}
+	return deletedTime
+}
+
+// boolStr returns the literal of b for col
+func (engine *Engine) boolStr(col *core.Column, b bool) string {
+	if col.SQLType.Name == core.Bool && engine.dialect.DBType() == core.POSTGRES {
+		if b {
+			return "true"
+		}
+		return "false"
+	}
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// softDeleteCond returns the condition which filters the soft deleted
+// records out, or only keeps them if onlyDeleted
+func (engine *Engine) softDeleteCond(col *core.Column, colName string, onlyDeleted bool) string {
+	var zero string
+	switch deletedKind(col) {
+	case deletedTime:
+		zero = "'0001-01-01 00:00:00'"
+	case deletedUnix:
+		zero = "0"
+	case deletedFlag:
+		zero = engine.boolStr(col, false)
+	}
+	if onlyDeleted {
+		return fmt.Sprintf("(%v IS NOT NULL AND %v <> %v)", colName, colName, zero)
+	}
+	return fmt.Sprintf("(%v IS NULL OR %v = %v)", colName, colName, zero)
+}
+
+// deletedValue returns the value of a soft deleted record and the closure
+// which sets it to the bean
+func (engine *Engine) deletedValue(col *core.Column) (interface{}, func(interface{})) {
+	if deletedKind(col) == deletedFlag {
+		var val interface{} = 1
+		if col.SQLType.Name == core.Bool {
+			val = true
+		}
+		return val, func(bean interface{}) {
+			setDeletedFlag(bean, col, true)
+		}
+	}
+
+	val, t := engine.NowTime2(col.SQLType.Name)
+	return val, func(bean interface{}) {
+		setColumnTime(bean, col, t)
+	}
+}
+
+// restoredValue returns the value of a restored record and the closure which
+// sets it to the bean
+func (engine *Engine) restoredValue(col *core.Column) (interface{}, func(interface{})) {
+	var val interface{}
+	switch deletedKind(col) {
+	case deletedTime:
+		if !col.Nullable {
+			val = "0001-01-01 00:00:00"
+		}
+	case deletedUnix:
+		val = 0
+	case deletedFlag:
+		val = 0
+		if col.SQLType.Name == core.Bool {
+			val = false
+		}
+	}
+	return val, func(bean interface{}) {
+		setDeletedFlag(bean, col, false)
+	}
+}
+
+// setDeletedFlag sets the field of a flag column, or resets the field of a
+// time column if deleted is false
+func setDeletedFlag(bean interface{}, col *core.Column, deleted bool) {
+	v, err := colValueOf(col, bean)
+	if err != nil || !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(deleted)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if deleted {
+			v.SetInt(1)
+		} else {
+			v.SetInt(0)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if deleted {
+			v.SetUint(1)
+		} else {
+			v.SetUint(0)
+		}
+	case reflect.Struct:
+		if !deleted {
+			v.Set(reflect.ValueOf(time.Time{}).Convert(v.Type()))
+		}
+	}
+}
+
+// deletedByColumn returns the column with tag "deleted_by", nil if none
+func (engine *Engine) deletedByColumn(table *core.Table) *core.Column {
+	if name := engine.tableExt(table).deletedBy; name != "" {
+		return table.GetColumn(name)
+	}
+	return nil
+}
+
+// softDeleteFilter returns the condition of the soft deleted records for a
+// query which has no condition bean, empty if there's no need.
+func (statement *Statement) softDeleteFilter() string {
+	col := statement.RefTable.DeletedColumn()
+	if col == nil || statement.unscoped {
+		return ""
+	}
+	colName := statement.Engine.Quote(col.Name)
+	if statement.JoinStr != "" {
+		colName = statement.Engine.Quote(statement.refName()) + "." + colName
+	}
+	return statement.Engine.softDeleteCond(col, colName, statement.onlyDeleted)
+}
+
+// OnlyDeleted makes the queries return the soft deleted records only
+func (statement *Statement) OnlyDeleted() *Statement {
+	statement.onlyDeleted = true
+	statement.unscoped = false
+	return statement
+}
+
+// ForceDelete makes Delete remove the records even if the table has a
+// "deleted" column
+func (statement *Statement) ForceDelete() *Statement {
+	statement.forceDelete = true
+	return statement
+}
+
+// DeletedBy sets the value of the "deleted_by" column for soft delete
+func (statement *Statement) DeletedBy(who interface{}) *Statement {
+	statement.deletedBy = who
+	return statement
+}
+
+// OnlyDeleted makes the queries return the soft deleted records only
+func (session *Session) OnlyDeleted() *Session {
+	session.Statement.OnlyDeleted()
+	return session
+}
+
+// ForceDelete makes Delete remove the records even if the table has a
+// "deleted" column
+func (session *Session) ForceDelete() *Session {
+	session.Statement.ForceDelete()
+	return session
+}
+
+// DeletedBy sets the value of the "deleted_by" column for soft delete
+func (session *Session) DeletedBy(who interface{}) *Session {
+	session.Statement.DeletedBy(who)
+	return session
+}
+
+// Restore undeletes the soft deleted records, bean's non-empty fields are
+// conditions
+func (session *Session) Restore(bean interface{}) (int64, error) {
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+	if session.Statement.lastError != nil {
+		return 0, session.Statement.lastError
+	}
+
+	table := session.Engine.TableInfo(bean)
+	session.Statement.RefTable = table
+	deletedColumn := table.DeletedColumn()
+	if deletedColumn == nil {
+		return 0, ErrNoDeletedColumn
+	}
+
+	colNames, args, err := buildConditions(session.Engine, table, bean, true, true,
+		false, true, session.Statement.allUseBool, session.Statement.useAllCols,
+		false, true, session.Statement.mustColumnMap, session.Statement.refName(), false)
+	if err != nil {
+		return 0, err
+	}
+
+	// the filter of the soft deleted records is added at last, the others
+	// are the conditions of the bean's fields
+	filter := session.Engine.softDeleteCond(deletedColumn, session.Engine.Quote(deletedColumn.Name), true)
+	beanConds := make([]string, 0, len(colNames))
+	for _, cond := range colNames {
+		if cond != filter {
+			beanConds = append(beanConds, cond)
+		}
+	}
+	if len(beanConds) == 0 && session.Statement.IdParam == nil &&
+		session.Statement.WhereStr == "" && len(session.Statement.inColumns) == 0 {
+		return 0, ErrNeedDeletedCond
+	}
+
+	var andStr = " " + session.Engine.dialect.AndStr() + " "
+	session.Statement.processIdParam()
+	conds := make([]string, 0, 4)
+	if session.Statement.WhereStr != "" {
+		conds = append(conds, "("+session.Statement.WhereStr+")")
+	}
+	if len(beanConds) > 0 {
+		conds = append(conds, strings.Join(beanConds, andStr))
+	}
+	inSql, inArgs := session.Statement.genInSql()
+	if len(inSql) > 0 {
+		conds = append(conds, inSql)
+	}
+	conds = append(conds, filter)
+	scopeConds, scopeArgs := session.Statement.scopeConds()
+	conds = append(conds, scopeConds...)
+
+	val, after := session.Engine.restoredValue(deletedColumn)
+	setStr := session.Engine.Quote(deletedColumn.Name) + " = ?"
+	setArgs := []interface{}{val}
+	if col := session.Engine.deletedByColumn(table); col != nil {
+		setStr += ", " + session.Engine.Quote(col.Name) + " = NULL"
+	}
+	if session.Statement.UseAutoTime && table.Updated != "" {
+		val, t := session.Engine.NowTime2(table.UpdatedColumn().SQLType.Name)
+		setStr += ", " + session.Engine.Quote(table.Updated) + " = ?"
+		setArgs = append(setArgs, val)
+		restored := after
+		after = func(bean interface{}) {
+			restored(bean)
+			setColumnTime(bean, table.UpdatedColumn(), t)
+		}
+	}
+
+	sqlStr := fmt.Sprintf("UPDATE %v SET %v WHERE %v",
+		session.Engine.Quote(session.Statement.TableName()), setStr, strings.Join(conds, andStr))
+
+	args = append(append(append(append(setArgs, session.Statement.Params...), args...), inArgs...), scopeArgs...)
+	res, err := session.exec(sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+		cacher.ClearIds(session.Statement.cacheTableName())
+		cacher.ClearBeans(session.Statement.cacheTableName())
+	}
+	session.clearIdentities(session.Statement.TableName())
+	after(bean)
+	return res.RowsAffected()
+}
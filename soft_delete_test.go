@@ -0,0 +1,164 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+type SoftTime struct {
+	Id        int64
+	Name      string
+	Deleted   time.Time `xorm:"deleted"`
+	DeletedBy string    `xorm:"deleted_by"`
+}
+
+type SoftFlag struct {
+	Id      int64
+	Name    string
+	Deleted bool `xorm:"deleted"`
+}
+
+type SoftUnix struct {
+	Id      int64
+	Name    string
+	Deleted int64 `xorm:"deleted"`
+}
+
+func TestSoftDeleteCond(t *testing.T) {
+	engine := testEngine(t, core.POSTGRES)
+	table := engine.TableInfo(new(SoftFlag))
+	assertSQL(t, `("deleted" IS NULL OR "deleted" = false)`,
+		engine.softDeleteCond(table.DeletedColumn(), engine.Quote("deleted"), false))
+	assertSQL(t, `("deleted" IS NOT NULL AND "deleted" <> false)`,
+		engine.softDeleteCond(table.DeletedColumn(), engine.Quote("deleted"), true))
+
+	table = engine.TableInfo(new(SoftUnix))
+	assertSQL(t, `("deleted" IS NULL OR "deleted" = 0)`,
+		engine.softDeleteCond(table.DeletedColumn(), engine.Quote("deleted"), false))
+}
+
+func TestSoftDelete(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	beans := []interface{}{new(SoftTime), new(SoftFlag), new(SoftUnix)}
+	assertNoErr(t, engine.Sync2(beans...))
+
+	_, err := engine.Insert(&SoftTime{Name: "a"}, &SoftTime{Name: "b"},
+		&SoftFlag{Name: "a"}, &SoftFlag{Name: "b"}, &SoftUnix{Name: "a"}, &SoftUnix{Name: "b"})
+	assertNoErr(t, err)
+
+	// the deleted field of a bean is set by Delete, so each call has its own
+	count := func(session *Session, bean interface{}) int64 {
+		cnt, err := session.Count(reflect.New(reflect.TypeOf(bean).Elem()).Interface())
+		assertNoErr(t, err)
+		return cnt
+	}
+	for _, bean := range beans {
+		table := engine.TableInfo(bean)
+		cnt, err := engine.Where("name = ?", "a").Delete(reflect.New(reflect.TypeOf(bean).Elem()).Interface())
+		assertNoErr(t, err)
+		if cnt != 1 {
+			t.Fatalf("%v: deleted %d records, expected 1", table.Name, cnt)
+		}
+		if cnt := count(engine.NewSession(), bean); cnt != 1 {
+			t.Errorf("%v: %d records are found, expected 1", table.Name, cnt)
+		}
+		if cnt := count(engine.OnlyDeleted(), bean); cnt != 1 {
+			t.Errorf("%v: %d deleted records are found, expected 1", table.Name, cnt)
+		}
+		if cnt := count(engine.Unscoped(), bean); cnt != 2 {
+			t.Errorf("%v: %d records are found unscoped, expected 2", table.Name, cnt)
+		}
+
+		cnt, err = engine.Where("name = ?", "a").Restore(bean)
+		assertNoErr(t, err)
+		if cnt != 1 {
+			t.Errorf("%v: restored %d records, expected 1", table.Name, cnt)
+		}
+		if cnt := count(engine.NewSession(), bean); cnt != 2 {
+			t.Errorf("%v: %d records are found after restore, expected 2", table.Name, cnt)
+		}
+
+		_, err = engine.ForceDelete().Where("name = ?", "b").Delete(bean)
+		assertNoErr(t, err)
+		if cnt := count(engine.Unscoped(), bean); cnt != 1 {
+			t.Errorf("%v: %d records are left after force delete, expected 1", table.Name, cnt)
+		}
+	}
+}
+
+func TestDeletedBy(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(SoftTime)))
+	record := SoftTime{Name: "a"}
+	_, err := engine.Insert(&record)
+	assertNoErr(t, err)
+
+	_, err = engine.DeletedBy("admin").Id(record.Id).Delete(new(SoftTime))
+	assertNoErr(t, err)
+	var deleted SoftTime
+	has, err := engine.OnlyDeleted().Id(record.Id).Get(&deleted)
+	assertNoErr(t, err)
+	if !has || deleted.DeletedBy != "admin" || deleted.Deleted.IsZero() {
+		t.Errorf("the deleted record is %+v", deleted)
+	}
+
+	_, err = engine.Id(record.Id).Restore(new(SoftTime))
+	assertNoErr(t, err)
+	var restored SoftTime
+	has, err = engine.Id(record.Id).Get(&restored)
+	assertNoErr(t, err)
+	if !has || restored.DeletedBy != "" {
+		t.Errorf("the restored record is %+v", restored)
+	}
+}
+
+type SoftUpdated struct {
+	Id      int64
+	Name    string
+	Deleted time.Time `xorm:"deleted"`
+	Updated time.Time `xorm:"updated"`
+}
+
+func TestRestoreConds(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(SoftUpdated)))
+	_, err := engine.Insert(&SoftUpdated{Name: "a"}, &SoftUpdated{Name: "b"})
+	assertNoErr(t, err)
+	_, err = engine.Where("id > ?", 0).Delete(new(SoftUpdated))
+	assertNoErr(t, err)
+
+	// the filter of the soft deleted records isn't a condition
+	if _, err = engine.Restore(new(SoftUpdated)); err != ErrNeedDeletedCond {
+		t.Errorf("expected ErrNeedDeletedCond, got %v", err)
+	}
+
+	// the bean is untouched if the restore fails
+	bean := &SoftUpdated{Name: "a"}
+	if _, err = engine.Where("no_such_column = ?", 1).Restore(bean); err == nil {
+		t.Error("expected the error of an unknown column")
+	}
+	if !bean.Updated.IsZero() {
+		t.Errorf("the updated time is set by a failed restore: %+v", bean)
+	}
+
+	cnt, err := engine.Restore(bean)
+	assertNoErr(t, err)
+	if cnt != 1 || bean.Updated.IsZero() {
+		t.Errorf("restored %d records by the bean's name, the bean is %+v", cnt, bean)
+	}
+	cnt, err = engine.Id(2).Restore(new(SoftUpdated))
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Errorf("restored %d records by Id, expected 1", cnt)
+	}
+}
@@ -161,7 +161,7 @@ func (db *sqlite3) SqlType(c *core.Column) string {
 	case core.TimeStampz:
 		return core.Text
 	case core.Char, core.Varchar, core.NVarchar, core.TinyText,
-		core.Text, core.MediumText, core.LongText, core.Json:
+		core.Text, core.MediumText, core.LongText, core.Json, core.Uuid, core.Enum, core.Set:
 		return core.Text
 	case core.Bit, core.TinyInt, core.SmallInt, core.MediumInt, core.Int, core.Integer, core.BigInt, core.Bool:
 		return core.Integer
@@ -195,7 +195,7 @@ func (db *sqlite3) IsReserved(name string) bool {
 }
 
 func (db *sqlite3) Quote(name string) string {
-	return "`" + name + "`"
+	return quoteParts(name, "`", "`")
 }
 
 func (db *sqlite3) QuoteStr() string {
@@ -295,11 +295,16 @@ func (db *sqlite3) GetColumns(tableName string) ([]string, map[string]*core.Colu
 
 	nStart := strings.Index(name, "(")
 	nEnd := strings.LastIndex(name, ")")
-	colCreates := strings.Split(name[nStart+1:nEnd], ",")
+	colCreates := splitTopLevel(name[nStart+1:nEnd], ',')
 	cols := make(map[string]*core.Column)
 	colSeq := make([]string, 0)
 	for _, colStr := range colCreates {
 		fields := strings.Fields(strings.TrimSpace(colStr))
+		// the table constraints follow the columns
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
 		col := new(core.Column)
 		col.Indexes = make(map[string]bool)
 		col.Nullable = true
@@ -410,20 +415,126 @@ func (db *sqlite3) GetIndexes(tableName string) (map[string]*core.Index, error)
 			index.Type = core.IndexType
 		}
 
-		nStart := strings.Index(sql, "(")
-		nEnd := strings.Index(sql, ")")
-		colIndexes := strings.Split(sql[nStart+1:nEnd], ",")
-
-		index.Cols = make([]string, 0)
-		for _, col := range colIndexes {
-			index.Cols = append(index.Cols, strings.Trim(col, "` []"))
-		}
+		def := &Index{}
+		parseIndexDef(def, sql)
+		index.Cols = indexColumns(def.Cols)
 		indexes[index.Name] = index
 	}
 
 	return indexes, nil
 }
 
+func (db *sqlite3) GetIndexOptions(tableName string) (map[string]*Index, error) {
+	args := []interface{}{tableName}
+	s := "SELECT sql FROM sqlite_master WHERE type='index' and tbl_name = ?"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]*Index)
+	for _, result := range results {
+		// the indexes of the constraints have no sql
+		sql := result[0]
+		nNStart := strings.Index(sql, "INDEX")
+		nNEnd := strings.Index(sql, "ON")
+		if sql == "" || nNStart == -1 || nNEnd == -1 {
+			continue
+		}
+		indexName := strings.Trim(sql[nNStart+6:nNEnd], "` []")
+		if strings.HasPrefix(indexName, "IDX_"+tableName) || strings.HasPrefix(indexName, "UQE_"+tableName) {
+			indexName = indexName[5+len(tableName) : len(indexName)]
+		}
+		index := &Index{Name: indexName, Unique: strings.HasPrefix(sql, "CREATE UNIQUE INDEX")}
+		parseIndexDef(index, sql)
+		indexes[indexName] = index
+	}
+	return indexes, nil
+}
+
+func (db *sqlite3) getForeignKeys(tableName string) ([]*ForeignKey, error) {
+	s := fmt.Sprintf("PRAGMA foreign_key_list(%v)", db.Quote(tableName))
+
+	rows, err := db.DB().Query(s)
+	if db.Logger != nil {
+		db.Logger.Info("[sql]", s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []*ForeignKey
+	for rows.Next() {
+		var id, seq int
+		var refTable, colName, onUpdate, onDelete, match string
+		var refCol sql.NullString
+		err = rows.Scan(&id, &seq, &refTable, &colName, &refCol, &onUpdate, &onDelete, &match)
+		if err != nil {
+			return nil, err
+		}
+		// the primary key is referenced if the column is omitted
+		if !refCol.Valid {
+			refCol.String = "id"
+		}
+		fks = addForeignKeyColumn(fks, fmt.Sprintf("%d", id), colName, refTable, refCol.String, onDelete, onUpdate)
+	}
+	// sqlite3 doesn't keep the constraint names
+	for _, fk := range fks {
+		fk.Name = ""
+	}
+	return fks, nil
+}
+
+// GetColumnOptions reads the generated columns and the checks from the
+// definition of the table and the foreign keys by PRAGMA, sqlite3 has no
+// comments.
+func (db *sqlite3) GetColumnOptions(tableName string) (*tableExt, error) {
+	args := []interface{}{tableName}
+	s := "SELECT sql FROM sqlite_master WHERE type='table' and name = ?"
+	results, err := queryStrings(db.DB(), db.Logger, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, errors.New("no table named " + tableName)
+	}
+
+	ext := newTableExt()
+	fks, err := db.getForeignKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ext.foreignKeys = fks
+	createSQL := results[0][0]
+	nStart := strings.Index(createSQL, "(")
+	nEnd := columnsEnd(createSQL)
+	if nStart < 0 || nEnd < nStart {
+		return ext, nil
+	}
+	for _, def := range splitTopLevel(createSQL[nStart+1:nEnd], ',') {
+		fields := strings.Fields(def)
+		upper := strings.ToUpper(def)
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT":
+			if pos := strings.Index(upper, " CHECK"); pos > 0 && len(fields) > 1 {
+				ext.checks = append(ext.checks, &Check{
+					Name: strings.Trim(fields[1], "`[]\""),
+					Expr: parenBody(def[pos:]),
+				})
+			}
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+		default:
+			if pos := strings.Index(upper, "GENERATED ALWAYS AS"); pos > 0 {
+				colExt := ext.newColumn(strings.Trim(fields[0], "`[]\""))
+				colExt.generated = parenBody(def[pos:])
+				colExt.stored = strings.Contains(upper[pos:], "STORED")
+			}
+		}
+	}
+	return ext, nil
+}
+
 func (db *sqlite3) Filters() []core.Filter {
 	return []core.Filter{&core.IdFilter{}}
 }
@@ -6,7 +6,6 @@ package xorm
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -35,46 +34,67 @@ type exprParam struct {
 	expr    string
 }
 
+type joinParam struct {
+	operator  string
+	table     string
+	condition string
+}
+
 // statement save all the sql info for executing SQL
 type Statement struct {
-	RefTable      *core.Table
-	Engine        *Engine
-	Start         int
-	LimitN        int
-	WhereStr      string
-	IdParam       *core.PK
-	Params        []interface{}
-	OrderStr      string
-	JoinStr       string
-	GroupByStr    string
-	HavingStr     string
-	ColumnStr     string
-	selectStr string
-	columnMap     map[string]bool
-	useAllCols    bool
-	OmitStr       string
-	ConditionStr  string
-	AltTableName  string
-	RawSQL        string
-	RawParams     []interface{}
-	UseCascade    bool
-	UseAutoJoin   bool
-	StoreEngine   string
-	Charset       string
-	BeanArgs      []interface{}
-	UseCache      bool
-	UseAutoTime   bool
-	IsDistinct    bool
-	TableAlias    string
-	allUseBool    bool
-	checkVersion  bool
-	unscoped      bool
-	mustColumnMap map[string]bool
-	nullableMap   map[string]bool
-	inColumns     map[string]*inParam
-	incrColumns   map[string]incrParam
-	decrColumns   map[string]decrParam
-	exprColumns   map[string]exprParam
+	RefTable         *core.Table
+	Engine           *Engine
+	Start            int
+	LimitN           int
+	WhereStr         string
+	IdParam          *core.PK
+	Params           []interface{}
+	OrderStr         string
+	JoinStr          string
+	joins            []joinParam
+	GroupByStr       string
+	HavingStr        string
+	ColumnStr        string
+	selectStr        string
+	columnMap        map[string]bool
+	useAllCols       bool
+	OmitStr          string
+	ConditionStr     string
+	AltTableName     string
+	RawSQL           string
+	RawParams        []interface{}
+	UseCascade       bool
+	UseAutoJoin      bool
+	StoreEngine      string
+	Charset          string
+	BeanArgs         []interface{}
+	UseCache         bool
+	UseAutoTime      bool
+	IsDistinct       bool
+	TableAlias       string
+	allUseBool       bool
+	checkVersion     bool
+	unscoped         bool
+	onlyDeleted      bool
+	forceDelete      bool
+	deletedBy        interface{}
+	withoutScopes    map[string]bool
+	withoutAllScopes bool
+	tenantId         interface{}
+	preloads         []string
+	idCondOnly       bool // the bean's fields are not conditions of Delete
+	mustColumnMap    map[string]bool
+	nullableMap      map[string]bool
+	inColumns        map[string]*inParam
+	incrColumns      map[string]incrParam
+	decrColumns      map[string]decrParam
+	exprColumns      map[string]exprParam
+	returning        bool
+	returningCols    []string
+	beanTableName    string // the table resolved by the bean, see TableNameResolver
+	matches          []matchParam
+	relevance        bool
+	lastError        error // the error of building a condition, returned by the operation
 }
 
 // init
@@ -87,6 +107,7 @@ func (statement *Statement) Init() {
 	statement.OrderStr = ""
 	statement.UseCascade = true
 	statement.JoinStr = ""
+	statement.joins = make([]joinParam, 0)
 	statement.GroupByStr = ""
 	statement.HavingStr = ""
 	statement.ColumnStr = ""
@@ -94,6 +115,7 @@ func (statement *Statement) Init() {
 	statement.columnMap = make(map[string]bool)
 	statement.ConditionStr = ""
 	statement.AltTableName = ""
+	statement.beanTableName = ""
 	statement.IdParam = nil
 	statement.RawSQL = ""
 	statement.RawParams = make([]interface{}, 0)
@@ -109,10 +131,23 @@ func (statement *Statement) Init() {
 	statement.nullableMap = make(map[string]bool)
 	statement.checkVersion = true
 	statement.unscoped = false
+	statement.onlyDeleted = false
+	statement.forceDelete = false
+	statement.deletedBy = nil
+	statement.withoutScopes = nil
+	statement.withoutAllScopes = false
+	statement.tenantId = nil
+	statement.preloads = nil
+	statement.idCondOnly = false
 	statement.inColumns = make(map[string]*inParam)
 	statement.incrColumns = make(map[string]incrParam)
 	statement.decrColumns = make(map[string]decrParam)
 	statement.exprColumns = make(map[string]exprParam)
+	statement.returning = false
+	statement.returningCols = nil
+	statement.matches = nil
+	statement.relevance = false
+	statement.lastError = nil
 }
 
 // add the raw sql statement
@@ -179,7 +214,7 @@ func buildUpdates(engine *Engine, table *core.Table, bean interface{},
 	includeVersion bool, includeUpdated bool, includeNil bool,
 	includeAutoIncr bool, allUseBool bool, useAllCols bool,
 	mustColumnMap map[string]bool, nullableMap map[string]bool,
-	columnMap map[string]bool, update bool) ([]string, []interface{}) {
+	columnMap map[string]bool, update bool) ([]string, []interface{}, error) {
 
 	colNames := make([]string, 0)
 	var args = make([]interface{}, 0)
@@ -207,7 +242,7 @@ func buildUpdates(engine *Engine, table *core.Table, bean interface{},
 			continue
 		}
 
-		fieldValuePtr, err := col.ValueOf(bean)
+		fieldValuePtr, err := colValueOf(col, bean)
 		if err != nil {
 			engine.LogError(err)
 			continue
@@ -278,6 +313,35 @@ func buildUpdates(engine *Engine, table *core.Table, bean interface{},
 			}
 		}
 
+		if conv := engine.converterOf(table, col, fieldType); conv != nil {
+			if !requiredField && isZeroValue(fieldValue) {
+				continue
+			}
+			if val, err = conv.toDB(fieldValue); err != nil {
+				return nil, nil, err
+			}
+			goto APPEND
+		}
+
+		if arg, ok, err := pgEncode(col, fieldValue); ok {
+			if err != nil {
+				return nil, nil, err
+			}
+			if !requiredField && isEmptyValue(fieldValue) {
+				continue
+			}
+			val = arg
+			goto APPEND
+		}
+
+		if arg, ok := optionArg(col, fieldValue); ok {
+			if !requiredField && isEmptyValue(fieldValue) {
+				continue
+			}
+			val = arg
+			goto APPEND
+		}
+
 		switch fieldType.Kind() {
 		case reflect.Bool:
 			if allUseBool || requiredField {
@@ -389,14 +453,14 @@ func buildUpdates(engine *Engine, table *core.Table, bean interface{},
 		colNames = append(colNames, fmt.Sprintf("%v = ?", engine.Quote(col.Name)))
 	}
 
-	return colNames, args
+	return colNames, args, nil
 }
 
 // Auto generating conditions according a struct
 func buildConditions(engine *Engine, table *core.Table, bean interface{},
 	includeVersion bool, includeUpdated bool, includeNil bool,
-	includeAutoIncr bool, allUseBool bool, useAllCols bool, unscoped bool,
-	mustColumnMap map[string]bool, tableName string, addedTableName bool) ([]string, []interface{}) {
+	includeAutoIncr bool, allUseBool bool, useAllCols bool, unscoped bool, onlyDeleted bool,
+	mustColumnMap map[string]bool, tableName string, addedTableName bool) ([]string, []interface{}, error) {
 	colNames := make([]string, 0)
 	var args = make([]interface{}, 0)
 	for _, col := range table.Columns() {
@@ -421,15 +485,15 @@ func buildConditions(engine *Engine, table *core.Table, bean interface{},
 			colName = engine.Quote(col.Name)
 		}
 
-		fieldValuePtr, err := col.ValueOf(bean)
+		fieldValuePtr, err := colValueOf(col, bean)
 		if err != nil {
 			engine.LogError(err)
 			continue
 		}
 
 		if col.IsDeleted && !unscoped { // tag "deleted" is enabled
-			colNames = append(colNames, fmt.Sprintf("(%v IS NULL or %v = '0001-01-01 00:00:00')", 
-				colName, colName))
+			colNames = append(colNames, engine.softDeleteCond(col, colName, onlyDeleted))
+			continue
 		}
 
 		fieldValue := *fieldValuePtr
@@ -465,6 +529,35 @@ func buildConditions(engine *Engine, table *core.Table, bean interface{},
 		}
 
 		var val interface{}
+		if conv := engine.converterOf(table, col, fieldType); conv != nil {
+			if !requiredField && isZeroValue(fieldValue) {
+				continue
+			}
+			if val, err = conv.toDB(fieldValue); err != nil {
+				return nil, nil, err
+			}
+			goto APPEND
+		}
+
+		if arg, ok, err := pgEncode(col, fieldValue); ok {
+			if err != nil {
+				return nil, nil, err
+			}
+			if !requiredField && isEmptyValue(fieldValue) {
+				continue
+			}
+			val = arg
+			goto APPEND
+		}
+
+		if arg, ok := optionArg(col, fieldValue); ok {
+			if !requiredField && isEmptyValue(fieldValue) {
+				continue
+			}
+			val = arg
+			goto APPEND
+		}
+
 		switch fieldType.Kind() {
 		case reflect.Bool:
 			if allUseBool || requiredField {
@@ -569,6 +662,7 @@ func buildConditions(engine *Engine, table *core.Table, bean interface{},
 			val = fieldValue.Interface()
 		}
 
+	APPEND:
 		args = append(args, val)
 		var condi string
 		if col.IsPrimaryKey && engine.dialect.DBType() == "ql" {
@@ -579,17 +673,20 @@ func buildConditions(engine *Engine, table *core.Table, bean interface{},
 		colNames = append(colNames, condi)
 	}
 
-	return colNames, args
+	return colNames, args, nil
 }
 
 // return current tableName
 func (statement *Statement) TableName() string {
 	if statement.AltTableName != "" {
-		return statement.AltTableName
+		return statement.Engine.tbNameWithSchema(statement.AltTableName)
+	}
+	if statement.beanTableName != "" {
+		return statement.Engine.tbNameWithSchema(statement.beanTableName)
 	}
 
 	if statement.RefTable != nil {
-		return statement.RefTable.Name
+		return statement.Engine.tbNameWithSchema(statement.RefTable.Name)
 	}
 	return ""
 }
@@ -697,7 +794,7 @@ func (statement *Statement) genInSql() (string, []interface{}) {
 	args := make([]interface{}, 0)
 	for _, params := range statement.inColumns {
 		inStrs = append(inStrs, fmt.Sprintf("(%v IN (%v))",
-			statement.Engine.autoQuote(params.colName),
+			statement.Engine.quoteExpr(params.colName),
 			strings.Join(makeArray("?", len(params.args)), ",")))
 		args = append(args, params.args...)
 	}
@@ -732,37 +829,6 @@ func col2NewCols(columns ...string) []string {
 	return newColumns
 }
 
-func (engine *Engine) autoQuote(col string) string {
-	col = strings.Replace(col, "`", "", -1)
-	col = strings.Replace(col, engine.QuoteStr(), "", -1)
-	fields := strings.Split(strings.TrimSpace(col), ".")
-	for i, field := range fields {
-		fields[i] = engine.Quote(field)
-	}
-	return strings.Join(fields, ".")
-}
-
-func (statement *Statement) col2NewColsWithQuote(columns ...string) []string {
-	newColumns := make([]string, 0)
-	for _, col := range columns {
-		col = strings.Replace(col, "`", "", -1)
-		col = strings.Replace(col, statement.Engine.QuoteStr(), "", -1)
-		ccols := strings.Split(col, ",")
-		for _, c := range ccols {
-			fields := strings.Split(strings.TrimSpace(c), ".")
-			if len(fields) == 1 {
-				newColumns = append(newColumns, statement.Engine.Quote(fields[0]))
-			} else if len(fields) == 2 {
-				newColumns = append(newColumns, statement.Engine.Quote(fields[0])+"."+
-					statement.Engine.Quote(fields[1]))
-			} else {
-				panic(errors.New("unwanted colnames"))
-			}
-		}
-	}
-	return newColumns
-}
-
 // Generate "Distince col1, col2 " statment
 func (statement *Statement) Distinct(columns ...string) *Statement {
 	statement.IsDistinct = true
@@ -782,10 +848,7 @@ func (statement *Statement) Cols(columns ...string) *Statement {
 	for _, nc := range newColumns {
 		statement.columnMap[strings.ToLower(nc)] = true
 	}
-	statement.ColumnStr = statement.Engine.Quote(strings.Join(newColumns, statement.Engine.Quote(", ")))
-	if strings.Contains(statement.ColumnStr, ".") {
-		statement.ColumnStr = strings.Replace(statement.ColumnStr, ".", statement.Engine.Quote("."), -1)
-	}
+	statement.ColumnStr = statement.Engine.quoteColumns(columns...)
 	return statement
 }
 
@@ -829,7 +892,7 @@ func (statement *Statement) Omit(columns ...string) {
 	for _, nc := range newColumns {
 		statement.columnMap[strings.ToLower(nc)] = false
 	}
-	statement.OmitStr = statement.Engine.Quote(strings.Join(newColumns, statement.Engine.Quote(", ")))
+	statement.OmitStr = statement.Engine.quoteColumns(newColumns...)
 }
 
 // Update use only: update columns to null when value is nullable and zero-value
@@ -855,12 +918,12 @@ func (statement *Statement) Limit(limit int, start ...int) *Statement {
 	return statement
 }
 
-// Generate "Order By order" statement
+// Generate "Order By order" statement, the column names of order are quoted
 func (statement *Statement) OrderBy(order string) *Statement {
 	if statement.OrderStr != "" {
 		statement.OrderStr += ", "
 	}
-	statement.OrderStr += order
+	statement.OrderStr += statement.Engine.quoteOrder(order)
 	return statement
 }
 
@@ -868,7 +931,7 @@ func (statement *Statement) Desc(colNames ...string) *Statement {
 	if statement.OrderStr != "" {
 		statement.OrderStr += ", "
 	}
-	newColNames := statement.col2NewColsWithQuote(colNames...)
+	newColNames := statement.Engine.quoteExprs(colNames...)
 	sqlStr := strings.Join(newColNames, " DESC, ")
 	statement.OrderStr += sqlStr + " DESC"
 	return statement
@@ -879,7 +942,7 @@ func (statement *Statement) Asc(colNames ...string) *Statement {
 	if statement.OrderStr != "" {
 		statement.OrderStr += ", "
 	}
-	newColNames := statement.col2NewColsWithQuote(colNames...)
+	newColNames := statement.Engine.quoteExprs(colNames...)
 	sqlStr := strings.Join(newColNames, " ASC, ")
 	statement.OrderStr += sqlStr + " ASC"
 	return statement
@@ -897,7 +960,7 @@ func (statement *Statement) Join(join_operator string, tablename interface{}, co
 			joinTable = statement.Engine.Quote(table) + " AS " + statement.Engine.Quote(t[1])
 		} else if l == 1 {
 			table := t[0]
-			joinTable = statement.Engine.Quote(table)
+			joinTable = statement.Engine.quoteExpr(table)
 		}
 	case []interface{}:
 		t := tablename.([]interface{})
@@ -911,17 +974,18 @@ func (statement *Statement) Join(join_operator string, tablename interface{}, co
 				table = f.(string)
 			} else if t.Kind() == reflect.Struct {
 				r := statement.Engine.autoMapType(v)
-				table = r.Name
+				table = statement.Engine.tbNameWithSchema(r.Name)
 			}
 		}
 		if l > 1 {
 			joinTable = statement.Engine.Quote(table) + " AS " + statement.Engine.Quote(fmt.Sprintf("%v", t[1]))
 		} else if l == 1 {
-			joinTable = statement.Engine.Quote(table)
+			joinTable = statement.Engine.quoteExpr(table)
 		}
 	default:
+		// the table could be followed by an alias
 		t := fmt.Sprintf("%v", tablename)
-		joinTable = statement.Engine.Quote(t)
+		joinTable = statement.Engine.quoteExpr(t)
 	}
 	if statement.JoinStr != "" {
 		statement.JoinStr = statement.JoinStr + fmt.Sprintf(" %v JOIN %v ON %v", join_operator,
@@ -930,12 +994,141 @@ func (statement *Statement) Join(join_operator string, tablename interface{}, co
 		statement.JoinStr = fmt.Sprintf("%v JOIN %v ON %v", join_operator,
 			joinTable, condition)
 	}
+	statement.joins = append(statement.joins, joinParam{join_operator, joinTable, condition})
 	return statement
 }
 
-// Generate "Group By keys" statement
+// refName returns the name which qualifies the columns of the main table,
+// the alias if there is one
+func (statement *Statement) refName() string {
+	if statement.TableAlias != "" {
+		return statement.TableAlias
+	}
+	return statement.TableName()
+}
+
+// fromTableStr returns the quoted main table with its alias
+func (statement *Statement) fromTableStr() string {
+	str := statement.Engine.Quote(statement.TableName())
+	if statement.TableAlias != "" {
+		if statement.Engine.dialect.DBType() == core.ORACLE {
+			str += " " + statement.Engine.Quote(statement.TableAlias)
+		} else {
+			str += " AS " + statement.Engine.Quote(statement.TableAlias)
+		}
+	}
+	return str
+}
+
+// pkInJoinSql rewrites a joined statement as "pk IN (SELECT pk FROM ... JOIN ...)",
+// it's used by the dialects which could not update or delete with joins.
+func (statement *Statement) pkInJoinSql(condition string) (string, error) {
+	pkCols := statement.RefTable.PKColumns()
+	if len(pkCols) == 0 {
+		return "", ErrNoPrimaryKey
+	}
+	quote := statement.Engine.Quote
+	refName := quote(statement.refName())
+	var pks, selects = make([]string, 0, len(pkCols)), make([]string, 0, len(pkCols))
+	for _, col := range pkCols {
+		pks = append(pks, quote(col.Name))
+		selects = append(selects, refName+"."+quote(col.Name))
+	}
+	pkStr := strings.Join(pks, ", ")
+	if len(pks) > 1 {
+		pkStr = "(" + pkStr + ")"
+	}
+	sqlStr := fmt.Sprintf("%v IN (SELECT %v FROM %v %v", pkStr, strings.Join(selects, ", "),
+		statement.fromTableStr(), statement.JoinStr)
+	if condition != "" {
+		sqlStr += " WHERE " + condition
+	}
+	return sqlStr + ")", nil
+}
+
+// firstJoinAsFrom splits the joins for postgres, the first joined table
+// becomes the FROM/USING table and its condition goes to WHERE.
+func (statement *Statement) firstJoinAsFrom(condition string) (string, string, error) {
+	first := statement.joins[0]
+	op := strings.ToUpper(strings.TrimSpace(first.operator))
+	if op != "" && op != "INNER" && op != "CROSS" {
+		return "", "", fmt.Errorf("%v JOIN could not be the first join of UPDATE or DELETE on %v",
+			op, statement.Engine.dialect.DBType())
+	}
+	fromStr := first.table
+	for _, join := range statement.joins[1:] {
+		fromStr += fmt.Sprintf(" %v JOIN %v ON %v", join.operator, join.table, join.condition)
+	}
+	if condition != "" {
+		condition = fmt.Sprintf("(%v) %v (%v)", first.condition, statement.Engine.dialect.AndStr(), condition)
+	} else {
+		condition = first.condition
+	}
+	return fromStr, condition, nil
+}
+
+// genJoinUpdateSql generates an UPDATE statement which honours the joins,
+// condition has no WHERE keyword.
+func (statement *Statement) genJoinUpdateSql(setStr, condition string) (string, error) {
+	var whereStr string
+	if condition != "" {
+		whereStr = " WHERE " + condition
+	}
+	switch statement.Engine.dialect.DBType() {
+	case core.MYSQL:
+		return fmt.Sprintf("UPDATE %v %v SET %v%v", statement.fromTableStr(),
+			statement.JoinStr, setStr, whereStr), nil
+	case core.POSTGRES:
+		fromStr, condition, err := statement.firstJoinAsFrom(condition)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("UPDATE %v SET %v FROM %v WHERE %v", statement.fromTableStr(),
+			setStr, fromStr, condition), nil
+	case core.MSSQL:
+		return fmt.Sprintf("UPDATE %v SET %v FROM %v %v%v", statement.Engine.Quote(statement.refName()),
+			setStr, statement.fromTableStr(), statement.JoinStr, whereStr), nil
+	default:
+		inSql, err := statement.pkInJoinSql(condition)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("UPDATE %v SET %v WHERE %v", statement.Engine.Quote(statement.TableName()),
+			setStr, inSql), nil
+	}
+}
+
+// genJoinDeleteSql generates a DELETE statement which honours the joins,
+// condition has no WHERE keyword.
+func (statement *Statement) genJoinDeleteSql(condition string) (string, error) {
+	var whereStr string
+	if condition != "" {
+		whereStr = " WHERE " + condition
+	}
+	switch statement.Engine.dialect.DBType() {
+	case core.MYSQL, core.MSSQL:
+		return fmt.Sprintf("DELETE %v FROM %v %v%v", statement.Engine.Quote(statement.refName()),
+			statement.fromTableStr(), statement.JoinStr, whereStr), nil
+	case core.POSTGRES:
+		fromStr, condition, err := statement.firstJoinAsFrom(condition)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("DELETE FROM %v USING %v WHERE %v", statement.fromTableStr(),
+			fromStr, condition), nil
+	default:
+		inSql, err := statement.pkInJoinSql(condition)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("DELETE FROM %v WHERE %v", statement.Engine.Quote(statement.TableName()),
+			inSql), nil
+	}
+}
+
+// Generate "Group By keys" statement, the column names of keys are quoted
 func (statement *Statement) GroupBy(keys string) *Statement {
-	statement.GroupByStr = keys
+	statement.GroupByStr = statement.Engine.quoteOrder(keys)
 	return statement
 }
 
@@ -945,9 +1138,17 @@ func (statement *Statement) Having(conditions string) *Statement {
 	return statement
 }
 
+// Returning reads the columns back into the bean after Insert and Update
+func (statement *Statement) Returning(columns ...string) *Statement {
+	statement.returning = true
+	statement.returningCols = append(statement.returningCols, col2NewCols(columns...)...)
+	return statement
+}
+
 // Always disable struct tag "deleted"
 func (statement *Statement) Unscoped() *Statement {
 	statement.unscoped = true
+	statement.onlyDeleted = false
 	return statement
 }
 
@@ -990,8 +1191,79 @@ func (statement *Statement) genColumnStr() string {
 }
 
 func (statement *Statement) genCreateTableSQL() string {
-	return statement.Engine.dialect.CreateTableSql(statement.RefTable, statement.AltTableName,
-		statement.StoreEngine, statement.Charset)
+	return statement.createTableSQL(statement.TableName())
+}
+
+// createTableSQL generates CREATE TABLE tableName from the definitions of
+// the columns, the checks and the foreign keys of the table. The constraints
+// are named by the statement's table, so a copy of the table keeps them.
+// The dialect's CreateTableSql isn't used since it knows the core.Table
+// only, which has none of them.
+func (statement *Statement) createTableSQL(tableName string) string {
+	engine := statement.Engine
+	dialect := engine.dialect
+	dbType := dialect.DBType()
+	table := statement.RefTable
+	ext := engine.tableExt(table)
+
+	defs := make([]string, 0, len(table.ColumnsSeq()))
+	for _, col := range table.Columns() {
+		// oracle defines the primary key by table
+		withPk := col.IsPrimaryKey && len(table.PrimaryKeys) == 1 && dbType != core.ORACLE
+		defs = append(defs, engine.columnSQL(table, col, withPk))
+	}
+	if len(table.PrimaryKeys) > 1 || (len(table.PrimaryKeys) > 0 && dbType == core.ORACLE) {
+		defs = append(defs, "PRIMARY KEY ("+engine.quoteColumns(table.PrimaryKeys...)+")")
+	}
+	for _, check := range ext.checks {
+		defs = append(defs, engine.checkSQL(statement.TableName(), check))
+	}
+	for _, fk := range ext.foreignKeys {
+		defs = append(defs, engine.foreignKeySQL(statement.TableName(), fk))
+	}
+
+	var sqlStr string
+	switch dbType {
+	case core.MSSQL:
+		sqlStr = fmt.Sprintf("IF NOT EXISTS (SELECT [name] FROM sys.tables WHERE [object_id] = OBJECT_ID(N'%v') ) CREATE TABLE ",
+			tableName)
+	case core.ORACLE:
+		sqlStr = "CREATE TABLE "
+	default:
+		sqlStr = "CREATE TABLE IF NOT EXISTS "
+	}
+	sqlStr += engine.quoteName(tableName) + " (" + strings.Join(defs, ", ") + ")"
+
+	if dialect.SupportEngine() && statement.StoreEngine != "" {
+		sqlStr += " ENGINE=" + statement.StoreEngine
+	}
+	if dialect.SupportCharset() {
+		charset := statement.Charset
+		if charset == "" {
+			charset = dialect.URI().Charset
+		}
+		if charset != "" {
+			sqlStr += " DEFAULT CHARSET " + charset
+		}
+	}
+	if dbType == core.MYSQL {
+		if table.AutoIncrement != "" {
+			start, step := engine.autoIncrOf(table, table.AutoIncrColumn())
+			if step != 0 {
+				engine.LogWarnf("mysql has no increment of table %v, use auto_increment_increment instead", table.Name)
+			}
+			if start != 0 {
+				sqlStr += fmt.Sprintf(" AUTO_INCREMENT=%d", start)
+			}
+		}
+		if ext.comment != "" {
+			sqlStr += " COMMENT=" + quoteComment(ext.comment)
+		}
+	}
+	if dbType == core.MSSQL {
+		sqlStr += ";"
+	}
+	return sqlStr
 }
 
 func indexName(tableName, idxName string) string {
@@ -1001,12 +1273,9 @@ func indexName(tableName, idxName string) string {
 func (s *Statement) genIndexSQL() []string {
 	var sqls []string = make([]string, 0)
 	tbName := s.TableName()
-	quote := s.Engine.Quote
-	for idxName, index := range s.RefTable.Indexes {
+	for _, index := range s.RefTable.Indexes {
 		if index.Type == core.IndexType {
-			sql := fmt.Sprintf("CREATE INDEX %v ON %v (%v);", quote(indexName(tbName, idxName)),
-				quote(tbName), quote(strings.Join(index.Cols, quote(","))))
-			sqls = append(sqls, sql)
+			sqls = append(sqls, s.Engine.indexSQL(tbName, s.RefTable, index))
 		}
 	}
 	return sqls
@@ -1021,8 +1290,7 @@ func (s *Statement) genUniqueSQL() []string {
 	tbName := s.TableName()
 	for _, index := range s.RefTable.Indexes {
 		if index.Type == core.UniqueType {
-			sql := s.Engine.dialect.CreateIndexSql(tbName, index)
-			sqls = append(sqls, sql)
+			sqls = append(sqls, s.Engine.indexSQL(tbName, s.RefTable, index))
 		}
 	}
 	return sqls
@@ -1030,12 +1298,17 @@ func (s *Statement) genUniqueSQL() []string {
 
 func (s *Statement) genDelIndexSQL() []string {
 	var sqls []string = make([]string, 0)
+	// the indexes are in the schema of their table, named by the table name
+	schema, tbName := splitSchema(s.TableName(), "")
 	for idxName, index := range s.RefTable.Indexes {
 		var rIdxName string
 		if index.Type == core.UniqueType {
-			rIdxName = uniqueName(s.TableName(), idxName)
+			rIdxName = uniqueName(tbName, idxName)
 		} else if index.Type == core.IndexType {
-			rIdxName = indexName(s.TableName(), idxName)
+			rIdxName = indexName(tbName, idxName)
+		}
+		if schema != "" && !s.Engine.dialect.IndexOnTable() {
+			rIdxName = schema + "." + rIdxName
 		}
 		sql := fmt.Sprintf("DROP INDEX %v", s.Engine.Quote(rIdxName))
 		if s.Engine.dialect.IndexOnTable() {
@@ -1051,7 +1324,10 @@ func (s *Statement) genDropSQL() string {
 	return s.Engine.dialect.MustDropTa(s.TableName()) + ";"
 }*/
 
-func (statement *Statement) genGetSql(bean interface{}) (string, []interface{}) {
+func (statement *Statement) genGetSql(bean interface{}) (string, []interface{}, error) {
+	if statement.lastError != nil {
+		return "", nil, statement.lastError
+	}
 	var table *core.Table
 	if statement.RefTable == nil {
 		table = statement.Engine.TableInfo(bean)
@@ -1062,12 +1338,16 @@ func (statement *Statement) genGetSql(bean interface{}) (string, []interface{})
 
 	var addedTableName = (len(statement.JoinStr) > 0)
 
-	colNames, args := buildConditions(statement.Engine, table, bean, true, true,
+	colNames, args, err := buildConditions(statement.Engine, table, bean, true, true,
 		false, true, statement.allUseBool, statement.useAllCols,
-		statement.unscoped, statement.mustColumnMap, statement.TableName(), addedTableName)
+		statement.unscoped, statement.onlyDeleted, statement.mustColumnMap, statement.refName(), addedTableName)
+	if err != nil {
+		return "", nil, err
+	}
 
 	statement.ConditionStr = strings.Join(colNames, " "+statement.Engine.dialect.AndStr()+" ")
 	statement.BeanArgs = args
+	statement.attachScopes()
 
 	var columnStr string = statement.ColumnStr
 	if len(statement.selectStr) > 0 {
@@ -1076,7 +1356,7 @@ func (statement *Statement) genGetSql(bean interface{}) (string, []interface{})
 		if len(statement.JoinStr) == 0 {
 			if len(columnStr) == 0 {
 				if statement.GroupByStr != "" {
-					columnStr = statement.Engine.Quote(strings.Replace(statement.GroupByStr, ",", statement.Engine.Quote(","), -1))
+					columnStr = statement.GroupByStr
 				} else {
 					columnStr = statement.genColumnStr()
 				}
@@ -1084,7 +1364,7 @@ func (statement *Statement) genGetSql(bean interface{}) (string, []interface{})
 		} else {
 			if len(columnStr) == 0 {
 				if statement.GroupByStr != "" {
-					columnStr = statement.Engine.Quote(strings.Replace(statement.GroupByStr, ",", statement.Engine.Quote(","), -1))
+					columnStr = statement.GroupByStr
 				} else {
 					columnStr = "*"
 				}
@@ -1093,13 +1373,13 @@ func (statement *Statement) genGetSql(bean interface{}) (string, []interface{})
 	}
 
 	statement.attachInSql() // !admpub!  fix bug:Iterate func missing "... IN (...)"
-	return statement.genSelectSql(columnStr), append(statement.Params, statement.BeanArgs...)
+	return statement.genSelectSql(columnStr), append(statement.Params, statement.BeanArgs...), nil
 }
 
 func (s *Statement) genAddColumnStr(col *core.Column) (string, []interface{}) {
 	quote := s.Engine.Quote
 	sql := fmt.Sprintf("ALTER TABLE %v ADD %v;", quote(s.TableName()),
-		col.String(s.Engine.dialect))
+		s.Engine.columnSQL(s.RefTable, col, true))
 	return sql, []interface{}{}
 }
 
@@ -1117,18 +1397,25 @@ func (s *Statement) genAddUniqueStr(uqeName string, cols []string) (string, []in
 	return sql, []interface{}{}
 }*/
 
-func (statement *Statement) genCountSql(bean interface{}) (string, []interface{}) {
+func (statement *Statement) genCountSql(bean interface{}) (string, []interface{}, error) {
+	if statement.lastError != nil {
+		return "", nil, statement.lastError
+	}
 	table := statement.Engine.TableInfo(bean)
 	statement.RefTable = table
 
 	var addedTableName = (len(statement.JoinStr) > 0)
 
-	colNames, args := buildConditions(statement.Engine, table, bean, true, true, false,
+	colNames, args, err := buildConditions(statement.Engine, table, bean, true, true, false,
 		true, statement.allUseBool, statement.useAllCols,
-		statement.unscoped, statement.mustColumnMap, statement.TableName(), addedTableName)
+		statement.unscoped, statement.onlyDeleted, statement.mustColumnMap, statement.refName(), addedTableName)
+	if err != nil {
+		return "", nil, err
+	}
 
 	statement.ConditionStr = strings.Join(colNames, " "+statement.Engine.Dialect().AndStr()+" ")
 	statement.BeanArgs = args
+	statement.attachScopes()
 
 	// count(index fieldname) > count(0) > count(*)
 	var id string = "*"
@@ -1136,13 +1423,13 @@ func (statement *Statement) genCountSql(bean interface{}) (string, []interface{}
 		id = ""
 	}
 	statement.attachInSql()
-	return statement.genSelectSql(fmt.Sprintf("count(%v)", id)), append(statement.Params, statement.BeanArgs...)
+	return statement.genSelectSql(fmt.Sprintf("count(%v)", id)), append(statement.Params, statement.BeanArgs...), nil
 }
 
 func (statement *Statement) genSelectSql(columnStr string) (a string) {
 	/*if statement.GroupByStr != "" {
 		if columnStr == "" {
-			columnStr = statement.Engine.Quote(strings.Replace(statement.GroupByStr, ",", statement.Engine.Quote(","), -1))
+			columnStr = statement.GroupByStr
 		}
 		//statement.GroupByStr = columnStr
 	}*/
@@ -1168,14 +1455,7 @@ func (statement *Statement) genSelectSql(columnStr string) (a string) {
 	} else if statement.ConditionStr != "" {
 		whereStr = fmt.Sprintf(" WHERE %v", statement.ConditionStr)
 	}
-	var fromStr string = " FROM " + statement.Engine.Quote(statement.TableName())
-	if statement.TableAlias != "" {
-		if statement.Engine.dialect.DBType() == core.ORACLE {
-			fromStr += " " + statement.Engine.Quote(statement.TableAlias)
-		} else {
-			fromStr += " AS " + statement.Engine.Quote(statement.TableAlias)
-		}
-	}
+	var fromStr string = " FROM " + statement.fromTableStr()
 	if statement.JoinStr != "" {
 		fromStr = fmt.Sprintf("%v %v", fromStr, statement.JoinStr)
 	}
@@ -1249,11 +1529,15 @@ func (statement *Statement) processIdParam() {
 	if statement.IdParam != nil {
 		if statement.Engine.dialect.DBType() != "ql" {
 			for i, col := range statement.RefTable.PKColumns() {
+				colName := statement.Engine.Quote(col.Name)
+				if statement.JoinStr != "" {
+					colName = statement.Engine.Quote(statement.refName()) + "." + colName
+				}
 				if i < len(*(statement.IdParam)) {
-					statement.And(fmt.Sprintf("%v %s ?", statement.Engine.Quote(col.Name),
+					statement.And(fmt.Sprintf("%v %s ?", colName,
 						statement.Engine.dialect.EqStr()), (*(statement.IdParam))[i])
 				} else {
-					statement.And(fmt.Sprintf("%v %s ?", statement.Engine.Quote(col.Name),
+					statement.And(fmt.Sprintf("%v %s ?", colName,
 						statement.Engine.dialect.EqStr()), "")
 				}
 			}
@@ -1263,4 +1547,5 @@ func (statement *Statement) processIdParam() {
 			}
 		}
 	}
+	statement.processMatches()
 }
@@ -0,0 +1,100 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// columnExt keeps the mapping informations of a column which core.Column
+// has no place for.
+type columnExt struct {
+	generator     string // name of the PKGenerator which fills the column
+	sequence      string // name of the sequence which fills the column
+	autoIncrStart int64  // start value of the autoincrement column
+	autoIncrStep  int64  // increment of the autoincrement column
+	comment       string
+	generated     string // expression of the generated column
+	stored        bool   // whether the generated column is stored or virtual
+	converter     string // name of tag conv, "-" means none
+}
+
+// tableExt keeps the mapping informations of a table which core.Table
+// has no place for.
+type tableExt struct {
+	columns   map[string]*columnExt
+	deletedBy string // name of the column with tag "deleted_by"
+	tenant    string // name of the column with tag "tenant"
+	scopes    []tableScope
+	assocs    map[string]*association // by field name
+
+	foreignKeys []*ForeignKey
+	checks      []*Check
+	comment     string            // returned by method TableComment
+	indexes     map[string]*Index // the indexes with options, by name
+}
+
+func newTableExt() *tableExt {
+	return &tableExt{
+		columns: make(map[string]*columnExt),
+		assocs:  make(map[string]*association),
+		indexes: make(map[string]*Index),
+	}
+}
+
+// column returns the column's ext, nil if the column has none
+func (ext *tableExt) column(name string) *columnExt {
+	return ext.columns[strings.ToLower(name)]
+}
+
+// newColumn returns the column's ext, creates it if the column has none
+func (ext *tableExt) newColumn(name string) *columnExt {
+	key := strings.ToLower(name)
+	colExt, ok := ext.columns[key]
+	if !ok {
+		colExt = &columnExt{}
+		ext.columns[key] = colExt
+	}
+	return colExt
+}
+
+// merge copies the column exts of the parent table, it's used by extends
+func (ext *tableExt) merge(parent *tableExt) {
+	for name, colExt := range parent.columns {
+		ext.columns[name] = colExt
+	}
+	if parent.deletedBy != "" {
+		ext.deletedBy = parent.deletedBy
+	}
+	if parent.tenant != "" {
+		ext.tenant = parent.tenant
+	}
+	for name, assoc := range parent.assocs {
+		ext.assocs[name] = assoc
+	}
+	ext.foreignKeys = append(ext.foreignKeys, parent.foreignKeys...)
+	ext.checks = append(ext.checks, parent.checks...)
+	for name, index := range parent.indexes {
+		ext.indexes[name] = index
+	}
+}
+
+// tableExt returns the ext of table, the returned value is never nil
+func (engine *Engine) tableExt(table *core.Table) *tableExt {
+	engine.mutex.RLock()
+	ext, ok := engine.tableExts[table]
+	engine.mutex.RUnlock()
+	if !ok {
+		return newTableExt()
+	}
+	return ext
+}
+
+// columnExt returns the ext of table's column, nil if it has none
+func (engine *Engine) columnExt(table *core.Table, col *core.Column) *columnExt {
+	return engine.tableExt(table).column(col.Name)
+}
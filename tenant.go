@@ -0,0 +1,115 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// Tenant binds the session to a tenant, the column with tag "tenant" is
+// filled on insert and added to all the conditions, and could not be changed
+// by update. The binding lasts until the session is closed.
+func (session *Session) Tenant(id interface{}) *Session {
+	session.tenantId = id
+	session.Statement.tenantId = id
+	return session
+}
+
+// tenantColumn returns the column with tag "tenant", nil if none
+func (engine *Engine) tenantColumn(table *core.Table) *core.Column {
+	if name := engine.tableExt(table).tenant; name != "" {
+		return table.GetColumn(name)
+	}
+	return nil
+}
+
+// tenantCond returns the condition of the bound tenant, empty if the session
+// isn't bound or the table has no tenant column
+func (statement *Statement) tenantCond() (string, []interface{}) {
+	if statement.tenantId == nil || statement.RefTable == nil {
+		return "", nil
+	}
+	col := statement.Engine.tenantColumn(statement.RefTable)
+	if col == nil {
+		return "", nil
+	}
+	colName := statement.Engine.Quote(col.Name)
+	if statement.JoinStr != "" {
+		colName = statement.Engine.Quote(statement.refName()) + "." + colName
+	}
+	return colName + " = ?", []interface{}{statement.tenantId}
+}
+
+// cacheTableName returns the table name used by the cacher, the records of
+// a tenant are cached apart from the others'.
+func (statement *Statement) cacheTableName() string {
+	tableName := statement.TableName()
+	if statement.tenantId == nil || statement.RefTable == nil ||
+		statement.Engine.tenantColumn(statement.RefTable) == nil {
+		return tableName
+	}
+	return tableName + tenantSep + fmt.Sprintf("%v", statement.tenantId)
+}
+
+// fillTenant sets the bound tenant to the bean which will be inserted
+func (session *Session) fillTenant(table *core.Table, bean interface{}) error {
+	if session.tenantId == nil {
+		return nil
+	}
+	col := session.Engine.tenantColumn(table)
+	if col == nil {
+		return nil
+	}
+
+	fieldValue, err := colValueOf(col, bean)
+	if err != nil {
+		return err
+	}
+	if !isZeroValue(*fieldValue) {
+		if !sameTenant(fieldValue.Interface(), session.tenantId) {
+			return ErrTenantChanged
+		}
+		return nil
+	}
+	return setGeneratedValue(fieldValue, session.tenantId)
+}
+
+// dropTenantUpdate removes the tenant column from the updated columns, an
+// error is returned if it's updated to another tenant.
+func (session *Session) dropTenantUpdate(table *core.Table, colNames []string, args []interface{}) ([]string, []interface{}, error) {
+	col := session.Engine.tenantColumn(table)
+	if col == nil {
+		return colNames, args, nil
+	}
+
+	newNames := make([]string, 0, len(colNames))
+	newArgs := make([]interface{}, 0, len(args))
+	for i, colName := range colNames {
+		name := strings.TrimSpace(strings.SplitN(colName, "=", 2)[0])
+		name, _ = unquoteIdent(name)
+		if !strings.EqualFold(name, col.Name) {
+			newNames = append(newNames, colName)
+			newArgs = append(newArgs, args[i])
+			continue
+		}
+		// the bean read from database has the same tenant
+		if session.tenantId != nil && !sameTenant(args[i], session.tenantId) {
+			return nil, nil, ErrTenantChanged
+		}
+	}
+	return newNames, newArgs, nil
+}
+
+func sameTenant(a, b interface{}) bool {
+	va, vb := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+	if !va.IsValid() || !vb.IsValid() {
+		return va.IsValid() == vb.IsValid()
+	}
+	return fmt.Sprintf("%v", va.Interface()) == fmt.Sprintf("%v", vb.Interface())
+}
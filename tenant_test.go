@@ -0,0 +1,72 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+)
+
+type TenantDoc struct {
+	Id       int64
+	TenantId int64 `xorm:"tenant"`
+	Name     string
+}
+
+func TestTenant(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(TenantDoc)))
+
+	session := engine.NewSession().Tenant(int64(1))
+	defer session.Close()
+	doc := TenantDoc{Name: "a"}
+	_, err := session.Insert(&doc)
+	assertNoErr(t, err)
+	if doc.TenantId != 1 {
+		t.Errorf("the tenant is not filled: %+v", doc)
+	}
+	_, err = engine.Insert(&TenantDoc{TenantId: 2, Name: "b"})
+	assertNoErr(t, err)
+	if _, err = session.Insert(&TenantDoc{TenantId: 2, Name: "c"}); err != ErrTenantChanged {
+		t.Errorf("inserted to another tenant: %v", err)
+	}
+
+	var docs []TenantDoc
+	assertNoErr(t, session.Find(&docs))
+	if len(docs) != 1 || docs[0].Name != "a" {
+		t.Errorf("found %+v of other tenants", docs)
+	}
+
+	// the bean read back has the same tenant
+	doc.Name = "x"
+	_, err = session.Id(doc.Id).Update(&doc)
+	assertNoErr(t, err)
+	cnt, err := session.Table(new(TenantDoc)).Id(doc.Id).
+		Update(map[string]interface{}{"name": "y", "tenant_id": 1})
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Errorf("updated %d records by map, expected 1", cnt)
+	}
+
+	_, err = session.Table(new(TenantDoc)).Id(doc.Id).Update(map[string]interface{}{"tenant_id": 2})
+	if err != ErrTenantChanged {
+		t.Errorf("the tenant is changed by map: %v", err)
+	}
+	doc.TenantId = 2
+	if _, err = session.Id(doc.Id).Update(&doc); err != ErrTenantChanged {
+		t.Errorf("the tenant is changed by bean: %v", err)
+	}
+
+	cnt, err = session.Where("name <> ?", "").Delete(new(TenantDoc))
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Errorf("deleted %d records, expected 1", cnt)
+	}
+	total, err := engine.Count(new(TenantDoc))
+	assertNoErr(t, err)
+	if total != 1 {
+		t.Errorf("%d records are left, expected 1", total)
+	}
+}
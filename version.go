@@ -0,0 +1,112 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// isTimeVersion reports whether the version column is a timestamp which is
+// set to now when the record changes, otherwise it's a counter increased by 1
+func isTimeVersion(col *core.Column) bool {
+	return col.SQLType.IsTime()
+}
+
+// versionCond returns the current version of bean which is used as the
+// condition. zero is false if the version is the zero value.
+func (session *Session) versionCond(col *core.Column, bean interface{}) (arg interface{}, zero bool, err error) {
+	fieldValue, err := colValueOf(col, bean)
+	if err != nil {
+		return nil, false, err
+	}
+	if isTimeVersion(col) {
+		if t, ok := fieldValue.Interface().(time.Time); ok {
+			return session.Engine.FormatTime(col.SQLType.Name, t), t.IsZero(), nil
+		}
+	}
+	arg, err = session.value2Interface(col, *fieldValue)
+	return arg, isZeroValue(*fieldValue), err
+}
+
+// versionSet returns the SET expression which changes the version and the
+// closure which sets the new version to the bean after the record changed
+func (session *Session) versionSet(col *core.Column, quoteCol func(string) string) (string, []interface{}, func(interface{})) {
+	if isTimeVersion(col) {
+		val, t := session.Engine.NowTime2(col.SQLType.Name)
+		return quoteCol(col.Name) + " = ?", []interface{}{val}, func(bean interface{}) {
+			setColumnTime(bean, col, t)
+		}
+	}
+	return quoteCol(col.Name) + " = " + quoteCol(col.Name) + " + 1", nil, func(bean interface{}) {
+		if fieldValue, err := colValueOf(col, bean); err == nil {
+			incrVersion(*fieldValue)
+		}
+	}
+}
+
+// initVersion sets the version of a new record to the bean, the counter is 1
+// and the timestamp is now
+func (session *Session) initVersion(col *core.Column, bean interface{}) {
+	if isTimeVersion(col) {
+		setColumnTime(bean, col, time.Now())
+		return
+	}
+	if fieldValue, err := colValueOf(col, bean); err == nil {
+		setVersion(*fieldValue, 1)
+	}
+}
+
+// incrVersion increases the counter version by 1
+func incrVersion(v reflect.Value) {
+	if !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(v.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(v.Uint() + 1)
+	}
+}
+
+// setVersion sets the counter version
+func setVersion(v reflect.Value, n int64) {
+	if !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(n))
+	}
+}
+
+// ErrVersionConflict is returned by Update and Delete when the record's
+// version doesn't match the bean's, i.e. it's changed or deleted by others
+// since the bean was read.
+type ErrVersionConflict struct {
+	Table string
+	PK    core.PK
+}
+
+func (e *ErrVersionConflict) Error() string {
+	return fmt.Sprintf("version of table %v record %v is conflicted", e.Table, e.PK)
+}
+
+func (session *Session) versionConflict(bean interface{}) error {
+	var pk core.PK
+	if len(session.Statement.RefTable.PrimaryKeys) > 0 {
+		pk = session.Engine.IdOf(bean)
+	}
+	return &ErrVersionConflict{
+		Table: session.Statement.TableName(),
+		PK:    pk,
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+)
+
+type VersionInt32 struct {
+	Id   int64
+	Name string
+	Ver  int32 `xorm:"version"`
+}
+
+type VersionUint struct {
+	Id      int64
+	Name    string
+	Ver     uint      `xorm:"version"`
+	Deleted time.Time `xorm:"deleted"`
+}
+
+type VersionTime struct {
+	Id   int64
+	Name string
+	Ver  time.Time `xorm:"version"`
+}
+
+func assertConflict(t *testing.T, err error) {
+	if _, ok := err.(*ErrVersionConflict); !ok {
+		t.Errorf("expected a version conflict, got %v", err)
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(VersionInt32), new(VersionUint), new(VersionTime)))
+
+	record := VersionInt32{Name: "a"}
+	_, err := engine.Insert(&record)
+	assertNoErr(t, err)
+	if record.Ver != 1 {
+		t.Errorf("the inserted version is %v, expected 1", record.Ver)
+	}
+	stale := record
+
+	record.Name = "b"
+	cnt, err := engine.Id(record.Id).Update(&record)
+	assertNoErr(t, err)
+	if cnt != 1 || record.Ver != 2 {
+		t.Errorf("updated %d records to version %v, expected 1 record of version 2", cnt, record.Ver)
+	}
+
+	stale.Name = "c"
+	_, err = engine.Id(stale.Id).Update(&stale)
+	assertConflict(t, err)
+	if conflict, ok := err.(*ErrVersionConflict); ok {
+		if conflict.Table != "version_int32" || len(conflict.PK) != 1 || conflict.PK[0] != record.Id {
+			t.Errorf("the conflict is %+v", conflict)
+		}
+	}
+	_, err = engine.Id(stale.Id).Delete(&stale)
+	assertConflict(t, err)
+	_, err = engine.Id(record.Id).Delete(&record)
+	assertNoErr(t, err)
+}
+
+func TestVersionSoftDelete(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(VersionUint)))
+
+	record := VersionUint{Name: "a"}
+	_, err := engine.Insert(&record)
+	assertNoErr(t, err)
+	stale := record
+	record.Name = "b"
+	_, err = engine.Id(record.Id).Update(&record)
+	assertNoErr(t, err)
+
+	_, err = engine.Id(stale.Id).Delete(&stale)
+	assertConflict(t, err)
+	cnt, err := engine.Id(record.Id).Delete(&record)
+	assertNoErr(t, err)
+	if cnt != 1 {
+		t.Errorf("deleted %d records, expected 1", cnt)
+	}
+	total, err := engine.Count(new(VersionUint))
+	assertNoErr(t, err)
+	if total != 0 {
+		t.Errorf("%d records are not deleted", total)
+	}
+}
+
+func TestTimeVersion(t *testing.T) {
+	engine, _, done := sqliteEngine(t)
+	defer done()
+	assertNoErr(t, engine.Sync2(new(VersionTime)))
+
+	record := VersionTime{Name: "a"}
+	_, err := engine.Insert(&record)
+	assertNoErr(t, err)
+	var stale VersionTime
+	has, err := engine.Id(record.Id).Get(&stale)
+	assertNoErr(t, err)
+	if !has || stale.Ver.IsZero() {
+		t.Fatalf("the version is not set: %+v", stale)
+	}
+
+	time.Sleep(time.Second)
+	record = stale
+	record.Name = "b"
+	_, err = engine.Id(record.Id).Update(&record)
+	assertNoErr(t, err)
+
+	stale.Name = "c"
+	_, err = engine.Id(stale.Id).Update(&stale)
+	assertConflict(t, err)
+}
@@ -82,10 +82,14 @@ func NewEngine(driverName string, dataSourceName string) (*Engine, error) {
 		db:            db,
 		dialect:       dialect,
 		Tables:        make(map[reflect.Type]*core.Table),
+		tableExts:     make(map[*core.Table]*tableExt),
+		pkGenerators:  make(map[string]PKGenerator),
+		converters:    make(map[reflect.Type]*Converter),
 		mutex:         &sync.RWMutex{},
 		TagIdentifier: "xorm",
 		Logger:        NewSimpleLogger(os.Stdout),
 		TZLocation:    time.Local,
+		cascadeDepth:  1,
 	}
 
 	engine.dialect.SetLogger(engine.Logger)
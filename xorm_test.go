@@ -0,0 +1,108 @@
+// Copyright 2015 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testEngine returns an engine of the dialect without a database, it's used
+// by the tests of the generated sqls
+func testEngine(t *testing.T, dbType core.DbType) *Engine {
+	regDrvsNDialects()
+	dialect := core.QueryDialect(dbType)
+	if dialect == nil {
+		t.Fatalf("no dialect %v", dbType)
+	}
+	if err := dialect.Init(nil, &core.Uri{DbType: dbType, DbName: "test"}, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	engine := &Engine{
+		dialect:       dialect,
+		Tables:        make(map[reflect.Type]*core.Table),
+		tableExts:     make(map[*core.Table]*tableExt),
+		pkGenerators:  make(map[string]PKGenerator),
+		converters:    make(map[reflect.Type]*Converter),
+		mutex:         &sync.RWMutex{},
+		TagIdentifier: "xorm",
+		Logger:        NewSimpleLogger(ioutil.Discard),
+		TZLocation:    time.Local,
+		cascadeDepth:  1,
+	}
+	engine.SetMapper(core.SnakeMapper{})
+	return engine
+}
+
+// testDBTypes are the dialects whose sqls are tested
+var testDBTypes = []core.DbType{core.MYSQL, core.POSTGRES, core.SQLITE, core.MSSQL, core.ORACLE}
+
+// sqlLogger keeps the sqls logged by an engine whose ShowSQL is true
+type sqlLogger struct {
+	*SimpleLogger
+	sqls []string
+}
+
+func (logger *sqlLogger) Infof(format string, v ...interface{}) error {
+	if msg := fmt.Sprintf(format, v...); strings.HasPrefix(msg, "[sql] ") {
+		logger.sqls = append(logger.sqls, msg[len("[sql] "):])
+	}
+	return nil
+}
+
+// Level is unknown so ShowSQL doesn't override it
+func (logger *sqlLogger) Level() core.LogLevel {
+	return core.LOG_UNKNOWN
+}
+
+// reset forgets the sqls logged
+func (logger *sqlLogger) reset() {
+	logger.sqls = nil
+}
+
+// sqliteEngine returns an engine of a new sqlite3 database, the sqls run are
+// kept by log. done removes the database.
+func sqliteEngine(t *testing.T) (engine *Engine, log *sqlLogger, done func()) {
+	dir, err := ioutil.TempDir("", "xorm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err = NewEngine("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	log = &sqlLogger{SimpleLogger: NewSimpleLogger(ioutil.Discard)}
+	engine.SetLogger(log)
+	engine.ShowSQL = true
+	return engine, log, func() {
+		engine.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// assertSQL fails the test if sqlStr is not expected
+func assertSQL(t *testing.T, expected, sqlStr string) {
+	if sqlStr != expected {
+		t.Errorf("expected sql\n\t%v\ngot\n\t%v", expected, sqlStr)
+	}
+}
+
+// assertNoErr fails the test now if err is not nil
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}